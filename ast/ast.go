@@ -2,6 +2,9 @@ package ast
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	checker "github.com/akonwi/ard/checker"
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
@@ -58,6 +61,31 @@ func (v VariableDeclaration) String() string {
 	return fmt.Sprintf("%s %s: %s", binding, v.Name, v.Type)
 }
 
+// let { name, age } = person
+type StructDestructuring struct {
+	BaseNode
+	Mutable bool
+	Names   []string
+	Value   Expression
+}
+
+func (s StructDestructuring) String() string {
+	return fmt.Sprintf("StructDestructuring(%v)", s.Names)
+}
+
+// let [first, second] = pair
+// or with a tuple pattern, let (first, second) = pair
+type ListDestructuring struct {
+	BaseNode
+	Mutable bool
+	Names   []string
+	Value   Expression
+}
+
+func (l ListDestructuring) String() string {
+	return fmt.Sprintf("ListDestructuring(%v)", l.Names)
+}
+
 type VariableAssignment struct {
 	BaseNode
 	Name     string
@@ -70,6 +98,32 @@ func (v VariableAssignment) String() string {
 	return fmt.Sprintf("%v = %s", v.Name, v.Value)
 }
 
+// person.age = 31
+type MemberAssignment struct {
+	BaseNode
+	Target   Expression
+	Member   string
+	Operator Operator
+	Value    Expression
+}
+
+func (m MemberAssignment) String() string {
+	return fmt.Sprintf("%v.%s = %s", m.Target, m.Member, m.Value)
+}
+
+// items[0] = 5
+type IndexAssignment struct {
+	BaseNode
+	Target   Expression
+	Index    Expression
+	Operator Operator
+	Value    Expression
+}
+
+func (i IndexAssignment) String() string {
+	return fmt.Sprintf("%v[%v] = %s", i.Target, i.Index, i.Value)
+}
+
 type Parameter struct {
 	BaseNode
 	Name string
@@ -118,6 +172,8 @@ func (a AnonymousFunction) GetType() checker.Type {
 type StructDefinition struct {
 	BaseNode
 	Type checker.StructType
+	// fields that may be omitted when instantiating the struct
+	Defaults []StructValue
 }
 
 func (s StructDefinition) String() string {
@@ -133,6 +189,8 @@ type StructInstance struct {
 	BaseNode
 	Type       checker.StructType
 	Properties []StructValue
+	// the instance in `Person{ ...base, age: 31 }` that provides the fields which aren't given. nil without one
+	Spread Expression
 }
 
 func (s StructInstance) String() string {
@@ -151,8 +209,50 @@ func (e EnumDefinition) String() string {
 	return fmt.Sprintf("EnumDefinition(%s)", e.Type.Name)
 }
 
+// Shape::Circle(1)
+type EnumVariantInstance struct {
+	BaseNode
+	Type    checker.EnumType
+	Variant string
+	Values  []Expression
+}
+
+func (e EnumVariantInstance) String() string {
+	return fmt.Sprintf("EnumVariantInstance(%s)", e.Type.FormatVariant(e.Variant))
+}
+func (e EnumVariantInstance) GetType() checker.Type {
+	return e.Type
+}
+
+// a match arm like `Shape::Circle(radius)`, which binds the variant's values
+type VariantPattern struct {
+	BaseNode
+	Type     checker.EnumType
+	Variant  string
+	Bindings []string
+}
+
+func (v VariantPattern) String() string {
+	return fmt.Sprintf("VariantPattern(%s%v)", v.Type.FormatVariant(v.Variant), v.Bindings)
+}
+func (v VariantPattern) GetType() checker.Type {
+	return v.Type
+}
+
+type TypeAlias struct {
+	BaseNode
+	Name string
+	Type checker.Type
+}
+
+func (t TypeAlias) String() string {
+	return fmt.Sprintf("TypeAlias(%s = %s)", t.Name, t.Type)
+}
+
 type WhileLoop struct {
 	BaseNode
+	// the name given with `label: while ...`, or "" when unlabeled
+	Label     string
 	Condition Expression
 	Body      []Statement
 }
@@ -163,6 +263,10 @@ func (w WhileLoop) String() string {
 
 type ForLoop struct {
 	BaseNode
+	// the name given with `label: for ...`, or "" when unlabeled
+	Label string
+	// the position of the cursor with `for i, name in names`, or nil without one
+	Index    *Identifier
 	Cursor   Identifier
 	Iterable Expression
 	Body     []Statement
@@ -172,6 +276,49 @@ func (f ForLoop) String() string {
 	return "ForLoop"
 }
 
+// `loop { ... }` runs until a `break` exits it
+type Loop struct {
+	BaseNode
+	// the name given with `label: loop ...`, or "" when unlabeled
+	Label string
+	Body  []Statement
+}
+
+func (l Loop) String() string {
+	return "Loop"
+}
+
+// exits the innermost loop, or the enclosing loop named by Label
+type Break struct {
+	BaseNode
+	Label string
+}
+
+func (b Break) String() string {
+	return fmt.Sprintf("Break(%s)", b.Label)
+}
+
+// skips to the next iteration of the innermost loop, or the enclosing loop named by Label
+type Continue struct {
+	BaseNode
+	Label string
+}
+
+func (c Continue) String() string {
+	return fmt.Sprintf("Continue(%s)", c.Label)
+}
+
+// `defer expr` runs the expression once the enclosing function is done, however it exits.
+// deferred expressions run in the reverse order they were declared
+type Defer struct {
+	BaseNode
+	Expr Expression
+}
+
+func (d Defer) String() string {
+	return "Defer"
+}
+
 type IfStatement struct {
 	BaseNode
 	Condition Expression
@@ -222,6 +369,36 @@ func (m MemberAccess) GetType() checker.Type {
 	return m.Member.GetType()
 }
 
+// person?.name, which is `none` when the target is
+type OptionalMemberAccess struct {
+	BaseNode
+	Target Expression
+	Member Expression
+	Type   checker.OptionType
+}
+
+func (o OptionalMemberAccess) String() string {
+	return fmt.Sprintf("OptionalMemberAccess(%s?.%s)", o.Target, o.Member)
+}
+func (o OptionalMemberAccess) GetType() checker.Type {
+	return o.Type
+}
+
+// items[0]
+type IndexAccess struct {
+	BaseNode
+	Target Expression
+	Index  Expression
+	Type   checker.Type
+}
+
+func (i IndexAccess) String() string {
+	return fmt.Sprintf("IndexAccess(%s[%s])", i.Target, i.Index)
+}
+func (i IndexAccess) GetType() checker.Type {
+	return i.Type
+}
+
 type Operator int
 
 const (
@@ -257,6 +434,10 @@ func (u UnaryExpression) String() string {
 	return fmt.Sprintf("(%v %v)", u.Operator, u.Operand)
 }
 func (u UnaryExpression) GetType() checker.Type {
+	// a negated condition is a Bool even when the operand was mistyped
+	if u.Operator == Bang {
+		return checker.BoolType
+	}
 	return u.Operand.GetType()
 }
 
@@ -335,6 +516,7 @@ func (i InterpolatedStr) GetType() checker.Type {
 
 type NumLiteral struct {
 	BaseNode
+	// the literal as written, which is kept for formatting
 	Value string
 	Type  checker.Type
 }
@@ -342,6 +524,18 @@ type NumLiteral struct {
 func (n NumLiteral) String() string {
 	return n.Value
 }
+
+// Number.MAX_SAFE_INTEGER in JS
+const maxSafeInteger = 1<<53 - 1
+
+// the numeric value of the literal. `_` separators are ignored
+func (n NumLiteral) Float() float64 {
+	value, err := strconv.ParseFloat(strings.ReplaceAll(n.Value, "_", ""), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
 func (n NumLiteral) GetType() checker.Type {
 	return checker.NumType
 }
@@ -373,6 +567,35 @@ func (l ListLiteral) GetType() checker.Type {
 	return l.Type
 }
 
+// `...xs` in a list literal, which adds every item of xs
+type Spread struct {
+	BaseNode
+	Expr Expression
+}
+
+func (s Spread) String() string {
+	return "Spread"
+}
+func (s Spread) GetType() checker.Type {
+	return s.Expr.GetType()
+}
+
+type TupleLiteral struct {
+	BaseNode
+	Items []Expression
+}
+
+func (t TupleLiteral) String() string {
+	return "TupleLiteral"
+}
+func (t TupleLiteral) GetType() checker.Type {
+	items := make([]checker.Type, len(t.Items))
+	for i, item := range t.Items {
+		items[i] = item.GetType()
+	}
+	return checker.TupleType{Items: items}
+}
+
 type MapEntry struct {
 	Key   string
 	Value Expression
@@ -401,6 +624,10 @@ func (m MatchExpression) String() string {
 	return fmt.Sprintf("MatchExpression(%s)", m.Subject)
 }
 func (m MatchExpression) GetType() checker.Type {
+	// every arm was rejected
+	if len(m.Cases) == 0 {
+		return checker.VoidType
+	}
 	return m.Cases[0].GetType()
 }
 
@@ -418,20 +645,166 @@ func (m MatchCase) GetType() checker.Type {
 	return m.Type
 }
 
+type BlockExpression struct {
+	BaseNode
+	Body []Statement
+	Type checker.Type
+}
+
+func (b BlockExpression) String() string {
+	return fmt.Sprintf("BlockExpression(%s)", b.Type)
+}
+func (b BlockExpression) GetType() checker.Type {
+	return b.Type
+}
+
+type ConditionalExpression struct {
+	BaseNode
+	Condition   Expression
+	Consequent  Expression
+	Alternative Expression
+}
+
+func (c ConditionalExpression) String() string {
+	return fmt.Sprintf("ConditionalExpression(%s)", c.Condition)
+}
+func (c ConditionalExpression) GetType() checker.Type {
+	// a branch that never finishes, like a panic(), doesn't decide the type
+	if c.Consequent.GetType() == checker.NeverType {
+		return c.Alternative.GetType()
+	}
+	return c.Consequent.GetType()
+}
+
+// the `some(x)` and `none` patterns when matching on an optional
+type OptionPattern struct {
+	BaseNode
+	IsSome  bool
+	Binding string
+	Type    checker.OptionType
+}
+
+func (o OptionPattern) String() string {
+	if o.IsSome {
+		return fmt.Sprintf("some(%s)", o.Binding)
+	}
+	return "none"
+}
+func (o OptionPattern) GetType() checker.Type {
+	return o.Type
+}
+
+// the catch-all `_` pattern in a match arm
+type Wildcard struct {
+	BaseNode
+	Type checker.Type
+}
+
+func (w Wildcard) String() string {
+	return "_"
+}
+func (w Wildcard) GetType() checker.Type {
+	return w.Type
+}
+
+// `result?` unwraps the ok value or returns the error from the enclosing function
+type TryExpression struct {
+	BaseNode
+	Expr Expression
+	Type checker.Type
+}
+
+func (t TryExpression) String() string {
+	return fmt.Sprintf("TryExpression(%s)", t.Expr)
+}
+func (t TryExpression) GetType() checker.Type {
+	return t.Type
+}
+
+// `@type(expr)` reports the type of an expression while developing, and evaluates to the expression
+type TypeQuery struct {
+	BaseNode
+	Expr Expression
+}
+
+func (t TypeQuery) String() string {
+	return fmt.Sprintf("TypeQuery(%s)", t.Expr)
+}
+func (t TypeQuery) GetType() checker.Type {
+	return t.Expr.GetType()
+}
+
+// `expr as Type`, for conversions the checker can't infer
+type Cast struct {
+	BaseNode
+	Expr Expression
+	Type checker.Type
+}
+
+func (c Cast) String() string {
+	return fmt.Sprintf("Cast(%s as %s)", c.Expr, c.Type)
+}
+func (c Cast) GetType() checker.Type {
+	return c.Type
+}
+
 type Parser struct {
 	sourceCode []byte
 	tree       *tree_sitter.Tree
 	scope      *checker.Scope
 	typeErrors []checker.Diagnostic
-}
-
+	// the declared return type of the function being parsed
+	returnType checker.Type
+	// default field values of the structs declared so far, by struct name
+	structDefaults map[string][]StructValue
+	// the top-level type definitions, parsed ahead of the rest of the program, by node id
+	definitions map[uintptr]Statement
+	// the symbols referenced or declared in the program, by their location
+	symbols map[byteRange]SymbolInfo
+	// every scope opened while parsing, in order
+	scopes []nodeScope
+	// the modules that can be imported, by import path
+	modules map[string]checker.ModuleType
+	// the loops enclosing the statement being parsed, innermost last
+	loops []*enclosingLoop
+	// whether the function being parsed is inside a loop, which its statements can't break out of
+	loopOutsideFunction bool
+	// the body of the function being parsed
+	functionBody *tree_sitter.Node
+	// whether the statement being parsed is directly in a function body, where it can be deferred
+	deferrable bool
+}
+
+type enclosingLoop struct {
+	// "" for unlabeled loops
+	label string
+	// whether a `break` exits this loop
+	exited bool
+}
+
+// the diagnostics found while parsing, minus any silenced with a `// kon:ignore` comment
 func (p *Parser) GetDiagnostics() []checker.Diagnostic {
-	return p.typeErrors
+	suppressed := p.suppressions()
+	diagnostics := []checker.Diagnostic{}
+	for _, diagnostic := range p.typeErrors {
+		if !suppressed[diagnostic.Range.StartPoint.Row][diagnostic.Code] {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	return diagnostics
 }
 
 func NewParser(sourceCode []byte, tree *tree_sitter.Tree) *Parser {
 	scope := checker.NewScope(nil, checker.ScopeOptions{IsTop: true})
-	return &Parser{sourceCode: sourceCode, tree: tree, scope: &scope}
+	return &Parser{
+		sourceCode:     sourceCode,
+		tree:           tree,
+		scope:          &scope,
+		structDefaults: make(map[string][]StructValue),
+		definitions:    make(map[uintptr]Statement),
+		symbols:        make(map[byteRange]SymbolInfo),
+		modules:        make(map[string]checker.ModuleType),
+	}
 }
 
 func (p *Parser) text(node *tree_sitter.Node) string {
@@ -460,40 +833,84 @@ func (p *Parser) mustChildren(node *tree_sitter.Node, name string) []tree_sitter
 	return children
 }
 
-func (p *Parser) pushScope() *checker.Scope {
-	new := checker.NewScope(p.scope, checker.ScopeOptions{})
-	p.scope = &new
+// opens a scope covering @node
+func (p *Parser) pushScope(node *tree_sitter.Node) *checker.Scope {
+	p.scope = p.scope.Child()
+	p.scopes = append(p.scopes, nodeScope{byteRange{node.StartByte(), node.EndByte()}, p.scope})
 	return p.scope
 }
 
 func (p *Parser) popScope() *checker.Scope {
-	p.scope = p.scope.GetParent()
+	p.scope = p.scope.Parent
 	return p.scope
 }
 
+func (p *Parser) declare(name string, t checker.Type, node *tree_sitter.Node) {
+	p.scope.Declare(name, t, node)
+	p.recordSymbol(node, name)
+}
+
+// records the declaration of a struct, enum, type alias or function at @node, given the scope's result for declaring it,
+// and reports a name that is already taken in the same scope
+func (p *Parser) declareUnique(name string, node *tree_sitter.Node, err error) {
+	if err != nil {
+		_, previous, _ := p.scope.LookupDeclaration(name)
+		msg := fmt.Sprintf("'%s' is already declared", name)
+		if previous != nil {
+			msg = fmt.Sprintf("%s at line %d", msg, previous.StartPosition().Row+1)
+		}
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.Duplicate, msg, node))
+		return
+	}
+	p.recordSymbol(node, name)
+}
+
+func (p *Parser) declareVariable(name string, t checker.Type, mutable bool, node *tree_sitter.Node) {
+	if mutable {
+		p.scope.DeclareMutable(name, t, node)
+		p.recordSymbol(node, name)
+	} else {
+		p.declare(name, t, node)
+	}
+}
+
+func (p *Parser) declareParameter(param Parameter) {
+	node := param.TSNode.ChildByFieldName("name")
+	p.scope.DeclareParameter(param.Name, param.Type, node)
+	p.recordSymbol(node, param.Name)
+}
+
 func (p *Parser) typeMismatchError(node *tree_sitter.Node, expected, actual checker.Type) {
 	msg := fmt.Sprintf("Type mismatch: expected %s, got %s", expected, actual)
-	p.typeErrors = append(p.typeErrors, checker.MakeError(msg, node))
+	p.typeErrors = append(p.typeErrors, checker.MakeError(checker.TypeMismatch, msg, node))
 }
 
 func (p *Parser) unaryOperatorError(node *tree_sitter.Node, expected checker.Type) {
 	msg := fmt.Sprintf("The '%v' operator can only be used on '%v'", p.text(node), expected)
-	p.typeErrors = append(p.typeErrors, checker.MakeError(msg, node))
+	p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidOperator, msg, node))
 }
 
 func (p *Parser) binaryOperatorError(node *tree_sitter.Node, operator string, expected checker.Type) {
 	msg := fmt.Sprintf("The '%v' operator can only be used between instances of '%v'", operator, expected)
-	p.typeErrors = append(p.typeErrors, checker.MakeError(msg, node))
+	p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidOperator, msg, node))
 }
 
-func (p *Parser) equalityOperatorError(node *tree_sitter.Node, operator string) {
-	msg := fmt.Sprintf("The '%v' operator can only be used between instances of 'Num', 'Str', or 'Bool'", operator)
-	p.typeErrors = append(p.typeErrors, checker.MakeError(msg, node))
+// values can only be compared with `==` and `!=` when they have the same type,
+// all the way down to the items of a list or the name of a struct
+func (p *Parser) checkComparable(node *tree_sitter.Node, left, right checker.Type) {
+	if left == nil || right == nil {
+		return
+	}
+	if left.Equals(right) && right.Equals(left) {
+		return
+	}
+	msg := fmt.Sprintf("Cannot compare '%s' and '%s'", left, right)
+	p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidOperator, msg, node))
 }
 
 func (p *Parser) logicalOperatorError(node *tree_sitter.Node, operator string) {
 	msg := fmt.Sprintf("The '%v' operator can only be used between instances of 'Bool'", operator)
-	p.typeErrors = append(p.typeErrors, checker.MakeError(msg, node))
+	p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidOperator, msg, node))
 }
 
 func (p *Parser) Parse() (Program, error) {
@@ -502,10 +919,18 @@ func (p *Parser) Parse() (Program, error) {
 		BaseNode:   BaseNode{TSNode: rootNode},
 		Statements: []Statement{}}
 
+	if err := p.declareTopLevel(rootNode); err != nil {
+		return Program{}, err
+	}
+
 	for i := range rootNode.NamedChildCount() {
-		stmt, err := p.parseStatement(rootNode.NamedChild(i))
-		if err != nil {
-			return Program{}, err
+		node := rootNode.NamedChild(i)
+		stmt, ok := p.definitions[node.Id()]
+		if !ok {
+			var err error
+			if stmt, err = p.parseStatement(node); err != nil {
+				return Program{}, err
+			}
 		}
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
@@ -515,6 +940,86 @@ func (p *Parser) Parse() (Program, error) {
 	return program, nil
 }
 
+// registers the top-level types and function signatures before any bodies are checked,
+// so that declarations can refer to ones further down the file and functions can call each other.
+// every struct and enum is named first, so that fields, payloads, aliases and signatures can refer to any of them.
+// a function without a return annotation is left out because its return type is inferred from its body
+func (p *Parser) declareTopLevel(rootNode *tree_sitter.Node) error {
+	// the fields and payloads are resolved once every type is named
+	definitions := []func() error{}
+	aliases, functions := []*tree_sitter.Node{}, []*tree_sitter.Node{}
+	for i := range rootNode.NamedChildCount() {
+		node := rootNode.NamedChild(i)
+		child := node.NamedChild(0)
+		if child == nil {
+			continue
+		}
+		switch child.GrammarName() {
+		case "struct_definition":
+			_type := p.declareStruct(child)
+			definitions = append(definitions, func() (err error) {
+				p.definitions[node.Id()], err = p.defineStruct(child, _type)
+				return err
+			})
+		case "enum_definition":
+			_type := p.declareEnum(child)
+			definitions = append(definitions, func() (err error) {
+				p.definitions[node.Id()], err = p.defineEnum(child, _type)
+				return err
+			})
+		case "type_alias":
+			aliases = append(aliases, node)
+		case "function_definition":
+			if child.ChildByFieldName("return") != nil {
+				functions = append(functions, child)
+			}
+		}
+	}
+
+	for _, node := range aliases {
+		alias, err := p.parseTypeAlias(node.NamedChild(0))
+		if err != nil {
+			return err
+		}
+		p.definitions[node.Id()] = alias
+	}
+	for _, node := range functions {
+		signature := p.functionSignature(node)
+		nameNode := node.ChildByFieldName("name")
+		p.declareUnique(signature.Name, nameNode, p.scope.Declare(signature.Name, signature, nameNode))
+	}
+	for _, define := range definitions {
+		if err := define(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the type of a function definition according to its annotations
+func (p *Parser) functionSignature(node *tree_sitter.Node) checker.FunctionType {
+	p.scope = p.scope.Child()
+	defer p.popScope()
+	if typeParamsNode := node.ChildByFieldName("type_parameters"); typeParamsNode != nil {
+		for i := range typeParamsNode.NamedChildCount() {
+			typeParam := p.text(typeParamsNode.NamedChild(i))
+			p.scope.DeclareAlias(typeParam, checker.MakeGeneric(typeParam), typeParamsNode.NamedChild(i))
+		}
+	}
+
+	parameters := p.parseParameters(node.ChildByFieldName("parameters"))
+	parameterTypes := make([]checker.Type, len(parameters))
+	for i, param := range parameters {
+		parameterTypes[i] = param.Type
+	}
+	return checker.FunctionType{
+		Name:       p.text(node.ChildByFieldName("name")),
+		Mutates:    node.ChildByFieldName("mutates") != nil,
+		Parameters: parameterTypes,
+		ReturnType: p.resolveType(node.ChildByFieldName("return")),
+	}
+}
+
 func (p *Parser) parseStatement(node *tree_sitter.Node) (Statement, error) {
 	child := node.NamedChild(0)
 	switch child.GrammarName() {
@@ -526,14 +1031,26 @@ func (p *Parser) parseStatement(node *tree_sitter.Node) (Statement, error) {
 		return p.parseFunctionDecl(child)
 	case "while_loop":
 		return p.parseWhileLoop(child)
+	case "loop":
+		return p.parseLoop(child)
 	case "for_loop":
 		return p.parseForLoop(child)
+	case "break":
+		return p.parseLoopJump(child, "break")
+	case "continue":
+		return p.parseLoopJump(child, "continue")
+	case "defer":
+		return p.parseDefer(child)
 	case "if_statement":
 		return p.parseIfStatement(child)
 	case "struct_definition":
 		return p.parseStructDefinition(child)
 	case "enum_definition":
 		return p.parseEnumDefinition(child)
+	case "type_alias":
+		return p.parseTypeAlias(child)
+	case "import":
+		return p.parseImport(child)
 	case "expression":
 		expr, err := p.parseExpression(child)
 		if err != nil {
@@ -550,8 +1067,13 @@ func (p *Parser) parseStatement(node *tree_sitter.Node) (Statement, error) {
 	}
 }
 
-func (p *Parser) parseVariableDecl(node *tree_sitter.Node) (VariableDeclaration, error) {
+func (p *Parser) parseVariableDecl(node *tree_sitter.Node) (Statement, error) {
 	isMutable := p.text(node.NamedChild(0)) == "mut"
+	if pattern := node.NamedChild(1); pattern.GrammarName() == "struct_pattern" {
+		return p.parseStructDestructuring(node, pattern, isMutable)
+	} else if pattern.GrammarName() == "list_pattern" || pattern.GrammarName() == "tuple_pattern" {
+		return p.parseListDestructuring(node, pattern, isMutable)
+	}
 	name := p.text(node.NamedChild(1))
 	declaredType := p.resolveType(node.ChildByFieldName("type"))
 	value, err := p.parseExpression(node.ChildByFieldName("value"))
@@ -561,7 +1083,10 @@ func (p *Parser) parseVariableDecl(node *tree_sitter.Node) (VariableDeclaration,
 
 	inferredType := value.GetType()
 
-	if declaredType != nil {
+	if inferredType == checker.VoidType {
+		msg := "Cannot assign a Void value"
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.VoidValue, msg, node.ChildByFieldName("value")))
+	} else if declaredType != nil {
 		if !declaredType.Equals(inferredType) {
 			p.typeMismatchError(node.ChildByFieldName("value"), declaredType, inferredType)
 		}
@@ -571,14 +1096,14 @@ func (p *Parser) parseVariableDecl(node *tree_sitter.Node) (VariableDeclaration,
 		if lt, ok := inferredType.(checker.ListType); ok {
 			if lt.ItemType == nil {
 				msg := fmt.Sprintf("Empty lists need a declared type")
-				p.typeErrors = append(p.typeErrors, checker.MakeError(msg, node))
+				p.typeErrors = append(p.typeErrors, checker.MakeError(checker.MissingTypeAnnotation, msg, node))
 			}
 		}
 
 		if mt, ok := inferredType.(checker.MapType); ok {
 			if mt.KeyType == nil || mt.ValueType == nil {
 				msg := fmt.Sprintf("Empty maps need a declared type")
-				p.typeErrors = append(p.typeErrors, checker.MakeError(msg, node))
+				p.typeErrors = append(p.typeErrors, checker.MakeError(checker.MissingTypeAnnotation, msg, node))
 			}
 		}
 	}
@@ -587,11 +1112,13 @@ func (p *Parser) parseVariableDecl(node *tree_sitter.Node) (VariableDeclaration,
 	if declaredType == nil {
 		symbolType = inferredType
 	}
-	p.scope.Declare(checker.Variable{
-		Mutable: isMutable,
-		Name:    name,
-		Type:    symbolType,
-	})
+	if !p.scope.IsTop() {
+		if _, exists := p.scope.Parent.Lookup(name); exists {
+			msg := fmt.Sprintf("'%s' shadows an existing declaration", name)
+			p.typeErrors = append(p.typeErrors, checker.MakeWarning(checker.Shadowing, msg, node.NamedChild(1)))
+		}
+	}
+	p.declareVariable(name, symbolType, isMutable, node.NamedChild(1))
 
 	return VariableDeclaration{
 		BaseNode: BaseNode{TSNode: node},
@@ -602,92 +1129,256 @@ func (p *Parser) parseVariableDecl(node *tree_sitter.Node) (VariableDeclaration,
 	}, nil
 }
 
-// use for resolving explicit type declarations
-func (p *Parser) resolveType(node *tree_sitter.Node) checker.Type {
-	if node == nil {
-		return nil
-	}
-	child := node.NamedChild(0)
-	switch child.GrammarName() {
-	case "primitive_type":
-		{
-			text := p.text(child)
-			switch text {
-			case "Str":
-				return checker.StrType
-			case "Num":
-				return checker.NumType
-			case "Bool":
-				return checker.BoolType
-			default:
-				panic(fmt.Errorf("Unresolved primitive type: %s", text))
-			}
-		}
-	case "list_type":
-		element_typeNode := child.ChildByFieldName("element_type")
-		return &checker.ListType{ItemType: p.resolveType(element_typeNode)}
-	case "map_type":
-		valueNode := child.ChildByFieldName("value")
-		return checker.MapType{
-			KeyType:   checker.StrType,
-			ValueType: p.resolveType(valueNode),
-		}
-	case "void":
-		return checker.VoidType
-	case "identifier":
-		identifier := p.text(child)
-		symbol := p.scope.Lookup(identifier)
-		if symbol == nil {
-			panic(fmt.Sprintf("Undefined: '%s'", identifier))
-		}
-		return symbol.GetType()
-	default:
-		panic(fmt.Errorf("Unresolved type: %v", child.GrammarName()))
-	}
-}
-
-func (p *Parser) parseVariableReassignment(node *tree_sitter.Node) (VariableAssignment, error) {
-	nameNode := node.ChildByFieldName("name")
-	operatorNode := node.ChildByFieldName("operator")
+func (p *Parser) parseStructDestructuring(node, pattern *tree_sitter.Node, isMutable bool) (Statement, error) {
 	valueNode := node.ChildByFieldName("value")
-
-	name := p.text(nameNode)
-	operator := resolveOperator(operatorNode)
-	symbol := p.scope.Lookup(name)
-
 	value, err := p.parseExpression(valueNode)
 	if err != nil {
-		return VariableAssignment{}, err
+		return nil, err
 	}
 
-	if symbol == nil {
-		msg := fmt.Sprintf("Undefined: '%s'", name)
-		p.typeErrors = append(p.typeErrors, checker.Diagnostic{Msg: msg, Range: nameNode.Range()})
-		return VariableAssignment{Name: name, Operator: operator, Value: value}, nil
+	structType, ok := value.GetType().(checker.StructType)
+	if !ok {
+		msg := fmt.Sprintf("Cannot destructure a '%s' as a struct", value.GetType())
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidDestructuring, msg, valueNode))
+		return nil, fmt.Errorf(msg)
+	}
+
+	fieldNodes := pattern.ChildrenByFieldName("field", p.tree.Walk())
+	names := make([]string, len(fieldNodes))
+	for i, fieldNode := range fieldNodes {
+		name := p.text(&fieldNode)
+		names[i] = name
+		fieldType, ok := structType.Fields[name]
+		if !ok {
+			msg := fmt.Sprintf("No field '%s' in '%s' struct", name, structName(structType))
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnknownMember, msg, &fieldNode))
+			continue
+		}
+		p.declareVariable(name, fieldType, isMutable, &fieldNode)
+	}
+
+	return StructDestructuring{
+		BaseNode: BaseNode{TSNode: node},
+		Mutable:  isMutable,
+		Names:    names,
+		Value:    value,
+	}, nil
+}
+
+func (p *Parser) parseListDestructuring(node, pattern *tree_sitter.Node, isMutable bool) (Statement, error) {
+	valueNode := node.ChildByFieldName("value")
+	value, err := p.parseExpression(valueNode)
+	if err != nil {
+		return nil, err
+	}
+
+	elementNodes := pattern.ChildrenByFieldName("element", p.tree.Walk())
+	names := make([]string, len(elementNodes))
+	for i, elementNode := range elementNodes {
+		names[i] = p.text(&elementNode)
+	}
+
+	// a tuple pattern only destructures a tuple, and binds every one of its elements
+	if pattern.GrammarName() == "tuple_pattern" {
+		tuple, ok := value.GetType().(checker.TupleType)
+		if !ok {
+			msg := fmt.Sprintf("Cannot destructure a '%s' as a tuple", value.GetType())
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidDestructuring, msg, valueNode))
+			return nil, fmt.Errorf(msg)
+		}
+		if len(names) < len(tuple.Items) {
+			msg := fmt.Sprintf("Cannot bind %d names from a tuple of %d elements", len(names), len(tuple.Items))
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidDestructuring, msg, pattern))
+		}
+	}
+
+	var types []checker.Type
+	switch valueType := value.GetType().(type) {
+	case checker.ListType:
+		types = make([]checker.Type, len(names))
+		for i := range names {
+			types[i] = valueType.ItemType
+		}
+	case checker.TupleType:
+		if len(names) > len(valueType.Items) {
+			msg := fmt.Sprintf("Cannot bind %d names from a tuple of %d elements", len(names), len(valueType.Items))
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidDestructuring, msg, pattern))
+			names = names[:len(valueType.Items)]
+		}
+		types = valueType.Items[:len(names)]
+	default:
+		msg := fmt.Sprintf("Cannot destructure a '%s' as a list", value.GetType())
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidDestructuring, msg, valueNode))
+		return nil, fmt.Errorf(msg)
+	}
+
+	for i, name := range names {
+		p.declareVariable(name, types[i], isMutable, &elementNodes[i])
+	}
+
+	return ListDestructuring{
+		BaseNode: BaseNode{TSNode: node},
+		Mutable:  isMutable,
+		Names:    names,
+		Value:    value,
+	}, nil
+}
+
+// use for resolving explicit type declarations
+func (p *Parser) resolveType(node *tree_sitter.Node) checker.Type {
+	if node == nil {
+		return nil
+	}
+	child := node.NamedChild(0)
+	switch child.GrammarName() {
+	case "primitive_type":
+		{
+			text := p.text(child)
+			switch text {
+			case "Str":
+				return checker.StrType
+			case "Num":
+				return checker.NumType
+			case "Bool":
+				return checker.BoolType
+			default:
+				panic(fmt.Errorf("Unresolved primitive type: %s", text))
+			}
+		}
+	case "list_type":
+		element_typeNode := child.ChildByFieldName("element_type")
+		return checker.MakeList(p.resolveType(element_typeNode))
+	case "generic_type":
+		return p.resolveGenericType(child)
+	case "tuple_type":
+		elementNodes := child.ChildrenByFieldName("element", p.tree.Walk())
+		items := make([]checker.Type, len(elementNodes))
+		for i, elementNode := range elementNodes {
+			items[i] = p.resolveType(&elementNode)
+		}
+		return checker.TupleType{Items: items}
+	case "map_type":
+		valueNode := child.ChildByFieldName("value")
+		return checker.MakeMap(p.resolveType(valueNode))
+	case "void":
+		return checker.VoidType
+	case "identifier":
+		identifier := p.text(child)
+		symbol, ok := p.scope.Lookup(identifier)
+		if !ok {
+			p.undefinedSymbolError(child)
+			// Never matches anything, so the annotation isn't reported again wherever it's used
+			return checker.NeverType
+		}
+		p.recordSymbol(child, identifier)
+		return symbol.GetType()
+	default:
+		panic(fmt.Errorf("Unresolved type: %v", child.GrammarName()))
+	}
+}
+
+// List<Num> and Map<Str, Num> are the long forms of [Num] and [Str:Num]
+func (p *Parser) resolveGenericType(node *tree_sitter.Node) checker.Type {
+	name := p.text(p.mustChild(node, "name"))
+	argNodes := p.mustChildren(node, "argument")
+	args := make([]checker.Type, len(argNodes))
+	for i, argNode := range argNodes {
+		args[i] = p.resolveType(&argNode)
+	}
+
+	expectArgs := func(count int) bool {
+		if len(args) != count {
+			msg := fmt.Sprintf("'%s' expects %d type arguments, got %d", name, count, len(args))
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidTypeArguments, msg, node))
+			return false
+		}
+		return true
+	}
+
+	switch name {
+	case "List":
+		if !expectArgs(1) {
+			return checker.MakeList(checker.NeverType)
+		}
+		return checker.MakeList(args[0])
+	case "Option":
+		if !expectArgs(1) {
+			return checker.OptionType{Inner: checker.VoidType}
+		}
+		return checker.OptionType{Inner: args[0]}
+	case "Result":
+		if !expectArgs(1) {
+			return checker.ResultType{OkType: checker.VoidType}
+		}
+		return checker.ResultType{OkType: args[0]}
+	case "Map":
+		if !expectArgs(2) {
+			return checker.MakeMap(nil)
+		}
+		if !checker.StrType.Equals(args[0]) {
+			msg := fmt.Sprintf("Map keys must be 'Str', got '%s'", args[0])
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidTypeArguments, msg, &argNodes[0]))
+		}
+		return checker.MakeMap(args[1])
+	default:
+		msg := fmt.Sprintf("Unknown type: '%s'", name)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnknownType, msg, node))
+		return checker.NeverType
+	}
+}
+
+func (p *Parser) parseVariableReassignment(node *tree_sitter.Node) (Statement, error) {
+	nameNode := node.ChildByFieldName("name")
+	operatorNode := node.ChildByFieldName("operator")
+	valueNode := node.ChildByFieldName("value")
+
+	if nameNode.GrammarName() == "member_access" {
+		return p.parseMemberReassignment(node)
+	}
+	if nameNode.GrammarName() == "index_access" {
+		return p.parseIndexReassignment(node)
+	}
+
+	name := p.text(nameNode)
+	operator := resolveOperator(operatorNode)
+	symbol, found := p.scope.Lookup(name)
+
+	value, err := p.parseExpression(valueNode)
+	if err != nil {
+		return VariableAssignment{}, err
+	}
+
+	if !found {
+		msg := fmt.Sprintf("Undefined: '%s'", name)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.Undefined, msg, nameNode))
+		return VariableAssignment{Name: name, Operator: operator, Value: value}, nil
 	}
 
 	variable, ok := symbol.(checker.Variable)
 	if !ok {
 		msg := fmt.Sprintf("'%s' is not a variable", name)
-		p.typeErrors = append(p.typeErrors, checker.Diagnostic{Msg: msg, Range: nameNode.Range()})
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NotAVariable, msg, nameNode))
 		return VariableAssignment{}, fmt.Errorf(msg)
 	}
 
-	if variable.Mutable == false {
+	if variable.Parameter {
+		msg := fmt.Sprintf("Cannot reassign parameter '%s'", name)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NotMutable, msg, nameNode))
+	} else if variable.Mutable == false {
 		msg := fmt.Sprintf("'%s' is not mutable", name)
-		p.typeErrors = append(p.typeErrors, checker.Diagnostic{Msg: msg, Range: nameNode.Range()})
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NotMutable, msg, nameNode))
 	}
 
 	switch operator {
 	case Assign:
 		if !variable.GetType().Equals(value.GetType()) {
 			msg := fmt.Sprintf("Expected a '%s' and received '%v'", variable.GetType(), value.GetType())
-			p.typeErrors = append(p.typeErrors, checker.Diagnostic{Msg: msg, Range: valueNode.Range()})
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.TypeMismatch, msg, valueNode))
 		}
 	case Increment, Decrement:
 		if variable.GetType() != checker.NumType || value.GetType() != checker.NumType {
 			msg := fmt.Sprintf("'%s' can only be used with 'Num'", p.text(operatorNode))
-			p.typeErrors = append(p.typeErrors, checker.Diagnostic{Msg: msg, Range: valueNode.Range()})
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidOperator, msg, valueNode))
 		}
 	}
 
@@ -698,25 +1389,166 @@ func (p *Parser) parseVariableReassignment(node *tree_sitter.Node) (VariableAssi
 	}, nil
 }
 
+func (p *Parser) parseMemberReassignment(node *tree_sitter.Node) (Statement, error) {
+	accessNode := node.ChildByFieldName("name")
+	operatorNode := node.ChildByFieldName("operator")
+	valueNode := node.ChildByFieldName("value")
+
+	access, err := p.parseMemberAccess(accessNode)
+	if err != nil {
+		return nil, err
+	}
+	// only struct fields can be assigned. list properties, enum variants and module members are read-only
+	memberAccess, ok := access.(MemberAccess)
+	if ok {
+		_, ok = memberAccess.Target.GetType().(checker.StructType)
+	}
+	if !ok {
+		msg := fmt.Sprintf("Cannot assign to '%s'", p.text(accessNode))
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidAssignment, msg, accessNode))
+		return nil, fmt.Errorf(msg)
+	}
+
+	value, err := p.parseExpression(valueNode)
+	if err != nil {
+		return nil, err
+	}
+
+	// fields of a struct bound with `let` are frozen
+	if root, ok := rootIdentifier(memberAccess.Target); ok {
+		symbol, _ := p.scope.Lookup(root.Name)
+		if variable, ok := symbol.(checker.Variable); ok && !variable.Mutable {
+			msg := fmt.Sprintf("'%s' is not mutable", root.Name)
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NotMutable, msg, accessNode))
+		}
+	}
+
+	operator := resolveOperator(operatorNode)
+	fieldType := memberAccess.Member.GetType()
+	switch operator {
+	case Assign:
+		if !fieldType.Equals(value.GetType()) {
+			msg := fmt.Sprintf("Expected a '%s' and received '%v'", fieldType, value.GetType())
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.TypeMismatch, msg, valueNode))
+		}
+	case Increment, Decrement:
+		if fieldType != checker.NumType || value.GetType() != checker.NumType {
+			msg := fmt.Sprintf("'%s' can only be used with 'Num'", p.text(operatorNode))
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidOperator, msg, valueNode))
+		}
+	}
+
+	return MemberAssignment{
+		BaseNode: BaseNode{TSNode: node},
+		Target:   memberAccess.Target,
+		Member:   memberAccess.Member.(Identifier).Name,
+		Operator: operator,
+		Value:    value,
+	}, nil
+}
+
+func (p *Parser) parseIndexReassignment(node *tree_sitter.Node) (Statement, error) {
+	accessNode := node.ChildByFieldName("name")
+	operatorNode := node.ChildByFieldName("operator")
+	valueNode := node.ChildByFieldName("value")
+
+	access, err := p.parseIndexAccess(accessNode)
+	if err != nil {
+		return nil, err
+	}
+	indexAccess := access.(IndexAccess)
+	// strings are immutable at runtime, so assigning a character would silently do nothing
+	if indexAccess.Target.GetType() == checker.StrType {
+		msg := fmt.Sprintf("Cannot assign to '%s'", p.text(accessNode))
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidAssignment, msg, accessNode))
+		return nil, fmt.Errorf(msg)
+	}
+
+	value, err := p.parseExpression(valueNode)
+	if err != nil {
+		return nil, err
+	}
+
+	if root, ok := rootIdentifier(indexAccess.Target); ok {
+		symbol, _ := p.scope.Lookup(root.Name)
+		if variable, ok := symbol.(checker.Variable); ok && !variable.Mutable {
+			msg := fmt.Sprintf("'%s' is not mutable", root.Name)
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NotMutable, msg, accessNode))
+		}
+	}
+
+	operator := resolveOperator(operatorNode)
+	switch operator {
+	case Assign:
+		if !indexAccess.Type.Equals(value.GetType()) {
+			msg := fmt.Sprintf("Expected a '%s' and received '%v'", indexAccess.Type, value.GetType())
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.TypeMismatch, msg, valueNode))
+		}
+	case Increment, Decrement:
+		if indexAccess.Type != checker.NumType || value.GetType() != checker.NumType {
+			msg := fmt.Sprintf("'%s' can only be used with 'Num'", p.text(operatorNode))
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidOperator, msg, valueNode))
+		}
+	}
+
+	return IndexAssignment{
+		BaseNode: BaseNode{TSNode: node},
+		Target:   indexAccess.Target,
+		Index:    indexAccess.Index,
+		Operator: operator,
+		Value:    value,
+	}, nil
+}
+
+// finds the variable at the base of a chain of member accesses
+func rootIdentifier(expr Expression) (Identifier, bool) {
+	switch expr := expr.(type) {
+	case Identifier:
+		return expr, true
+	case MemberAccess:
+		return rootIdentifier(expr.Target)
+	case IndexAccess:
+		return rootIdentifier(expr.Target)
+	default:
+		return Identifier{}, false
+	}
+}
+
 func (p *Parser) parseFunctionDecl(node *tree_sitter.Node) (FunctionDeclaration, error) {
 	name := p.text(node.ChildByFieldName("name"))
+	// `fn mut name()` marks a function as mutating its arguments or outer state
+	mutates := node.ChildByFieldName("mutates") != nil
+
+	// type parameters are visible to the signature and the body
+	scope := p.pushScope(node)
+	if typeParamsNode := node.ChildByFieldName("type_parameters"); typeParamsNode != nil {
+		for i := range typeParamsNode.NamedChildCount() {
+			typeParamNode := typeParamsNode.NamedChild(i)
+			typeParam := p.text(typeParamNode)
+			scope.DeclareAlias(typeParam, checker.MakeGeneric(typeParam), typeParamNode)
+			p.recordSymbol(typeParamNode, typeParam)
+		}
+	}
 	parameters := p.parseParameters(node.ChildByFieldName("parameters"))
 	returnType := p.resolveType(node.ChildByFieldName("return"))
+	outerReturnType := p.returnType
+	p.returnType = returnType
+	leaveFunction := p.enterFunction()
 
-	scope := p.pushScope()
 	parameterTypes := make([]checker.Type, len(parameters))
 	for i, param := range parameters {
 		parameterTypes[i] = param.Type
-		scope.Declare(checker.Variable{
-			Mutable: false,
-			Name:    param.Name,
-			Type:    param.Type,
-		})
+		p.declareParameter(param)
 	}
 
-	body, err := p.parseBlock(node.ChildByFieldName("body"))
+	outerBody := p.functionBody
+	p.functionBody = node.ChildByFieldName("body")
+	body, err := p.parseBlock(p.functionBody)
 
 	p.popScope()
+	p.returnType = outerReturnType
+	leaveFunction()
+	p.functionBody = outerBody
 
 	if err != nil {
 		return FunctionDeclaration{}, err
@@ -733,7 +1565,7 @@ func (p *Parser) parseFunctionDecl(node *tree_sitter.Node) (FunctionDeclaration,
 
 	if returnType == nil {
 		returnType = inferredType
-	} else if returnType != inferredType {
+	} else if !returnType.Equals(inferredType) {
 		if lastStatement != nil {
 			p.typeMismatchError(lastStatement.GetTSNode(), returnType, inferredType)
 		} else {
@@ -743,19 +1575,25 @@ func (p *Parser) parseFunctionDecl(node *tree_sitter.Node) (FunctionDeclaration,
 
 	fnType := checker.FunctionType{
 		Name:       name,
-		Mutates:    false,
+		Mutates:    mutates,
 		Parameters: parameterTypes,
 		ReturnType: returnType,
 	}
-	p.scope.Declare(fnType)
+	// the signature of an annotated top-level function is declared ahead of the program
+	if !p.scope.IsTop() || node.ChildByFieldName("return") == nil {
+		nameNode := node.ChildByFieldName("name")
+		p.declareUnique(name, nameNode, p.scope.Declare(name, fnType, nameNode))
+	}
 
-	return FunctionDeclaration{
+	decl := FunctionDeclaration{
 		BaseNode:   BaseNode{TSNode: node},
 		Name:       name,
 		Parameters: parameters,
 		ReturnType: returnType,
 		Body:       body,
-	}, nil
+	}
+	p.checkRecursion(decl)
+	return decl, nil
 }
 
 func (p *Parser) parseParameters(node *tree_sitter.Node) []Parameter {
@@ -776,7 +1614,21 @@ func (p *Parser) parseParameters(node *tree_sitter.Node) []Parameter {
 	return parameters
 }
 
+// the type a block evaluates to: that of its trailing expression, or Void if it doesn't end with one
+func blockType(body []Statement) checker.Type {
+	if len(body) > 0 {
+		if expr, ok := body[len(body)-1].(Expression); ok {
+			return expr.GetType()
+		}
+	}
+	return checker.VoidType
+}
+
 func (p *Parser) parseBlock(node *tree_sitter.Node) ([]Statement, error) {
+	outerDeferrable := p.deferrable
+	p.deferrable = p.functionBody != nil && node.Id() == p.functionBody.Id()
+	defer func() { p.deferrable = outerDeferrable }()
+
 	statements := []Statement{}
 	for i := range node.NamedChildCount() {
 		stmt, err := p.parseStatement(node.NamedChild(i))
@@ -801,22 +1653,133 @@ func (p *Parser) parseWhileLoop(node *tree_sitter.Node) (Statement, error) {
 
 	if condition.GetType() != checker.BoolType {
 		msg := fmt.Sprintf("A while loop condition must be a 'Bool' expression")
-		p.typeErrors = append(p.typeErrors, checker.Diagnostic{Msg: msg, Range: conditionNode.Range()})
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidCondition, msg, conditionNode))
 	}
 
-	body, err := p.parseBlock(bodyNode)
+	label := p.loopLabel(node)
+	body, exited, err := p.parseExitableLoopBody(label, bodyNode)
 	if err != nil {
 		return nil, err
 	}
+	if literal, ok := condition.(BoolLiteral); ok && literal.Value && !exited {
+		msg := "while condition is always true and loop has no break"
+		p.typeErrors = append(p.typeErrors, checker.MakeWarning(checker.InfiniteLoop, msg, node))
+	}
 
 	return WhileLoop{
+		Label:     label,
 		Condition: condition,
 		Body:      body,
 	}, nil
 }
 
+// the name of a labeled loop, or "" if it has none
+func (p *Parser) loopLabel(node *tree_sitter.Node) string {
+	if labelNode := node.ChildByFieldName("label"); labelNode != nil {
+		return p.text(labelNode)
+	}
+	return ""
+}
+
+// parses the body of a loop labeled @label, where `break` and `continue` can refer to it
+func (p *Parser) parseLoopBody(label string, bodyNode *tree_sitter.Node) ([]Statement, error) {
+	body, _, err := p.parseExitableLoopBody(label, bodyNode)
+	return body, err
+}
+
+// like parseLoopBody, but also reports whether any `break` in the body exits this loop
+func (p *Parser) parseExitableLoopBody(label string, bodyNode *tree_sitter.Node) ([]Statement, bool, error) {
+	loop := &enclosingLoop{label: label}
+	p.loops = append(p.loops, loop)
+	defer func() { p.loops = p.loops[:len(p.loops)-1] }()
+	body, err := p.parseBlock(bodyNode)
+	return body, loop.exited, err
+}
+
+func (p *Parser) parseLoop(node *tree_sitter.Node) (Statement, error) {
+	label := p.loopLabel(node)
+	body, exited, err := p.parseExitableLoopBody(label, node.ChildByFieldName("body"))
+	if err != nil {
+		return nil, err
+	}
+	if !exited {
+		p.typeErrors = append(p.typeErrors, checker.MakeWarning(checker.InfiniteLoop, "This loop has no 'break' and will never end", node))
+	}
+	return Loop{
+		BaseNode: BaseNode{TSNode: node},
+		Label:    label,
+		Body:     body,
+	}, nil
+}
+
+// enters the body of a function, where the loops outside of it can't be exited from.
+// match arms and block expressions count too, since they are compiled to functions.
+// returns a function that leaves the body
+func (p *Parser) enterFunction() func() {
+	outerLoops, outerLoopOutside := p.loops, p.loopOutsideFunction
+	p.loopOutsideFunction = outerLoopOutside || len(outerLoops) > 0
+	p.loops = nil
+	return func() {
+		p.loops, p.loopOutsideFunction = outerLoops, outerLoopOutside
+	}
+}
+
+// the innermost enclosing loop named @label, or the innermost loop if @label is ""
+func (p *Parser) enclosingLoop(label string) *enclosingLoop {
+	for i := len(p.loops) - 1; i >= 0; i-- {
+		if label == "" || p.loops[i].label == label {
+			return p.loops[i]
+		}
+	}
+	return nil
+}
+
+// parses a `break` or `continue`, which must be inside a loop and may name an enclosing loop
+func (p *Parser) parseLoopJump(node *tree_sitter.Node, keyword string) (Statement, error) {
+	labelNode := node.ChildByFieldName("label")
+	label := ""
+	if labelNode != nil {
+		label = p.text(labelNode)
+	}
+
+	if len(p.loops) == 0 && p.loopOutsideFunction {
+		// the loop is outside of the function, where JS can't jump to either
+		msg := fmt.Sprintf("'%s' cannot cross a function boundary", keyword)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NotInLoop, msg, node))
+	} else if len(p.loops) == 0 {
+		msg := fmt.Sprintf("Cannot use '%s' outside of a loop", keyword)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NotInLoop, msg, node))
+	} else if target := p.enclosingLoop(label); target == nil {
+		msg := fmt.Sprintf("No enclosing loop labeled '%s'", label)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnknownLabel, msg, labelNode))
+	} else if keyword == "break" {
+		target.exited = true
+	}
+
+	if keyword == "continue" {
+		return Continue{BaseNode: BaseNode{TSNode: node}, Label: label}, nil
+	}
+	return Break{BaseNode: BaseNode{TSNode: node}, Label: label}, nil
+}
+
+// parses a `defer`, which must be directly in a function body so that it runs when the function is done
+func (p *Parser) parseDefer(node *tree_sitter.Node) (Statement, error) {
+	if !p.deferrable {
+		msg := "'defer' can only be used directly in a function body"
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NotInFunction, msg, node))
+	}
+
+	expr, err := p.parseExpression(p.mustChild(node, "expression"))
+	if err != nil {
+		return nil, err
+	}
+	return Defer{BaseNode: BaseNode{TSNode: node}, Expr: expr}, nil
+}
+
 func (p *Parser) parseForLoop(node *tree_sitter.Node) (Statement, error) {
 	cursorNode := node.ChildByFieldName("cursor")
+	// the `i` in `for i, name in names`
+	indexNode := node.ChildByFieldName("index")
 	rangeNode := node.ChildByFieldName("range")
 	bodyNode := node.ChildByFieldName("body")
 
@@ -826,42 +1789,46 @@ func (p *Parser) parseForLoop(node *tree_sitter.Node) (Statement, error) {
 	}
 
 	iterableType := iterable.GetType()
+	label := p.loopLabel(node)
 
+	var cursorType checker.Type
 	if iterableType == checker.NumType || iterableType == checker.StrType {
-		_cursor := Identifier{Name: p.text(cursorNode), Type: iterableType}
-		newScope := p.pushScope()
-		newScope.Declare(checker.Variable{Mutable: false, Name: _cursor.Name, Type: _cursor.Type})
-		body, err := p.parseBlock(bodyNode)
-		p.popScope()
-		if err != nil {
-			return nil, err
-		}
-		return ForLoop{
-			Cursor:   _cursor,
-			Iterable: iterable,
-			Body:     body,
-		}, nil
+		cursorType = iterableType
+	} else if _listType, ok := iterableType.(checker.ListType); ok {
+		cursorType = _listType.ItemType
+	} else {
+		msg := fmt.Sprintf("Cannot iterate over a '%s'", iterableType)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NotIterable, msg, rangeNode))
+		return nil, fmt.Errorf(msg)
 	}
 
-	if _listType, ok := iterableType.(checker.ListType); ok {
-		_cursor := Identifier{Name: p.text(cursorNode), Type: _listType.ItemType}
-		newScope := p.pushScope()
-		newScope.Declare(checker.Variable{Mutable: false, Name: _cursor.Name, Type: _cursor.Type})
-		body, err := p.parseBlock(bodyNode)
-		p.popScope()
-		if err != nil {
-			return nil, err
-		}
-		return ForLoop{
-			Cursor:   _cursor,
-			Iterable: iterable,
-			Body:     body,
-		}, nil
+	// counting up to a number has no elements to pair with an index
+	if indexNode != nil && iterableType == checker.NumType {
+		msg := fmt.Sprintf("Cannot bind an index when iterating over a '%s'", iterableType)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidDestructuring, msg, indexNode))
+		return nil, fmt.Errorf(msg)
 	}
 
-	msg := fmt.Sprintf("Cannot iterate over a '%s'", iterableType)
-	p.typeErrors = append(p.typeErrors, checker.Diagnostic{Msg: msg, Range: rangeNode.Range()})
-	return nil, fmt.Errorf(msg)
+	p.pushScope(node)
+	var index *Identifier
+	if indexNode != nil {
+		index = &Identifier{Name: p.text(indexNode), Type: checker.NumType}
+		p.declare(index.Name, index.Type, indexNode)
+	}
+	_cursor := Identifier{Name: p.text(cursorNode), Type: cursorType}
+	p.declare(_cursor.Name, _cursor.Type, cursorNode)
+	body, err := p.parseLoopBody(label, bodyNode)
+	p.popScope()
+	if err != nil {
+		return nil, err
+	}
+	return ForLoop{
+		Label:    label,
+		Index:    index,
+		Cursor:   _cursor,
+		Iterable: iterable,
+		Body:     body,
+	}, nil
 }
 
 func (p *Parser) parseIfStatement(node *tree_sitter.Node) (Statement, error) {
@@ -876,7 +1843,7 @@ func (p *Parser) parseIfStatement(node *tree_sitter.Node) (Statement, error) {
 
 	if condition.GetType() != checker.BoolType {
 		msg := fmt.Sprintf("An if condition must be a 'Bool' expression")
-		p.typeErrors = append(p.typeErrors, checker.Diagnostic{Msg: msg, Range: conditionNode.Range()})
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidCondition, msg, conditionNode))
 	}
 
 	body, err := p.parseBlock(bodyNode)
@@ -922,52 +1889,127 @@ func (p *Parser) parseElseClause(node *tree_sitter.Node) (Statement, error) {
 }
 
 func (p *Parser) parseStructDefinition(node *tree_sitter.Node) (Statement, error) {
+	return p.defineStruct(node, p.declareStruct(node))
+}
+
+// declares the struct defined by @node before its fields are known, so that they can refer to it.
+// the fields are filled in by defineStruct
+func (p *Parser) declareStruct(node *tree_sitter.Node) checker.StructType {
 	nameNode := node.ChildByFieldName("name")
+	_type := checker.StructType{Name: p.text(nameNode), Fields: make(map[string]checker.Type)}
+	p.declareUnique(_type.Name, nameNode, p.scope.Declare(_type.Name, _type, nameNode))
+	return _type
+}
+
+func (p *Parser) defineStruct(node *tree_sitter.Node, _type checker.StructType) (Statement, error) {
 	fieldNodes := node.ChildrenByFieldName("field", p.tree.Walk())
 
-	fields := make(map[string]checker.Type)
+	var defaults []StructValue
 	for _, fieldNode := range fieldNodes {
 		nameNode := fieldNode.ChildByFieldName("name")
 		name := p.text(nameNode)
 		typeNode := fieldNode.ChildByFieldName("type")
 		fieldType := p.resolveType(typeNode)
-		fields[name] = fieldType
-	}
+		_type.Fields[name] = fieldType
 
-	_type := checker.StructType{Name: p.text(nameNode), Fields: fields}
-	p.scope.Declare(_type)
+		if defaultNode := fieldNode.ChildByFieldName("default"); defaultNode != nil {
+			value, err := p.parseExpression(defaultNode)
+			if err != nil {
+				return nil, err
+			}
+			if !fieldType.Equals(value.GetType()) {
+				p.typeMismatchError(defaultNode, fieldType, value.GetType())
+			}
+			defaults = append(defaults, StructValue{Name: name, Value: value})
+		}
+	}
+	p.structDefaults[_type.Name] = defaults
 
 	strct := StructDefinition{
-		Type: _type,
+		Type:     _type,
+		Defaults: defaults,
 	}
 	return strct, nil
 }
 
+// `{ name: "Joe", age: 42 }` is an instance of an anonymous struct with the fields it's given
+func (p *Parser) parseObjectLiteral(node *tree_sitter.Node) (Expression, error) {
+	fieldNodes := node.ChildrenByFieldName("field", p.tree.Walk())
+	fields := make(map[string]checker.Type, len(fieldNodes))
+	properties := make([]StructValue, 0, len(fieldNodes))
+	for _, propertyNode := range fieldNodes {
+		nameNode := propertyNode.ChildByFieldName("name")
+		name := p.text(nameNode)
+
+		value, err := p.parseExpression(propertyNode.ChildByFieldName("value"))
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := fields[name]; ok {
+			msg := fmt.Sprintf("Duplicate field '%s' in object literal", name)
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.Duplicate, msg, nameNode))
+			continue
+		}
+		fields[name] = value.GetType()
+		properties = append(properties, StructValue{Name: name, Value: value})
+	}
+
+	return StructInstance{
+		BaseNode:   BaseNode{TSNode: node},
+		Type:       checker.StructType{Fields: fields},
+		Properties: properties,
+	}, nil
+}
+
+// how a struct is referred to in diagnostics. anonymous structs are spelled out
+func structName(structType checker.StructType) string {
+	if structType.IsAnonymous() {
+		return structType.String()
+	}
+	return structType.Name
+}
+
 func (p *Parser) parseStructInstance(node *tree_sitter.Node) (Expression, error) {
 	nameNode := node.ChildByFieldName("name")
 	fieldNodes := node.ChildrenByFieldName("field", p.tree.Walk())
 
 	name := p.text(nameNode)
-	symbol := p.scope.Lookup(name)
-	if symbol == nil {
+	symbol, ok := p.scope.Lookup(name)
+	if !ok {
 		return nil, p.undefinedSymbolError(nameNode)
 	}
+	p.recordSymbol(nameNode, name)
 
 	structType, ok := symbol.GetType().(checker.StructType)
 	if !ok {
 		msg := fmt.Sprintf("'%s' is not a struct", name)
-		p.typeErrors = append(p.typeErrors, checker.MakeError(msg, nameNode))
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NotAStruct, msg, nameNode))
 		return nil, fmt.Errorf(msg)
 	}
 
+	// `...base` provides every field that isn't given
+	var spread Expression
+	if spreadNode := node.ChildByFieldName("spread"); spreadNode != nil {
+		expr, err := p.parseExpression(p.mustChild(spreadNode, "expression"))
+		if err != nil {
+			return nil, err
+		}
+		if !structType.Equals(expr.GetType()) {
+			msg := fmt.Sprintf("Cannot spread a '%s' into '%s'", expr.GetType(), structType.Name)
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.TypeMismatch, msg, spreadNode))
+		}
+		spread = expr
+	}
+
 	receivedNames := make(map[string]int8)
-	properties := make([]StructValue, len(fieldNodes))
-	for i, propertyNode := range fieldNodes {
+	// in source order, so the generated object lists its properties in the same order
+	properties := make([]StructValue, 0, len(fieldNodes))
+	for _, propertyNode := range fieldNodes {
 		nameNode := propertyNode.ChildByFieldName("name")
 		name := p.text(nameNode)
 
-		valueNode := propertyNode.ChildByFieldName("value")
-		value, err := p.parsePrimitiveValue(valueNode)
+		value, err := p.parseExpression(propertyNode.ChildByFieldName("value"))
 		if err != nil {
 			return nil, err
 		}
@@ -975,7 +2017,7 @@ func (p *Parser) parseStructInstance(node *tree_sitter.Node) (Expression, error)
 		expectedType, ok := structType.Fields[name]
 		if !ok {
 			msg := fmt.Sprintf("'%s' is not a field of '%s'", name, structType.Name)
-			p.typeErrors = append(p.typeErrors, checker.MakeError(msg, nameNode))
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnknownMember, msg, nameNode))
 			continue
 		}
 
@@ -984,19 +2026,42 @@ func (p *Parser) parseStructInstance(node *tree_sitter.Node) (Expression, error)
 		}
 
 		if _, ok := receivedNames[name]; ok {
-			p.typeErrors = append(p.typeErrors, checker.MakeError(fmt.Sprintf("Duplicate field '%s' in struct '%s'", name, structType.Name), nameNode))
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.Duplicate, fmt.Sprintf("Duplicate field '%s' in struct '%s'", name, structType.Name), nameNode))
 		} else {
 			receivedNames[name] = 0
 		}
-		properties[i] = StructValue{Name: name, Value: value}
+		properties = append(properties, StructValue{Name: name, Value: value})
+	}
+
+	if spread != nil {
+		return StructInstance{
+			BaseNode:   BaseNode{TSNode: node},
+			Type:       structType,
+			Properties: properties,
+			Spread:     spread,
+		}, nil
+	}
+
+	// omitted fields with a default take the default value
+	for _, fallback := range p.structDefaults[structType.Name] {
+		if _, ok := receivedNames[fallback.Name]; !ok {
+			receivedNames[fallback.Name] = 0
+			properties = append(properties, fallback)
+		}
 	}
 
+	missing := []string{}
 	for name := range structType.Fields {
 		if _, ok := receivedNames[name]; !ok {
-			msg := fmt.Sprintf("Missing field '%s' in struct '%s'", name, structType.Name)
-			p.typeErrors = append(p.typeErrors, checker.MakeError(msg, node))
+			missing = append(missing, name)
 		}
 	}
+	// fields are kept in a map, so sort them for a stable order of diagnostics
+	sort.Strings(missing)
+	for _, name := range missing {
+		msg := fmt.Sprintf("Missing field '%s' in struct '%s'", name, structType.Name)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.MissingField, msg, node))
+	}
 
 	return StructInstance{
 		BaseNode:   BaseNode{TSNode: node},
@@ -1006,6 +2071,12 @@ func (p *Parser) parseStructInstance(node *tree_sitter.Node) (Expression, error)
 }
 
 func (p *Parser) parseEnumDefinition(node *tree_sitter.Node) (Statement, error) {
+	return p.defineEnum(node, p.declareEnum(node))
+}
+
+// declares the enum defined by @node with its variants but before the types of their values are known,
+// so that they can refer to it. the payloads are filled in by defineEnum
+func (p *Parser) declareEnum(node *tree_sitter.Node) checker.EnumType {
 	nameNode := node.ChildByFieldName("name")
 	variantNodes := node.ChildrenByFieldName("variant", p.tree.Walk())
 
@@ -1016,21 +2087,47 @@ func (p *Parser) parseEnumDefinition(node *tree_sitter.Node) (Statement, error)
 		name := p.text(nameNode)
 		if _, ok := names[name]; ok {
 			msg := fmt.Sprintf("Duplicate variant '%s'", name)
-			p.typeErrors = append(p.typeErrors, checker.MakeError(msg, nameNode))
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.Duplicate, msg, nameNode))
 		} else {
 			names[name] = 0
 		}
 		variants[i] = name
 	}
 
-	_type := checker.EnumType{Name: p.text(nameNode), Variants: variants}
+	_type := checker.EnumType{Name: p.text(nameNode), Variants: variants, Payloads: make(map[string][]checker.Type)}
+	p.declareUnique(_type.Name, nameNode, p.scope.Declare(_type.Name, _type, nameNode))
+	return _type
+}
+
+func (p *Parser) defineEnum(node *tree_sitter.Node, _type checker.EnumType) (Statement, error) {
+	for i, variantNode := range node.ChildrenByFieldName("variant", p.tree.Walk()) {
+		// `Circle(Num)` carries a value of each listed type
+		if typeNodes := variantNode.ChildrenByFieldName("type", p.tree.Walk()); len(typeNodes) > 0 {
+			types := make([]checker.Type, len(typeNodes))
+			for j, typeNode := range typeNodes {
+				types[j] = p.resolveType(&typeNode)
+			}
+			_type.Payloads[_type.Variants[i]] = types
+		}
+	}
 
-	enum := EnumDefinition{
+	return EnumDefinition{
 		BaseNode: BaseNode{TSNode: node},
 		Type:     _type,
-	}
-	p.scope.Declare(_type)
-	return enum, nil
+	}, nil
+}
+
+func (p *Parser) parseTypeAlias(node *tree_sitter.Node) (Statement, error) {
+	nameNode := p.mustChild(node, "name")
+	name := p.text(nameNode)
+	_type := p.resolveType(p.mustChild(node, "type"))
+
+	p.declareUnique(name, nameNode, p.scope.DeclareAlias(name, _type, nameNode))
+	return TypeAlias{
+		BaseNode: BaseNode{TSNode: node},
+		Name:     name,
+		Type:     _type,
+	}, nil
 }
 
 func (p *Parser) parseExpression(node *tree_sitter.Node) (Expression, error) {
@@ -1052,6 +2149,8 @@ func (p *Parser) parseExpression(node *tree_sitter.Node) (Expression, error) {
 		return p.parseListValue(child)
 	case "map_value":
 		return p.parseMapLiteral(child)
+	case "tuple_value":
+		return p.parseTupleLiteral(child)
 	case "identifier":
 		return p.parseIdentifier(child)
 	case "unary_expression":
@@ -1060,56 +2159,145 @@ func (p *Parser) parseExpression(node *tree_sitter.Node) (Expression, error) {
 		return p.parseBinaryExpression(child)
 	case "member_access":
 		return p.parseMemberAccess(child)
+	case "index_access":
+		return p.parseIndexAccess(child)
 	case "function_call":
 		return p.parseFunctionCall(child, nil)
 	case "struct_instance":
 		return p.parseStructInstance(child)
+	case "object_literal":
+		return p.parseObjectLiteral(child)
 	case "match_expression":
 		return p.parseMatchExpression(child)
 	case "anonymous_function":
-		return p.parseAnonymousFunction(child)
+		return p.parseAnonymousFunction(child, nil)
+	case "block":
+		return p.parseBlockExpression(child)
+	case "conditional_expression":
+		return p.parseConditionalExpression(child)
+	case "try_expression":
+		return p.parseTryExpression(child)
+	case "type_query":
+		return p.parseTypeQuery(child)
+	case "cast_expression":
+		return p.parseCast(child)
+	case "variable_definition", "reassignment", "function_definition", "while_loop", "for_loop", "loop",
+		"if_statement", "struct_definition", "enum_definition", "type_alias", "import", "break", "continue":
+		// statements don't produce a value
+		msg := "Expected an expression"
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NotAnExpression, msg, child))
+		return nil, fmt.Errorf(msg)
 	default:
 		return nil, fmt.Errorf("Unhandled expression: %s", child.GrammarName())
 	}
 }
 
+func (p *Parser) parseTypeQuery(node *tree_sitter.Node) (Expression, error) {
+	expr, err := p.parseExpression(p.mustChild(node, "expression"))
+	if err != nil {
+		return nil, err
+	}
+	msg := fmt.Sprintf("type of expression is %s", expr.GetType())
+	p.typeErrors = append(p.typeErrors, checker.MakeInfo(checker.TypeOf, msg, node))
+	return TypeQuery{
+		BaseNode: BaseNode{TSNode: node},
+		Expr:     expr,
+	}, nil
+}
+
+func (p *Parser) parseCast(node *tree_sitter.Node) (Expression, error) {
+	expr, err := p.parseExpression(p.mustChild(node, "expression"))
+	if err != nil {
+		return nil, err
+	}
+	castType := p.resolveType(p.mustChild(node, "type"))
+	if !canCast(expr.GetType(), castType) {
+		msg := fmt.Sprintf("Cannot cast %s to %s", expr.GetType(), castType)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidCast, msg, node))
+	}
+	return Cast{
+		BaseNode: BaseNode{TSNode: node},
+		Expr:     expr,
+		Type:     castType,
+	}, nil
+}
+
+// the sanctioned conversions are
+// - narrowing an optional to its inner type, which is unchecked at runtime
+// - wrapping a value as an optional of its type
+// - formatting a Num or Bool as a Str
+func canCast(from, to checker.Type) bool {
+	if to.Equals(from) {
+		return true
+	}
+	if option, ok := from.(checker.OptionType); ok && to.Equals(option.Inner) {
+		return true
+	}
+	if option, ok := to.(checker.OptionType); ok && option.Inner.Equals(from) {
+		return true
+	}
+	return to == checker.StrType && (from == checker.NumType || from == checker.BoolType)
+}
+
 func (p *Parser) parseIdentifier(node *tree_sitter.Node) (Identifier, error) {
 	name := p.text(node)
-	symbol := p.scope.Lookup(name)
-	if symbol == nil {
+	symbol, ok := p.scope.Lookup(name)
+	if !ok {
 		return Identifier{}, p.undefinedSymbolError(node)
 	}
+	p.recordSymbol(node, name)
 
 	return Identifier{Name: name, Type: symbol.GetType()}, nil
 }
 
 func (p *Parser) undefinedSymbolError(node *tree_sitter.Node) error {
 	msg := fmt.Sprintf("Undefined: '%s'", p.text(node))
-	p.typeErrors = append(p.typeErrors, checker.MakeError(msg, node))
+	p.typeErrors = append(p.typeErrors, checker.MakeError(checker.Undefined, msg, node))
 	return fmt.Errorf(msg)
 }
 
+// `\{{` writes a literal `{{` in a string instead of starting an interpolation
+func unescapeDelimiters(text string) string {
+	return strings.ReplaceAll(text, `\{{`, "{{")
+}
+
 func (p *Parser) parsePrimitiveValue(node *tree_sitter.Node) (Expression, error) {
 	child := node.Child(0)
 	switch child.GrammarName() {
 	case "string":
 		chunkNodes := p.mustChildren(child, "chunk")
-		if len(chunkNodes) == 1 && chunkNodes[0].GrammarName() == "string_content" {
+		interpolated := false
+		for _, chunkNode := range chunkNodes {
+			if name := chunkNode.GrammarName(); name != "string_content" && name != "escaped_interpolation" {
+				interpolated = true
+			}
+		}
+		if !interpolated {
 			return StrLiteral{
 				BaseNode: BaseNode{TSNode: node},
-				Value:    p.text(node)}, nil
+				Value:    unescapeDelimiters(p.text(node))}, nil
 		}
 
-		chunks := make([]Expression, len(chunkNodes))
-		for i, chunkNode := range chunkNodes {
-			if chunkNode.GrammarName() == "string_content" {
-				chunks[i] = StrLiteral{BaseNode: BaseNode{TSNode: &chunkNode}, Value: p.text(&chunkNode)}
-			} else {
+		chunks := make([]Expression, 0, len(chunkNodes))
+		for _, chunkNode := range chunkNodes {
+			switch chunkNode.GrammarName() {
+			case "string_content", "escaped_interpolation":
+				// an escaped delimiter is literal text, so it joins the text around it
+				text := unescapeDelimiters(p.text(&chunkNode))
+				if last := len(chunks) - 1; last >= 0 {
+					if previous, ok := chunks[last].(StrLiteral); ok {
+						previous.Value += text
+						chunks[last] = previous
+						continue
+					}
+				}
+				chunks = append(chunks, StrLiteral{BaseNode: BaseNode{TSNode: &chunkNode}, Value: text})
+			default:
 				chunk, err := p.parseExpression(p.mustChild(&chunkNode, "expression"))
 				if err != nil {
 					return nil, err
 				}
-				chunks[i] = chunk
+				chunks = append(chunks, chunk)
 			}
 		}
 		return InterpolatedStr{
@@ -1117,9 +2305,7 @@ func (p *Parser) parsePrimitiveValue(node *tree_sitter.Node) (Expression, error)
 			Chunks:   chunks,
 		}, nil
 	case "number":
-		return NumLiteral{
-			BaseNode: BaseNode{TSNode: node},
-			Value:    p.text(child)}, nil
+		return p.parseNumLiteral(node, child), nil
 	case "boolean":
 		return BoolLiteral{
 			BaseNode: BaseNode{TSNode: node},
@@ -1141,15 +2327,23 @@ func (p *Parser) parseListValue(node *tree_sitter.Node) (Expression, error) {
 			return nil, err
 		}
 		items[i] = item
-		if i == 0 {
+
+		if spread, ok := item.(Spread); ok {
+			spreadType := p.spreadItemType(&innerNode, spread, itemType)
+			if itemType == nil {
+				itemType = spreadType
+			}
+			continue
+		}
+		if itemType == nil {
 			itemType = item.GetType()
-		} else if itemType != item.GetType() {
+		} else if !itemType.Equals(item.GetType()) {
 			msg := fmt.Sprintf("List elements must be of the same type")
-			p.typeErrors = append(p.typeErrors, checker.MakeError(msg, &innerNode))
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.MixedList, msg, &innerNode))
 			break
 		}
 	}
-	listType := checker.ListType{ItemType: itemType}
+	listType := checker.MakeList(itemType)
 
 	return ListLiteral{
 		BaseNode: BaseNode{TSNode: node},
@@ -1158,6 +2352,52 @@ func (p *Parser) parseListValue(node *tree_sitter.Node) (Expression, error) {
 	}, nil
 }
 
+// the type of the items that @spread adds to a list whose items are @itemType, or nil if it's still unknown
+func (p *Parser) spreadItemType(node *tree_sitter.Node, spread Spread, itemType checker.Type) checker.Type {
+	source := spread.GetType()
+	list, ok := source.(checker.ListType)
+	if !ok {
+		msg := fmt.Sprintf("Cannot spread a '%s' into a list", source)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.TypeMismatch, msg, node))
+		return nil
+	}
+	// an empty list fits into any other
+	if itemType != nil && list.ItemType != nil && !itemType.Equals(list.ItemType) {
+		msg := fmt.Sprintf("Cannot spread a '%s' into a list of '%s'", list, itemType)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.TypeMismatch, msg, node))
+	}
+	return list.ItemType
+}
+
+func (p *Parser) parseTupleLiteral(node *tree_sitter.Node) (Expression, error) {
+	elementNodes := node.ChildrenByFieldName("element", p.tree.Walk())
+	items := make([]Expression, len(elementNodes))
+	for i, elementNode := range elementNodes {
+		item, err := p.parseExpression(&elementNode)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return TupleLiteral{
+		BaseNode: BaseNode{TSNode: node},
+		Items:    items,
+	}, nil
+}
+
+// the literal written at @numberNode. JS numbers are doubles, so integers beyond 2^53 - 1 can't all be represented
+func (p *Parser) parseNumLiteral(node *tree_sitter.Node, numberNode *tree_sitter.Node) NumLiteral {
+	literal := NumLiteral{
+		BaseNode: BaseNode{TSNode: node},
+		Value:    p.text(numberNode),
+	}
+	if literal.Float() > maxSafeInteger {
+		msg := "Numeric literal exceeds safe integer range and may lose precision"
+		p.typeErrors = append(p.typeErrors, checker.MakeWarning(checker.UnsafeInteger, msg, numberNode))
+	}
+	return literal
+}
+
 func (p *Parser) parseListElement(node *tree_sitter.Node) (Expression, error) {
 	switch node.GrammarName() {
 	case "string":
@@ -1165,13 +2405,21 @@ func (p *Parser) parseListElement(node *tree_sitter.Node) (Expression, error) {
 			BaseNode: BaseNode{TSNode: node},
 			Value:    p.text(node)}, nil
 	case "number":
-		return NumLiteral{
-			BaseNode: BaseNode{TSNode: node},
-			Value:    p.text(node)}, nil
+		return p.parseNumLiteral(node, node), nil
 	case "boolean":
 		return BoolLiteral{
 			BaseNode: BaseNode{TSNode: node},
 			Value:    p.text(node) == "true"}, nil
+	case "list_value":
+		return p.parseListValue(node)
+	case "map_value":
+		return p.parseMapLiteral(node)
+	case "spread":
+		expr, err := p.parseExpression(p.mustChild(node, "expression"))
+		if err != nil {
+			return nil, err
+		}
+		return Spread{BaseNode: BaseNode{TSNode: node}, Expr: expr}, nil
 	default:
 		return nil, fmt.Errorf("Unhandled list element: %s", node.GrammarName())
 	}
@@ -1179,7 +2427,8 @@ func (p *Parser) parseListElement(node *tree_sitter.Node) (Expression, error) {
 
 func (p *Parser) parseMapLiteral(node *tree_sitter.Node) (Expression, error) {
 	entryNodes := node.ChildrenByFieldName("entry", p.tree.Walk())
-	entries := make([]MapEntry, len(entryNodes))
+	// in source order, so `new Map([...])` lists its entries in the same order
+	entries := make([]MapEntry, 0, len(entryNodes))
 
 	var valueType checker.Type
 
@@ -1191,7 +2440,7 @@ func (p *Parser) parseMapLiteral(node *tree_sitter.Node) (Expression, error) {
 		}
 		if _, ok := receivedKeys[key]; ok {
 			msg := fmt.Sprintf("Duplicate key '%s' in map", key)
-			p.typeErrors = append(p.typeErrors, checker.MakeError(msg, &entryNode))
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.Duplicate, msg, &entryNode))
 		} else {
 			receivedKeys[key] = 0
 		}
@@ -1203,9 +2452,9 @@ func (p *Parser) parseMapLiteral(node *tree_sitter.Node) (Expression, error) {
 			// p.typeErrors = append(p.typeErrors, checker.MakeError(msg, &entryNode))
 			break
 		}
-		entries[i] = MapEntry{Key: key, Value: value}
+		entries = append(entries, MapEntry{Key: key, Value: value})
 	}
-	mapType := checker.MapType{KeyType: checker.StrType, ValueType: valueType}
+	mapType := checker.MakeMap(valueType)
 
 	return MapLiteral{
 		BaseNode: BaseNode{TSNode: node},
@@ -1218,7 +2467,13 @@ func (p *Parser) parseMapEntry(node *tree_sitter.Node) (string, Expression, erro
 	keyNode := node.ChildByFieldName("key")
 	key := p.text(keyNode)
 	valueNode := node.ChildByFieldName("value")
-	value, err := p.parsePrimitiveValue(valueNode)
+	var value Expression
+	var err error
+	if valueNode.GrammarName() == "list_value" {
+		value, err = p.parseListValue(valueNode)
+	} else {
+		value, err = p.parsePrimitiveValue(valueNode)
+	}
 	if err != nil {
 		return key, nil, err
 	}
@@ -1275,7 +2530,8 @@ func resolveOperator(node *tree_sitter.Node) Operator {
 		return Multiply
 	case "modulo":
 		return Modulo
-	case "bang":
+	// `not x` is the keyword spelling of `!x`
+	case "bang", "not":
 		return Bang
 	case "greater_than":
 		return GreaterThan
@@ -1339,9 +2595,7 @@ func (p *Parser) parseBinaryExpression(node *tree_sitter.Node) (Expression, erro
 			p.binaryOperatorError(node, p.text(operatorNode), checker.NumType)
 		}
 	case Equal, NotEqual:
-		if left.GetType() != right.GetType() {
-			p.equalityOperatorError(node, p.text(operatorNode))
-		}
+		p.checkComparable(node, left.GetType(), right.GetType())
 	case And, Or:
 		if left.GetType() != checker.BoolType || right.GetType() != checker.BoolType {
 			p.logicalOperatorError(node, p.text(operatorNode))
@@ -1349,7 +2603,7 @@ func (p *Parser) parseBinaryExpression(node *tree_sitter.Node) (Expression, erro
 	case Range:
 		if left.GetType() != checker.NumType || right.GetType() != checker.NumType {
 			msg := "A range must be between two Num"
-			p.typeErrors = append(p.typeErrors, checker.MakeError(msg, operatorNode))
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidRange, msg, operatorNode))
 		}
 	}
 
@@ -1372,7 +2626,13 @@ func (p *Parser) parseBinaryExpression(node *tree_sitter.Node) (Expression, erro
 func (p *Parser) parseMemberAccess(node *tree_sitter.Node) (Expression, error) {
 	targetNode := p.mustChild(node, "target")
 	operatorNode := node.ChildByFieldName("operator")
-	memberNode := node.ChildByFieldName("member")
+
+	// `Num` is a type rather than a value, unless a variable has taken the name
+	if targetNode.GrammarName() == "identifier" && p.text(targetNode) == checker.NumType.Name {
+		if _, ok := p.scope.Lookup(checker.NumType.Name); !ok {
+			return p.parseNumStatic(node)
+		}
+	}
 
 	target, err := p.parseExpression(targetNode)
 	if err != nil {
@@ -1385,10 +2645,84 @@ func (p *Parser) parseMemberAccess(node *tree_sitter.Node) (Expression, error) {
 		accessType = Instance
 	case "double_colon":
 		accessType = Static
+	case "optional_chain":
+		return p.parseOptionalMemberAccess(node, target, targetNode)
 	default:
 		panic(fmt.Errorf("Unexpected member access operator: %s", operatorNode.GrammarName()))
 	}
 
+	return p.parseMember(node, target, accessType)
+}
+
+// Num.from(str) parses a number, which is none when the string isn't one
+func (p *Parser) parseNumStatic(node *tree_sitter.Node) (Expression, error) {
+	memberNode := node.ChildByFieldName("member")
+	nameNode := memberNode
+	if memberNode.GrammarName() == "function_call" {
+		nameNode = p.mustChild(memberNode, "target")
+	}
+	if memberNode.GrammarName() != "function_call" || p.text(nameNode) != "from" {
+		msg := fmt.Sprintf("No member '%s' on Num", p.text(nameNode))
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnknownMember, msg, nameNode))
+		return nil, fmt.Errorf(msg)
+	}
+
+	call, err := p.parseCall(memberNode, checker.NumFrom, nil)
+	if err != nil {
+		return nil, err
+	}
+	return MemberAccess{
+		BaseNode:   BaseNode{TSNode: node},
+		Target:     Identifier{Name: checker.NumType.Name, Type: checker.NumType},
+		AccessType: Static,
+		Member:     call,
+	}, nil
+}
+
+// the value inside an optional, so the member of `target?.member` is checked like that of `target.member`
+type unwrappedOption struct {
+	Expression
+	inner checker.Type
+}
+
+func (u unwrappedOption) GetType() checker.Type {
+	return u.inner
+}
+
+// target?.member
+func (p *Parser) parseOptionalMemberAccess(node *tree_sitter.Node, target Expression, targetNode *tree_sitter.Node) (Expression, error) {
+	option, ok := target.GetType().(checker.OptionType)
+	if !ok {
+		msg := fmt.Sprintf("'%s' is not optional, use '.' instead of '?.'", target.GetType())
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NotOptional, msg, targetNode))
+		return nil, fmt.Errorf(msg)
+	}
+
+	expr, err := p.parseMember(node, unwrappedOption{Expression: target, inner: option.Inner}, Instance)
+	if err != nil {
+		return nil, err
+	}
+	access, ok := expr.(MemberAccess)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported: optional access of %s", expr)
+	}
+
+	// a member that is already optional isn't wrapped again, so chains like `a?.b?.c` stay one level deep
+	memberType, ok := access.Member.GetType().(checker.OptionType)
+	if !ok {
+		memberType = checker.OptionType{Inner: access.Member.GetType()}
+	}
+	return OptionalMemberAccess{
+		BaseNode: BaseNode{TSNode: node},
+		Target:   target,
+		Member:   access.Member,
+		Type:     memberType,
+	}, nil
+}
+
+func (p *Parser) parseMember(node *tree_sitter.Node, target Expression, accessType MemberAccessType) (Expression, error) {
+	memberNode := node.ChildByFieldName("member")
+
 	switch target.GetType().(type) {
 	case checker.EnumType:
 		enum := target.GetType().(checker.EnumType)
@@ -1397,6 +2731,10 @@ func (p *Parser) parseMemberAccess(node *tree_sitter.Node) (Expression, error) {
 			name := p.text(memberNode)
 			if accessType == Static {
 				if ok := enum.HasVariant(name); ok {
+					if payload := enum.Payloads[name]; len(payload) > 0 {
+						msg := fmt.Sprintf("Expected %d arguments, got 0", len(payload))
+						p.typeErrors = append(p.typeErrors, checker.MakeError(checker.ArgumentCount, msg, memberNode))
+					}
 					return MemberAccess{
 						Target:     target,
 						AccessType: accessType,
@@ -1404,13 +2742,54 @@ func (p *Parser) parseMemberAccess(node *tree_sitter.Node) (Expression, error) {
 					}, nil
 				}
 				msg := fmt.Sprintf("'%s' is not a variant of '%s' enum", name, enum.Name)
-				p.typeErrors = append(p.typeErrors, checker.MakeError(msg, memberNode))
+				p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnknownMember, msg, memberNode))
 				return nil, fmt.Errorf(msg)
 			}
-			return nil, fmt.Errorf("Unsupported: instance members on enums")
+			return nil, fmt.Errorf("Unsupported: instance members on enums")
+		case "function_call":
+			if accessType != Static {
+				return nil, fmt.Errorf("Unsupported: instance members on enums")
+			}
+			return p.parseEnumVariantInstance(node, memberNode, enum)
 		default:
 			panic(fmt.Errorf("Unhandled member type on enum: %s", memberNode.GrammarName()))
 		}
+	case checker.ModuleType:
+		module := target.GetType().(checker.ModuleType)
+		if accessType != Instance {
+			return nil, fmt.Errorf("Unsupported: static members on modules")
+		}
+		nameNode := memberNode
+		if memberNode.GrammarName() == "function_call" {
+			nameNode = p.mustChild(memberNode, "target")
+		}
+		name := p.text(nameNode)
+		memberType := module.GetProperty(name)
+		if memberType == nil {
+			msg := fmt.Sprintf("No member '%s' in '%s' module", name, module.Name)
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnknownMember, msg, nameNode))
+			return nil, fmt.Errorf(msg)
+		}
+		switch memberNode.GrammarName() {
+		case "identifier":
+			return MemberAccess{
+				Target:     target,
+				AccessType: accessType,
+				Member:     Identifier{Name: name, Type: memberType},
+			}, nil
+		case "function_call":
+			call, err := p.parseFunctionCall(memberNode, &target)
+			if err != nil {
+				return nil, err
+			}
+			return MemberAccess{
+				Target:     target,
+				AccessType: accessType,
+				Member:     call,
+			}, nil
+		default:
+			panic(fmt.Errorf("Unhandled member type on module: %s", memberNode.GrammarName()))
+		}
 	case checker.StructType:
 		structDef := target.GetType().(checker.StructType)
 		switch memberNode.GrammarName() {
@@ -1424,8 +2803,8 @@ func (p *Parser) parseMemberAccess(node *tree_sitter.Node) (Expression, error) {
 						Member:     Identifier{Name: name, Type: fieldType},
 					}, nil
 				} else {
-					msg := fmt.Sprintf("No field '%s' in '%s' struct", name, structDef.Name)
-					p.typeErrors = append(p.typeErrors, checker.MakeError(msg, memberNode))
+					msg := fmt.Sprintf("No field '%s' in '%s' struct", name, structName(structDef))
+					p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnknownMember, msg, memberNode))
 					return nil, fmt.Errorf(msg)
 				}
 			}
@@ -1443,7 +2822,7 @@ func (p *Parser) parseMemberAccess(node *tree_sitter.Node) (Expression, error) {
 					property := listType.GetProperty(name)
 					if property == nil {
 						msg := fmt.Sprintf("No property '%s' on List", name)
-						p.typeErrors = append(p.typeErrors, checker.MakeError(msg, memberNode))
+						p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnknownMember, msg, memberNode))
 						return nil, fmt.Errorf(msg)
 					}
 
@@ -1457,6 +2836,11 @@ func (p *Parser) parseMemberAccess(node *tree_sitter.Node) (Expression, error) {
 				}
 			}
 		case "function_call":
+			if p.text(p.mustChild(memberNode, "target")) == "join" && listType.ItemType != nil && !checker.StrType.Equals(listType.ItemType) {
+				msg := fmt.Sprintf("Cannot join a list of '%s', only a list of 'Str'", listType.ItemType)
+				p.typeErrors = append(p.typeErrors, checker.MakeError(checker.TypeMismatch, msg, memberNode))
+				return nil, fmt.Errorf(msg)
+			}
 			call, err := p.parseFunctionCall(memberNode, &target)
 			if err != nil {
 				return nil, err
@@ -1472,13 +2856,6 @@ func (p *Parser) parseMemberAccess(node *tree_sitter.Node) (Expression, error) {
 		}
 	case checker.PrimitiveType:
 		prim := target.GetType().(checker.PrimitiveType)
-		if prim.Name != "Str" {
-			return MemberAccess{
-				Target:     target,
-				AccessType: accessType,
-			}, nil
-		}
-
 		switch memberNode.GrammarName() {
 		case "identifier":
 			name := p.text(memberNode)
@@ -1486,7 +2863,7 @@ func (p *Parser) parseMemberAccess(node *tree_sitter.Node) (Expression, error) {
 				property := prim.GetProperty(name)
 				if property == nil {
 					msg := fmt.Sprintf("No property '%s' on %s", name, prim.Name)
-					p.typeErrors = append(p.typeErrors, checker.MakeError(msg, memberNode))
+					p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnknownMember, msg, memberNode))
 					return nil, fmt.Errorf(msg)
 				}
 
@@ -1496,20 +2873,84 @@ func (p *Parser) parseMemberAccess(node *tree_sitter.Node) (Expression, error) {
 					Member:     Identifier{Name: name, Type: property},
 				}, nil
 			} else {
-				panic("Unimplemented: static members on Str")
+				panic(fmt.Errorf("Unimplemented: static members on %s", prim.Name))
+			}
+		case "function_call":
+			// the target is parsed first, so a chain like `name.trim().size` is checked one step at a time
+			call, err := p.parseFunctionCall(memberNode, &target)
+			if err != nil {
+				return nil, err
 			}
+
+			return MemberAccess{
+				Target:     target,
+				AccessType: accessType,
+				Member:     call,
+			}, nil
 		default:
-			panic(fmt.Errorf("Unhandled member type on Str: %s", memberNode.GrammarName()))
+			panic(fmt.Errorf("Unhandled member type on %s: %s", prim.Name, memberNode.GrammarName()))
 		}
+	case checker.OptionType:
+		// the members of the inner value are only reachable once none is ruled out
+		msg := fmt.Sprintf("'%s' is optional, use '?.' instead of '.'", target.GetType())
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnknownMember, msg, p.mustChild(node, "target")))
+		return nil, fmt.Errorf(msg)
 	default:
 		panic(fmt.Errorf("Unhandled target type for MemberAccess: %s", target.GetType()))
 	}
 }
 
+func (p *Parser) parseIndexAccess(node *tree_sitter.Node) (Expression, error) {
+	targetNode := p.mustChild(node, "target")
+	indexNode := p.mustChild(node, "index")
+
+	target, err := p.parseExpression(targetNode)
+	if err != nil {
+		return nil, err
+	}
+	index, err := p.parseExpression(indexNode)
+	if err != nil {
+		return nil, err
+	}
+
+	// indexing a Str yields the single character at that position.
+	// like list elements, the index isn't bounds checked and reading past the end is undefined at runtime
+	if target.GetType() == checker.StrType {
+		if index.GetType() != checker.NumType {
+			msg := "A string index must be a 'Num'"
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidIndex, msg, indexNode))
+		}
+		return IndexAccess{
+			BaseNode: BaseNode{TSNode: node},
+			Target:   target,
+			Index:    index,
+			Type:     checker.StrType,
+		}, nil
+	}
+
+	listType, ok := target.GetType().(checker.ListType)
+	if !ok {
+		msg := fmt.Sprintf("Cannot index into a '%s'", target.GetType())
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidIndex, msg, targetNode))
+		return nil, fmt.Errorf(msg)
+	}
+	if index.GetType() != checker.NumType {
+		msg := "A list index must be a 'Num'"
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidIndex, msg, indexNode))
+	}
+
+	return IndexAccess{
+		BaseNode: BaseNode{TSNode: node},
+		Target:   target,
+		Index:    index,
+		Type:     listType.ItemType,
+	}, nil
+}
+
 /* look for a function in scope */
 func (p *Parser) findFunction(name string) *checker.FunctionType {
-	symbol := p.scope.Lookup(name)
-	if symbol == nil {
+	symbol, ok := p.scope.Lookup(name)
+	if !ok {
 		return nil
 	}
 	fnType, ok := symbol.GetType().(checker.FunctionType)
@@ -1531,6 +2972,18 @@ func (p *Parser) findMethod(subject checker.Type, name string) *checker.Function
 			}
 			return &signature
 		}
+	case checker.ModuleType:
+		signature, ok := subject.(checker.ModuleType).GetProperty(name).(checker.FunctionType)
+		if !ok {
+			return nil
+		}
+		return &signature
+	case checker.PrimitiveType:
+		signature, ok := subject.(checker.PrimitiveType).GetProperty(name).(checker.FunctionType)
+		if !ok {
+			return nil
+		}
+		return &signature
 	default:
 		panic(fmt.Errorf("Unhandled method call on %s", subject))
 	}
@@ -1545,6 +2998,7 @@ func (p *Parser) parseFunctionCall(node *tree_sitter.Node, target *Expression) (
 	if target == nil {
 		if fn := p.findFunction(p.text(targetNode)); fn != nil {
 			signature = *fn
+			p.recordSymbol(targetNode, fn.Name)
 		} else {
 			return FunctionCall{}, p.undefinedSymbolError(node)
 		}
@@ -1553,42 +3007,56 @@ func (p *Parser) parseFunctionCall(node *tree_sitter.Node, target *Expression) (
 			signature = *method
 		} else {
 			msg := fmt.Sprintf("Method '%s' not found on %s", p.text(targetNode), (*target).GetType())
-			p.typeErrors = append(p.typeErrors, checker.MakeError(msg, node))
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnknownMember, msg, node))
 			return FunctionCall{}, fmt.Errorf(msg)
 		}
 	}
 
+	return p.parseCall(node, signature, target)
+}
+
+// checks the arguments of a call to a function with the given @signature
+func (p *Parser) parseCall(node *tree_sitter.Node, signature checker.FunctionType, target *Expression) (FunctionCall, error) {
 	argsNode := node.ChildByFieldName("arguments")
 	argNodes := argsNode.ChildrenByFieldName("argument", p.tree.Walk())
 
-	if len(argNodes) != len(signature.Parameters) {
+	required := len(signature.Parameters) - signature.Optional
+	if len(argNodes) < required || len(argNodes) > len(signature.Parameters) {
 		msg := fmt.Sprintf("Expected %d arguments, got %d", len(signature.Parameters), len(argNodes))
-		p.typeErrors = append(p.typeErrors, checker.MakeError(msg, argsNode))
+		if signature.Optional > 0 {
+			msg = fmt.Sprintf("Expected %d to %d arguments, got %d", required, len(signature.Parameters), len(argNodes))
+		}
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.ArgumentCount, msg, argsNode))
 		return FunctionCall{}, fmt.Errorf(msg)
 	}
 
 	args := make([]Expression, len(argNodes))
+	generics := make(map[string]checker.Type)
 	for i, argNode := range argNodes {
-		arg, err := p.parseExpression(&argNode)
+		expectedType := checker.ResolveGenerics(signature.Parameters[i], generics)
+		arg, err := p.parseArgument(&argNode, expectedType)
 		if err != nil {
 			return FunctionCall{}, err
 		}
-		expectedType := signature.Parameters[i]
 		resolvedArg := coerceArgIfNecessary(arg, expectedType)
 
 		if !expectedType.Equals(resolvedArg) {
 			p.typeMismatchError(&argNode, expectedType, resolvedArg)
 		}
+		checker.BindGenerics(expectedType, resolvedArg, generics)
 		args[i] = arg
 	}
+	if len(generics) > 0 {
+		signature.ReturnType = checker.ResolveGenerics(signature.ReturnType, generics)
+	}
 
-	if signature.Mutates {
+	if signature.Mutates && target != nil {
 		if identifier, is_identifier := (*target).(Identifier); is_identifier {
-			symbol := p.scope.Lookup(identifier.Name)
+			symbol, _ := p.scope.Lookup(identifier.Name)
 			if v, ok := symbol.(checker.Variable); ok {
 				if v.Mutable == false {
 					msg := fmt.Sprintf("Cannot mutate an immutable list")
-					p.typeErrors = append(p.typeErrors, checker.MakeError(msg, node))
+					p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NotMutable, msg, node))
 				}
 			}
 		}
@@ -1602,6 +3070,16 @@ func (p *Parser) parseFunctionCall(node *tree_sitter.Node, target *Expression) (
 	}, nil
 }
 
+// anonymous functions passed as arguments take their parameter types from the expected signature
+func (p *Parser) parseArgument(node *tree_sitter.Node, expectedType checker.Type) (Expression, error) {
+	if child := node.Child(0); child != nil && child.GrammarName() == "anonymous_function" {
+		if signature, ok := expectedType.(checker.FunctionType); ok {
+			return p.parseAnonymousFunction(child, &signature)
+		}
+	}
+	return p.parseExpression(node)
+}
+
 // if @arg is an anonymous function and @expectedType is a function
 // it returns the generics coerced with the expected type.
 //
@@ -1657,66 +3135,339 @@ func (p *Parser) parseMatchExpression(node *tree_sitter.Node) (Expression, error
 		providedCases := make(map[string]int)
 		cases := make([]MatchCase, 0)
 		var resultType checker.Type = checker.VoidType
-		for i, caseNode := range caseNodes {
-			_case, err := p.parseMemberAccess(p.mustChild(&caseNode, "pattern"))
+		hasWildcard := false
+		for _, caseNode := range caseNodes {
+			patternNode := p.mustChild(&caseNode, "pattern")
+			if hasWildcard {
+				p.unreachableArmError(&caseNode)
+				continue
+			}
+
+			var pattern Expression
+			var body []Statement
+			var returnType checker.Type
+			var err error
+			if patternNode.GrammarName() == "wildcard" {
+				hasWildcard = true
+				pattern = Wildcard{BaseNode: BaseNode{TSNode: patternNode}, Type: enum}
+				body, returnType, err = p.parseMatchCaseBody(&caseNode)
+			} else {
+				if !p.isVariantPattern(patternNode, enum) {
+					msg := fmt.Sprintf("Pattern '%s' is not a variant of '%s'", p.text(patternNode), enum.Name)
+					p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidPattern, msg, patternNode))
+					continue
+				}
+				if p.mustChild(patternNode, "member").GrammarName() == "function_call" {
+					variantPattern, ok := p.parseVariantPattern(patternNode, enum)
+					if !ok {
+						continue
+					}
+					pattern = variantPattern
+					providedCases[variantPattern.Variant] = 0
+					p.pushScope(&caseNode)
+					bindingNodes := p.mustChild(patternNode, "member").ChildByFieldName("arguments").ChildrenByFieldName("argument", p.tree.Walk())
+					for j, binding := range variantPattern.Bindings {
+						p.declare(binding, enum.Payloads[variantPattern.Variant][j], &bindingNodes[j])
+					}
+					body, returnType, err = p.parseMatchCaseBody(&caseNode)
+					p.popScope()
+				} else {
+					_case, parseErr := p.parseMemberAccess(patternNode)
+					if parseErr != nil {
+						return nil, parseErr
+					}
+					memberAccess := _case.(MemberAccess)
+					pattern = memberAccess
+					providedCases[memberAccess.Member.(Identifier).Name] = 0
+					body, returnType, err = p.parseMatchCaseBody(&caseNode)
+				}
+			}
 			if err != nil {
 				return nil, err
 			}
-			var returnType checker.Type = checker.VoidType
-			var body = make([]Statement, 0)
-			bodyNode := p.mustChild(&caseNode, "body")
-			if bodyNode.GrammarName() == "block" {
-				_body, err := p.parseBlock(bodyNode)
-				if err != nil {
-					return nil, err
-				}
-				body = _body
+			cases = append(cases, MatchCase{
+				Pattern: pattern,
+				Body:    body,
+				Type:    returnType,
+			})
 
-				last := body[len(body)-1]
-				if expr, ok := last.(Expression); ok {
-					returnType = expr.GetType()
-				}
-			} else if bodyNode.GrammarName() == "expression" {
-				_body, err := p.parseExpression(bodyNode)
+			// rejected arms are skipped, so the first arm that was accepted decides the type
+			if len(cases) == 1 {
+				resultType = returnType
+			} else if resultType.Equals(returnType) == false {
+				p.typeMismatchError(&caseNode, resultType, returnType)
+			}
+		}
+		for _, variant := range enum.Variants {
+			if _, ok := providedCases[variant]; !ok && !hasWildcard {
+				msg := fmt.Sprintf("Missing case for '%s'", enum.FormatVariant(variant))
+				p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NonExhaustiveMatch, msg, node))
+			}
+		}
+
+		return MatchExpression{
+			BaseNode: BaseNode{TSNode: node},
+			Subject:  expression,
+			Cases:    cases,
+		}, nil
+	case checker.PrimitiveType:
+		if expression.GetType() != checker.BoolType {
+			return nil, p.matchSubjectError(expressionNode, expression.GetType())
+		}
+
+		providedCases := make(map[bool]int)
+		cases := make([]MatchCase, 0)
+		var resultType checker.Type = checker.VoidType
+		hasWildcard := false
+		for _, caseNode := range caseNodes {
+			patternNode := p.mustChild(&caseNode, "pattern")
+			if hasWildcard {
+				p.unreachableArmError(&caseNode)
+				continue
+			}
+
+			var pattern Expression
+			if patternNode.GrammarName() == "wildcard" {
+				hasWildcard = true
+				pattern = Wildcard{BaseNode: BaseNode{TSNode: patternNode}, Type: checker.BoolType}
+			} else {
+				value, err := p.parsePrimitiveValue(patternNode)
 				if err != nil {
 					return nil, err
 				}
-				body = append(body, _body)
-				returnType = _body.GetType()
+				literal, ok := value.(BoolLiteral)
+				if !ok {
+					p.typeMismatchError(patternNode, checker.BoolType, value.GetType())
+					continue
+				}
+				providedCases[literal.Value] = 0
+				pattern = literal
+			}
+			body, returnType, err := p.parseMatchCaseBody(&caseNode)
+			if err != nil {
+				return nil, err
 			}
 
-			memberAccess := _case.(MemberAccess)
 			cases = append(cases, MatchCase{
-				Pattern: memberAccess,
+				Pattern: pattern,
 				Body:    body,
 				Type:    returnType,
 			})
-			providedCases[memberAccess.Member.(Identifier).Name] = 0
 
-			if i == 0 {
+			// rejected arms are skipped, so the first arm that was accepted decides the type
+			if len(cases) == 1 {
 				resultType = returnType
 			} else if resultType.Equals(returnType) == false {
 				p.typeMismatchError(&caseNode, resultType, returnType)
 			}
 		}
-		for _, variant := range enum.Variants {
-			if _, ok := providedCases[variant]; !ok {
-				msg := fmt.Sprintf("Missing case for '%s'", enum.FormatVariant(variant))
-				p.typeErrors = append(p.typeErrors, checker.MakeError(msg, node))
+		for _, value := range []bool{true, false} {
+			if _, ok := providedCases[value]; !ok && !hasWildcard {
+				msg := fmt.Sprintf("Match is not exhaustive: missing %t", value)
+				p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NonExhaustiveMatch, msg, node))
 			}
 		}
 
+		return MatchExpression{
+			BaseNode: BaseNode{TSNode: node},
+			Subject:  expression,
+			Cases:    cases,
+		}, nil
+	case checker.OptionType:
+		option := expression.GetType().(checker.OptionType)
+
+		providedCases := make(map[bool]int)
+		cases := make([]MatchCase, 0)
+		var resultType checker.Type = checker.VoidType
+		hasWildcard := false
+		for _, caseNode := range caseNodes {
+			patternNode := p.mustChild(&caseNode, "pattern")
+			if hasWildcard {
+				p.unreachableArmError(&caseNode)
+				continue
+			}
+
+			var pattern Expression
+			var body []Statement
+			var returnType checker.Type
+			var err error
+			switch patternNode.GrammarName() {
+			case "wildcard":
+				hasWildcard = true
+				pattern = Wildcard{BaseNode: BaseNode{TSNode: patternNode}, Type: option}
+				body, returnType, err = p.parseMatchCaseBody(&caseNode)
+			case "some_pattern":
+				bindingNode := p.mustChild(patternNode, "binding")
+				binding := p.text(bindingNode)
+				providedCases[true] = 0
+				pattern = OptionPattern{BaseNode: BaseNode{TSNode: patternNode}, IsSome: true, Binding: binding, Type: option}
+				p.pushScope(&caseNode)
+				p.declare(binding, option.Inner, bindingNode)
+				body, returnType, err = p.parseMatchCaseBody(&caseNode)
+				p.popScope()
+			case "none_pattern":
+				providedCases[false] = 0
+				pattern = OptionPattern{BaseNode: BaseNode{TSNode: patternNode}, IsSome: false, Type: option}
+				body, returnType, err = p.parseMatchCaseBody(&caseNode)
+			default:
+				msg := fmt.Sprintf("Expected a 'some' or 'none' pattern for '%s'", option)
+				p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidPattern, msg, patternNode))
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			cases = append(cases, MatchCase{
+				Pattern: pattern,
+				Body:    body,
+				Type:    returnType,
+			})
+
+			// rejected arms are skipped, so the first arm that was accepted decides the type
+			if len(cases) == 1 {
+				resultType = returnType
+			} else if resultType.Equals(returnType) == false {
+				p.typeMismatchError(&caseNode, resultType, returnType)
+			}
+		}
+		if _, ok := providedCases[true]; !ok && !hasWildcard {
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NonExhaustiveMatch, "Match is not exhaustive: missing some", node))
+		}
+		if _, ok := providedCases[false]; !ok && !hasWildcard {
+			p.typeErrors = append(p.typeErrors, checker.MakeError(checker.NonExhaustiveMatch, "Match is not exhaustive: missing none", node))
+		}
+
 		return MatchExpression{
 			BaseNode: BaseNode{TSNode: node},
 			Subject:  expression,
 			Cases:    cases,
 		}, nil
 	default:
-		panic(fmt.Sprintf("Unsupported subject type for match expression: %v", expression.GetType()))
+		return nil, p.matchSubjectError(expressionNode, expression.GetType())
+	}
+}
+
+func (p *Parser) matchSubjectError(node *tree_sitter.Node, subjectType checker.Type) error {
+	msg := fmt.Sprintf("Cannot match on '%s', only on an enum, a Bool or an optional", subjectType)
+	p.typeErrors = append(p.typeErrors, checker.MakeError(checker.TypeMismatch, msg, node))
+	return fmt.Errorf(msg)
+}
+
+// whether @node names one of the variants of @enum, like `Color::Red` or `Shape::Circle(r)`
+func (p *Parser) isVariantPattern(node *tree_sitter.Node, enum checker.EnumType) bool {
+	if node.GrammarName() != "member_access" {
+		return false
+	}
+	target := node.ChildByFieldName("target")
+	member := node.ChildByFieldName("member")
+	if target == nil || member == nil {
+		return false
+	}
+	if member.GrammarName() == "function_call" {
+		member = member.ChildByFieldName("target")
+	}
+	return p.text(target) == enum.Name && enum.HasVariant(p.text(member))
+}
+
+// Shape::Circle(1)
+func (p *Parser) parseEnumVariantInstance(node, callNode *tree_sitter.Node, enum checker.EnumType) (Expression, error) {
+	variantNode := p.mustChild(callNode, "target")
+	variant := p.text(variantNode)
+	if !enum.HasVariant(variant) {
+		msg := fmt.Sprintf("'%s' is not a variant of '%s' enum", variant, enum.Name)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnknownMember, msg, variantNode))
+		return nil, fmt.Errorf(msg)
+	}
+
+	payload := enum.Payloads[variant]
+	argsNode := callNode.ChildByFieldName("arguments")
+	argNodes := argsNode.ChildrenByFieldName("argument", p.tree.Walk())
+	if len(argNodes) != len(payload) {
+		msg := fmt.Sprintf("Expected %d arguments, got %d", len(payload), len(argNodes))
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.ArgumentCount, msg, argsNode))
+		return nil, fmt.Errorf(msg)
+	}
+
+	values := make([]Expression, len(argNodes))
+	for i, argNode := range argNodes {
+		value, err := p.parseExpression(&argNode)
+		if err != nil {
+			return nil, err
+		}
+		if !payload[i].Equals(value.GetType()) {
+			p.typeMismatchError(&argNode, payload[i], value.GetType())
+		}
+		values[i] = value
+	}
+
+	return EnumVariantInstance{
+		BaseNode: BaseNode{TSNode: node},
+		Type:     enum,
+		Variant:  variant,
+		Values:   values,
+	}, nil
+}
+
+// Shape::Circle(radius) binds each of the variant's values to a name in the arm's body
+func (p *Parser) parseVariantPattern(node *tree_sitter.Node, enum checker.EnumType) (VariantPattern, bool) {
+	callNode := p.mustChild(node, "member")
+	variant := p.text(p.mustChild(callNode, "target"))
+	payload := enum.Payloads[variant]
+
+	argsNode := callNode.ChildByFieldName("arguments")
+	argNodes := argsNode.ChildrenByFieldName("argument", p.tree.Walk())
+	if len(argNodes) != len(payload) {
+		msg := fmt.Sprintf("'%s' has %d values, got %d bindings", enum.FormatVariant(variant), len(payload), len(argNodes))
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidPattern, msg, argsNode))
+		return VariantPattern{}, false
+	}
+
+	bindings := make([]string, len(argNodes))
+	for i, argNode := range argNodes {
+		bindings[i] = p.text(&argNode)
+	}
+	return VariantPattern{
+		BaseNode: BaseNode{TSNode: node},
+		Type:     enum,
+		Variant:  variant,
+		Bindings: bindings,
+	}, true
+}
+
+func (p *Parser) unreachableArmError(node *tree_sitter.Node) {
+	msg := "Unreachable match arm after wildcard"
+	p.typeErrors = append(p.typeErrors, checker.MakeError(checker.UnreachableArm, msg, node))
+}
+
+// parses the body of a match arm, which is either a block or a single expression
+func (p *Parser) parseMatchCaseBody(caseNode *tree_sitter.Node) ([]Statement, checker.Type, error) {
+	var returnType checker.Type = checker.VoidType
+	var body = make([]Statement, 0)
+	bodyNode := p.mustChild(caseNode, "body")
+	defer p.enterFunction()()
+	if bodyNode.GrammarName() == "block" {
+		_body, err := p.parseBlock(bodyNode)
+		if err != nil {
+			return nil, nil, err
+		}
+		body = _body
+		returnType = blockType(body)
+	} else if bodyNode.GrammarName() == "expression" {
+		_body, err := p.parseExpression(bodyNode)
+		if err != nil {
+			return nil, nil, err
+		}
+		body = append(body, _body)
+		returnType = _body.GetType()
 	}
+	return body, returnType, nil
 }
 
-func (p *Parser) parseAnonymousFunction(node *tree_sitter.Node) (AnonymousFunction, error) {
+/*
+@expected - the signature the function is being passed as, used to type unannotated parameters
+*/
+func (p *Parser) parseAnonymousFunction(node *tree_sitter.Node, expected *checker.FunctionType) (AnonymousFunction, error) {
 	parameterNodes := node.ChildrenByFieldName("parameter", p.tree.Walk())
 	parameters := make([]Parameter, len(parameterNodes))
 	for i, paramNode := range parameterNodes {
@@ -1725,6 +3476,11 @@ func (p *Parser) parseAnonymousFunction(node *tree_sitter.Node) (AnonymousFuncti
 		typeNode := paramNode.ChildByFieldName("type")
 		if typeNode == nil {
 			_type = checker.GenericType{}
+			if expected != nil && i < len(expected.Parameters) {
+				if _, isGeneric := expected.Parameters[i].(checker.GenericType); !isGeneric {
+					_type = expected.Parameters[i]
+				}
+			}
 		} else {
 			_type = p.resolveType(typeNode)
 		}
@@ -1735,27 +3491,26 @@ func (p *Parser) parseAnonymousFunction(node *tree_sitter.Node) (AnonymousFuncti
 		}
 	}
 
-	scope := p.pushScope()
+	p.pushScope(node)
 	for _, param := range parameters {
-		scope.Declare(checker.Variable{
-			Mutable: false,
-			Name:    param.Name,
-			Type:    param.Type,
-		})
-	}
-	body, err := p.parseBlock(p.mustChild(node, "body"))
+		p.declareParameter(param)
+	}
+	leaveFunction := p.enterFunction()
+	outerBody := p.functionBody
+	p.functionBody = p.mustChild(node, "body")
+	// an anonymous function doesn't declare a Result return type, so `?` can't propagate out of it
+	outerReturnType := p.returnType
+	p.returnType = nil
+	body, err := p.parseBlock(p.functionBody)
+	leaveFunction()
+	p.functionBody = outerBody
+	p.returnType = outerReturnType
+	p.popScope()
 	if err != nil {
 		return AnonymousFunction{}, err
 	}
-	p.popScope()
 
-	var returnType checker.Type = checker.VoidType
-	if len(body) > 0 {
-		last := body[len(body)-1]
-		if expr, ok := last.(Expression); ok {
-			returnType = expr.GetType()
-		}
-	}
+	returnType := blockType(body)
 
 	return AnonymousFunction{
 		BaseNode:   BaseNode{TSNode: node},
@@ -1764,3 +3519,79 @@ func (p *Parser) parseAnonymousFunction(node *tree_sitter.Node) (AnonymousFuncti
 		ReturnType: returnType,
 	}, nil
 }
+
+func (p *Parser) parseBlockExpression(node *tree_sitter.Node) (Expression, error) {
+	p.pushScope(node)
+	leaveFunction := p.enterFunction()
+	body, err := p.parseBlock(node)
+	leaveFunction()
+	p.popScope()
+	if err != nil {
+		return nil, err
+	}
+
+	return BlockExpression{
+		BaseNode: BaseNode{TSNode: node},
+		Body:     body,
+		Type:     blockType(body),
+	}, nil
+}
+
+func (p *Parser) parseConditionalExpression(node *tree_sitter.Node) (Expression, error) {
+	conditionNode := p.mustChild(node, "condition")
+	consequentNode := p.mustChild(node, "consequent")
+	alternativeNode := p.mustChild(node, "alternative")
+
+	condition, err := p.parseExpression(conditionNode)
+	if err != nil {
+		return nil, err
+	}
+	if condition.GetType() != checker.BoolType {
+		msg := fmt.Sprintf("A conditional expression's condition must be a 'Bool' expression")
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidCondition, msg, conditionNode))
+	}
+
+	consequent, err := p.parseExpression(consequentNode)
+	if err != nil {
+		return nil, err
+	}
+	alternative, err := p.parseExpression(alternativeNode)
+	if err != nil {
+		return nil, err
+	}
+	if !consequent.GetType().Equals(alternative.GetType()) {
+		p.typeMismatchError(alternativeNode, consequent.GetType(), alternative.GetType())
+	}
+
+	return ConditionalExpression{
+		BaseNode:    BaseNode{TSNode: node},
+		Condition:   condition,
+		Consequent:  consequent,
+		Alternative: alternative,
+	}, nil
+}
+
+func (p *Parser) parseTryExpression(node *tree_sitter.Node) (Expression, error) {
+	exprNode := p.mustChild(node, "expr")
+	expr, err := p.parseExpression(exprNode)
+	if err != nil {
+		return nil, err
+	}
+
+	result, ok := expr.GetType().(checker.ResultType)
+	if !ok {
+		msg := fmt.Sprintf("'?' can only be used on a Result, got '%s'", expr.GetType())
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidTry, msg, exprNode))
+		return nil, fmt.Errorf(msg)
+	}
+	if _, ok := p.returnType.(checker.ResultType); !ok {
+		msg := "'?' can only be used in a function that returns a Result"
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.InvalidTry, msg, node))
+	}
+
+	return TryExpression{
+		BaseNode: BaseNode{TSNode: node},
+		Expr:     expr,
+		Type:     result.OkType,
+	}, nil
+}
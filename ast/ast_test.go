@@ -27,6 +27,24 @@ var compareOptions = cmp.Options{
 		}
 		return true
 	}),
+	// enums get an empty payload map when declared, and it's only filled in for the variants that carry values
+	cmp.Comparer(func(x, y map[string][]checker.Type) bool {
+		if len(x) != len(y) {
+			return false
+		}
+		for k, v1 := range x {
+			v2, ok := y[k]
+			if !ok || len(v1) != len(v2) {
+				return false
+			}
+			for i := range v1 {
+				if !v1[i].Equals(v2[i]) {
+					return false
+				}
+			}
+		}
+		return true
+	}),
 	cmp.Comparer(func(x, y map[string]int) bool {
 		if len(x) != len(y) {
 			return false
@@ -53,6 +71,8 @@ type test struct {
 	input       string
 	output      Program
 	diagnostics []checker.Diagnostic
+	// modules the input can import, by import path
+	modules map[string]checker.ModuleType
 }
 
 func runTests(t *testing.T, tests []test) {
@@ -60,6 +80,9 @@ func runTests(t *testing.T, tests []test) {
 		t.Run(tt.name, func(t *testing.T) {
 			tree := tsParser.Parse([]byte(tt.input), nil)
 			parser := NewParser([]byte(tt.input), tree)
+			for path, module := range tt.modules {
+				parser.AddModule(path, module)
+			}
 			ast, err := parser.Parse()
 			if err != nil && len(tt.diagnostics) == 0 {
 				t.Fatal(fmt.Errorf("Error parsing tree: %v", err))
@@ -81,7 +104,12 @@ func runTests(t *testing.T, tests []test) {
 				)
 			}
 			for i, want := range tt.diagnostics {
-				if diff := cmp.Diff(want, parser.typeErrors[i], compareOptions); diff != "" {
+				got := parser.typeErrors[i]
+				// codes are only checked when a test expects one
+				if want.Code == "" {
+					got.Code = ""
+				}
+				if diff := cmp.Diff(want, got, compareOptions); diff != "" {
 					t.Errorf("Error does not match (-want +got):\n%s", diff)
 				}
 			}
@@ -311,6 +339,23 @@ func TestForLoops(t *testing.T) {
 			},
 			diagnostics: []checker.Diagnostic{},
 		},
+		{
+			name:  "Valid descending number range",
+			input: `for i in 10..1 {}`,
+			output: Program{
+				Statements: []Statement{
+					ForLoop{
+						Cursor: Identifier{Name: "i", Type: checker.NumType},
+						Iterable: RangeExpression{
+							Start: NumLiteral{Value: "10"},
+							End:   NumLiteral{Value: "1"},
+						},
+						Body: []Statement{},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
 		{
 			name:  "Iterating over a string",
 			input: `for char in "foobar" {}`,
@@ -346,6 +391,48 @@ func TestForLoops(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Binding the index and the element of a list",
+			input: `
+				for i, name in ["jane", "joe"] {
+					let position: Num = i
+					let greeting: Str = name
+				}`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "Binding the index of a string",
+			input: `for i, char in "foobar" {}`,
+			output: Program{
+				Statements: []Statement{
+					ForLoop{
+						Index:  &Identifier{Name: "i", Type: checker.NumType},
+						Cursor: Identifier{Name: "char", Type: checker.StrType},
+						Iterable: StrLiteral{
+							Value: `"foobar"`,
+						},
+						Body: []Statement{},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "Cannot bind an index over a range",
+			input: `for i, n in 1..10 {}`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidDestructuring, Msg: "Cannot bind an index when iterating over a 'Num'"},
+			},
+		},
+		{
+			name: "Iterating over an annotated list",
+			input: `
+				let xs: List<Num> = [1, 2]
+				let ys: [Str] = ["a", "b"]
+				for x in xs { let n: Num = x }
+				for i, y in ys { let s: Str = y }`,
+			diagnostics: []checker.Diagnostic{},
+		},
 		{
 			name:  "Cannot iterate over a boolean",
 			input: `for wtf in true {}`,
@@ -360,6 +447,192 @@ func TestForLoops(t *testing.T) {
 	runTests(t, tests)
 }
 
+func TestLoopLabels(t *testing.T) {
+	tests := []test{
+		{
+			name: "Breaking out of a labeled outer loop",
+			input: `
+				outer: for i in 1..3 {
+					for j in 1..3 {
+						break outer
+					}
+				}`,
+			output: Program{
+				Statements: []Statement{
+					ForLoop{
+						Label:  "outer",
+						Cursor: Identifier{Name: "i", Type: checker.NumType},
+						Iterable: RangeExpression{
+							Start: NumLiteral{Value: "1"},
+							End:   NumLiteral{Value: "3"},
+						},
+						Body: []Statement{
+							ForLoop{
+								Cursor: Identifier{Name: "j", Type: checker.NumType},
+								Iterable: RangeExpression{
+									Start: NumLiteral{Value: "1"},
+									End:   NumLiteral{Value: "3"},
+								},
+								Body: []Statement{Break{Label: "outer"}},
+							},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Referencing an undefined label",
+			input: `
+				outer: while true {
+					continue inner
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.UnknownLabel, Msg: "No enclosing loop labeled 'inner'"},
+				{Code: checker.InfiniteLoop, Msg: "while condition is always true and loop has no break", Severity: checker.Warning},
+			},
+		},
+		{
+			name:  "Breaking outside of a loop",
+			input: `break`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.NotInLoop, Msg: "Cannot use 'break' outside of a loop"},
+			},
+		},
+		{
+			name: "Breaking directly in a loop",
+			input: `
+				for i in 3 {
+					if i == 1 {
+						continue
+					}
+					break
+				}`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Breaking from a closure inside a loop",
+			input: `
+				outer: for i in 3 {
+					let skip = () { continue }
+					let stop = () {
+						if true {
+							break outer
+						}
+					}
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.NotInLoop, Msg: "'continue' cannot cross a function boundary"},
+				{Code: checker.NotInLoop, Msg: "'break' cannot cross a function boundary"},
+			},
+		},
+		{
+			name: "Breaking from a match arm or block inside a loop",
+			input: `
+				for i in 3 {
+					match i == 1 {
+						true => {
+							break
+						},
+						false => {
+							continue
+						}
+					}
+					let skip = {
+						break
+					}
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.NotInLoop, Msg: "'break' cannot cross a function boundary"},
+				{Code: checker.NotInLoop, Msg: "'continue' cannot cross a function boundary"},
+				{Code: checker.NotInLoop, Msg: "'break' cannot cross a function boundary"},
+			},
+		},
+		{
+			name: "Loops inside a closure can be exited",
+			input: `
+				for i in 3 {
+					let first = () {
+						for j in 3 {
+							break
+						}
+					}
+				}`,
+			diagnostics: []checker.Diagnostic{},
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestLoop(t *testing.T) {
+	tests := []test{
+		{
+			name: "A loop exited with break",
+			input: `
+				loop {
+					if true {
+						break
+					}
+				}`,
+			output: Program{
+				Statements: []Statement{
+					Loop{
+						Body: []Statement{
+							IfStatement{
+								Condition: BoolLiteral{Value: true},
+								Body:      []Statement{Break{}},
+							},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "A break that exits an inner loop doesn't count",
+			input: `
+				loop {
+					while true {
+						break
+					}
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InfiniteLoop, Msg: "This loop has no 'break' and will never end", Severity: checker.Warning},
+			},
+		},
+		{
+			name: "A while loop that is always true without a break",
+			input: `
+				mut count = 0
+				while true {
+					count =+ 1
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InfiniteLoop, Msg: "while condition is always true and loop has no break", Severity: checker.Warning},
+			},
+		},
+		{
+			name: "A while loop that is always true with a break",
+			input: `
+				while true {
+					break
+				}`,
+			output: Program{
+				Statements: []Statement{
+					WhileLoop{
+						Condition: BoolLiteral{Value: true},
+						Body:      []Statement{Break{}},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+	}
+
+	runTests(t, tests)
+}
+
 func TestInterpolatedStrings(t *testing.T) {
 	tests := []test{
 		{
@@ -384,6 +657,37 @@ func TestInterpolatedStrings(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "Escaped delimiter",
+			input: `"use \{{name}} for names"`,
+			output: Program{
+				Statements: []Statement{
+					StrLiteral{Value: `"use {{name}} for names"`},
+				},
+			},
+		},
+		{
+			name: "Escaped delimiter next to an interpolation",
+			input: `
+			let name = "world"
+			"\{{name}} is {{name}}\{{"`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name:  "name",
+						Type:  checker.StrType,
+						Value: StrLiteral{Value: `"world"`},
+					},
+					InterpolatedStr{
+						Chunks: []Expression{
+							StrLiteral{Value: "{{name}} is "},
+							Identifier{Name: "name", Type: checker.StrType},
+							StrLiteral{Value: "{{"},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	runTests(t, tests)
@@ -402,3 +706,90 @@ func TestComments(t *testing.T) {
 		},
 	})
 }
+
+func TestTrailingCommas(t *testing.T) {
+	numList := checker.ListType{ItemType: checker.NumType}
+	numMap := checker.MapType{KeyType: checker.StrType, ValueType: checker.NumType}
+	runTests(t, []test{
+		{
+			name:  "In a list",
+			input: `let items = [1, 2, 3,]`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name: "items",
+						Type: numList,
+						Value: ListLiteral{
+							Type: numList,
+							Items: []Expression{
+								NumLiteral{Value: "1"},
+								NumLiteral{Value: "2"},
+								NumLiteral{Value: "3"},
+							},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "In a map",
+			input: `let scores = ["a": 1,]`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name: "scores",
+						Type: numMap,
+						Value: MapLiteral{
+							Entries: []MapEntry{
+								{Key: `"a"`, Value: NumLiteral{Value: "1"}},
+							},
+							Type: numMap,
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "In parameters",
+			input: `fn double(x: Num,) Num { x * 2 }`,
+			output: Program{
+				Statements: []Statement{
+					FunctionDeclaration{
+						Name:       "double",
+						Parameters: []Parameter{{Name: "x", Type: checker.NumType}},
+						ReturnType: checker.NumType,
+						Body: []Statement{
+							BinaryExpression{
+								Left:     Identifier{Name: "x", Type: checker.NumType},
+								Operator: Multiply,
+								Right:    NumLiteral{Value: "2"},
+							},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+	})
+}
+
+func TestDiagnosticCodes(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "A type mismatch",
+			input: `let name: Str = 42`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Str, got Num"},
+			},
+		},
+		{
+			name:  "An undefined identifier",
+			input: `count <= 10`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.Undefined, Msg: "Undefined: 'count'"},
+			},
+		},
+	})
+}
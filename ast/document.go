@@ -4,31 +4,58 @@ import (
 	"strings"
 )
 
+// lines keep their indentation as a level so nesting documents doesn't rebuild every line.
+// the indentation is only written out when the document is rendered
+type line struct {
+	level int
+	text  string
+}
+
 type Document struct {
 	indentLevel int
-	lines       []string
+	indentUnit  string
+	lines       []line
 }
 
 func MakeDoc(content string) Document {
-	var lines []string
+	lines := []line{}
 	if content != "" {
-		contentLines := strings.Split(content, "\n")
-		lines = make([]string, len(contentLines))
-		for i, line := range contentLines {
-			lines[i] = line
+		for _, text := range strings.Split(content, "\n") {
+			lines = append(lines, line{text: text})
 		}
-	} else {
-		lines = make([]string, 0)
 	}
-	return Document{lines: lines, indentLevel: 0}
+	return Document{lines: lines, indentLevel: 0, indentUnit: "  "}
 }
 
 func (d Document) String() string {
-	return strings.Join(d.lines, "\n")
+	size := 0
+	for _, l := range d.lines {
+		size += l.level*len(d.indentUnit) + len(l.text) + 1
+	}
+
+	var builder strings.Builder
+	builder.Grow(size)
+	for i, l := range d.lines {
+		if i > 0 {
+			builder.WriteByte('\n')
+		}
+		for range l.level {
+			builder.WriteString(d.indentUnit)
+		}
+		builder.WriteString(l.text)
+	}
+	return builder.String()
 }
 
-func (d Document) indentation() string {
-	return strings.Repeat(" ", d.indentLevel*2)
+// whether the document would render as an empty string, without rendering it
+func (d Document) IsEmpty() bool {
+	return len(d.lines) == 0 || (len(d.lines) == 1 && d.lines[0].level == 0 && d.lines[0].text == "")
+}
+
+// sets what a single level of indentation is. the default is two spaces
+func (d *Document) SetIndentUnit(unit string) *Document {
+	d.indentUnit = unit
+	return d
 }
 
 func (d *Document) Indent() *Document {
@@ -41,27 +68,24 @@ func (d *Document) Dedent() *Document {
 	return d
 }
 
-func (d *Document) Line(line string) *Document {
-	d.lines = append(d.lines, d.indentation()+line)
+func (d *Document) Line(text string) *Document {
+	d.lines = append(d.lines, line{level: d.indentLevel, text: text})
 	return d
 }
 
 func (d *Document) Nest(doc Document) *Document {
-	d.Indent()
-	for i, line := range doc.lines {
-		// skip trailing empties
-		if i == len(doc.lines)-1 && line == "" {
-			continue
-		}
-		d.lines = append(d.lines, d.indentation()+line)
+	lines := doc.lines
+	// skip trailing empties
+	if last := len(lines) - 1; last >= 0 && lines[last].level == 0 && lines[last].text == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for _, l := range lines {
+		d.lines = append(d.lines, line{level: d.indentLevel + 1 + l.level, text: l.text})
 	}
-	d.Dedent()
 	return d
 }
 
 func (d *Document) Append(doc Document) *Document {
-	for _, line := range doc.lines {
-		d.lines = append(d.lines, line)
-	}
+	d.lines = append(d.lines, doc.lines...)
 	return d
 }
@@ -114,6 +114,99 @@ func TestMatchingOnEnums(t *testing.T) {
 				{Msg: "Missing case for 'Color::Green'"},
 			},
 		},
+		{
+			name: "Arms must be variants of the matched enum",
+			input: fmt.Sprintf(`%v
+				enum Size { Small, Large }
+				let light = Color::Red
+				match light {
+					Color::Red => "Stop",
+					Size::Large => "Big",
+					Color::Yellow => "Yield",
+					Color::Green => "Go"
+				}`, traffic_light_code),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Pattern 'Size::Large' is not a variant of 'Color'"},
+			},
+		},
+		{
+			name: "An invalid first arm doesn't decide the type of the others",
+			input: fmt.Sprintf(`%v
+				enum Size { Small, Large }
+				let light = Color::Red
+				let action: Str = match light {
+					Size::Small => 0,
+					Color::Red => "Stop",
+					Color::Yellow => "Yield",
+					Color::Green => "Go"
+				}`, traffic_light_code),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Pattern 'Size::Small' is not a variant of 'Color'"},
+			},
+		},
+		{
+			name: "Every arm is invalid",
+			input: fmt.Sprintf(`%v
+				enum Size { Small, Large }
+				let light = Color::Red
+				let action = match light {
+					Size::Small => "Small",
+					Size::Large => "Large"
+				}`, traffic_light_code),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Pattern 'Size::Small' is not a variant of 'Color'"},
+				{Msg: "Pattern 'Size::Large' is not a variant of 'Color'"},
+				{Msg: "Missing case for 'Color::Red'"},
+				{Msg: "Missing case for 'Color::Green'"},
+				{Msg: "Missing case for 'Color::Yellow'"},
+			},
+		},
+		{
+			name: "Arms must be existing variants",
+			input: fmt.Sprintf(`%v
+				let light = Color::Red
+				match light {
+					Color::Red => "Stop",
+					Color::Blue => "Calm",
+					_ => "Go"
+				}`, traffic_light_code),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Pattern 'Color::Blue' is not a variant of 'Color'"},
+			},
+		},
+		{
+			name: "A wildcard satisfies exhaustiveness",
+			input: fmt.Sprintf(`%v
+				let light = Color::Red
+				match light {
+					Color::Red => "Stop",
+					_ => "Go"
+				}`, traffic_light_code),
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "todo() can stand in for any arm",
+			input: fmt.Sprintf(`%v
+				let light = Color::Red
+				match light {
+					Color::Red => "Stop",
+					Color::Yellow => todo(),
+					Color::Green => "Go"
+				}`, traffic_light_code),
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "A wildcard must be the last arm",
+			input: fmt.Sprintf(`%v
+				let light = Color::Red
+				match light {
+					_ => "Go",
+					Color::Red => "Stop"
+				}`, traffic_light_code),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Unreachable match arm after wildcard"},
+			},
+		},
 		{
 			name: "Each case must return the same type",
 			input: fmt.Sprintf(`%v
@@ -190,3 +283,100 @@ func TestMatchingOnEnums(t *testing.T) {
 
 	runTests(t, tests)
 }
+
+func TestEnumsWithData(t *testing.T) {
+	shapeCode := `
+		enum Shape {
+			Circle(Num),
+			Rect(Num, Num),
+			Empty
+		}`
+	shape := checker.EnumType{
+		Name:     "Shape",
+		Variants: []string{"Circle", "Rect", "Empty"},
+		Payloads: map[string][]checker.Type{
+			"Circle": {checker.NumType},
+			"Rect":   {checker.NumType, checker.NumType},
+		},
+	}
+
+	runTests(t, []test{
+		{
+			name: "Constructing a variant with values",
+			input: fmt.Sprintf(`%s
+				Shape::Rect(2, 3)`, shapeCode),
+			output: Program{
+				Statements: []Statement{
+					EnumDefinition{Type: shape},
+					EnumVariantInstance{
+						Type:    shape,
+						Variant: "Rect",
+						Values:  []Expression{NumLiteral{Value: "2"}, NumLiteral{Value: "3"}},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Constructing a variant with the wrong number of values",
+			input: fmt.Sprintf(`%s
+				Shape::Circle(1, 2)`, shapeCode),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Expected 1 arguments, got 2"},
+			},
+		},
+		{
+			name: "Constructing a variant with the wrong type of value",
+			input: fmt.Sprintf(`%s
+				Shape::Circle("big")`, shapeCode),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected Num, got Str"},
+			},
+		},
+		{
+			name: "Binding a variant's values in a match",
+			input: fmt.Sprintf(`%s
+				let shape = Shape::Circle(2)
+				match shape {
+					Shape::Circle(radius) => radius * radius,
+					Shape::Rect(width, height) => width * height,
+					Shape::Empty => 0
+				}`, shapeCode),
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Binding the wrong number of values",
+			input: fmt.Sprintf(`%s
+				let shape = Shape::Circle(2)
+				match shape {
+					Shape::Circle(a, b) => a,
+					_ => 0
+				}`, shapeCode),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "'Shape::Circle' has 1 values, got 2 bindings"},
+			},
+		},
+	})
+}
+
+func TestEnumEquality(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Comparing variants of the same enum",
+			input: fmt.Sprintf(`%s
+				let light = Color::Red
+				light == Color::Green
+				light != Color::Yellow`, traffic_light_code),
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Comparing variants of different enums",
+			input: fmt.Sprintf(`%s
+				enum Size { Small, Large }
+				Color::Red == Size::Small`, traffic_light_code),
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidOperator, Msg: "Cannot compare 'Color' and 'Size'"},
+			},
+		},
+	})
+}
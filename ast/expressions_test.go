@@ -6,6 +6,52 @@ import (
 	checker "github.com/akonwi/ard/checker"
 )
 
+func TestNumLiteralFloat(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+	}{
+		{"42", 42},
+		{"0", 0},
+		{"3.14", 3.14},
+		{"0.5", 0.5},
+		{"1_000_000", 1000000},
+		{"1_000.25", 1000.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := (NumLiteral{Value: tt.value}).Float(); got != tt.want {
+				t.Errorf("NumLiteral{%s}.Float() = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsafeIntegers(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:        "The largest safe integer",
+			input:       `let big = 9007199254740991`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "Beyond the safe integer range",
+			input: `let big = 9_007_199_254_740_993`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.UnsafeInteger, Msg: "Numeric literal exceeds safe integer range and may lose precision", Severity: checker.Warning},
+			},
+		},
+		{
+			name:  "In a list",
+			input: `let big = [1, 9007199254740993]`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.UnsafeInteger, Msg: "Numeric literal exceeds safe integer range and may lose precision", Severity: checker.Warning},
+			},
+		},
+	})
+}
+
 func TestUnaryExpressions(t *testing.T) {
 	tests := []test{
 		{
@@ -45,6 +91,57 @@ func TestUnaryExpressions(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "Boolean negation with a symbol",
+			input: `let no = !true`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name:  "no",
+						Type:  checker.BoolType,
+						Value: UnaryExpression{Operator: Bang, Operand: BoolLiteral{Value: true}},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "Boolean negation with the keyword",
+			input: `let no = not true`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name:  "no",
+						Type:  checker.BoolType,
+						Value: UnaryExpression{Operator: Bang, Operand: BoolLiteral{Value: true}},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "Negating a number with '!'",
+			input: `!5`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidOperator, Msg: "The '!' operator can only be used on 'Bool'"},
+			},
+		},
+		{
+			name: "A negation is a Bool",
+			input: `
+				let count = 5
+				let flag: Bool = !count`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidOperator, Msg: "The '!' operator can only be used on 'Bool'"},
+			},
+		},
+		{
+			name:  "Negating a number with 'not'",
+			input: `not 5`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidOperator, Msg: "The 'not' operator can only be used on 'Bool'"},
+			},
+		},
 	}
 
 	runTests(t, tests)
@@ -473,7 +570,7 @@ func TestBinaryExpressions(t *testing.T) {
 			},
 			diagnostics: []checker.Diagnostic{
 				{
-					Msg: "The '==' operator can only be used between instances of 'Num', 'Str', or 'Bool'",
+					Msg: "Cannot compare 'Str' and 'Bool'",
 				},
 			},
 		},
@@ -513,7 +610,7 @@ func TestBinaryExpressions(t *testing.T) {
 			},
 			diagnostics: []checker.Diagnostic{
 				{
-					Msg: "The '==' operator can only be used between instances of 'Num', 'Str', or 'Bool'",
+					Msg: "Cannot compare 'Num' and 'Str'",
 				},
 			},
 		},
@@ -553,7 +650,7 @@ func TestBinaryExpressions(t *testing.T) {
 			},
 			diagnostics: []checker.Diagnostic{
 				{
-					Msg: "The '==' operator can only be used between instances of 'Num', 'Str', or 'Bool'",
+					Msg: "Cannot compare 'Bool' and 'Str'",
 				},
 			},
 		},
@@ -595,7 +692,7 @@ func TestBinaryExpressions(t *testing.T) {
 			},
 			diagnostics: []checker.Diagnostic{
 				{
-					Msg: "The '!=' operator can only be used between instances of 'Num', 'Str', or 'Bool'",
+					Msg: "Cannot compare 'Str' and 'Bool'",
 				},
 			},
 		},
@@ -635,7 +732,7 @@ func TestBinaryExpressions(t *testing.T) {
 			},
 			diagnostics: []checker.Diagnostic{
 				{
-					Msg: "The '!=' operator can only be used between instances of 'Num', 'Str', or 'Bool'",
+					Msg: "Cannot compare 'Num' and 'Str'",
 				},
 			},
 		},
@@ -675,7 +772,7 @@ func TestBinaryExpressions(t *testing.T) {
 			},
 			diagnostics: []checker.Diagnostic{
 				{
-					Msg: "The '!=' operator can only be used between instances of 'Num', 'Str', or 'Bool'",
+					Msg: "Cannot compare 'Bool' and 'Str'",
 				},
 			},
 		},
@@ -804,6 +901,50 @@ func TestBinaryExpressions(t *testing.T) {
 	runTests(t, tests)
 }
 
+func TestComparisons(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Comparing values of the same type",
+			input: `
+				struct Point { x: Num, y: Num }
+				let a = [1, 2]
+				let b = [3, 4]
+				a == b
+				Point{x: 1, y: 2} != Point{x: 3, y: 4}`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Comparing lists of different items",
+			input: `
+				let nums = [1, 2]
+				let strs = ["a", "b"]
+				nums == strs`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidOperator, Msg: "Cannot compare '[Num]' and '[Str]'"},
+			},
+		},
+		{
+			name: "Comparing different structs",
+			input: `
+				struct Point { x: Num, y: Num }
+				struct Size { x: Num, y: Num }
+				Point{x: 1, y: 2} == Size{x: 1, y: 2}`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidOperator, Msg: "Cannot compare 'Struct(Point)' and 'Struct(Size)'"},
+			},
+		},
+		{
+			name: "Comparing a list with a single value",
+			input: `
+				let nums = [1, 2]
+				nums != 1`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidOperator, Msg: "Cannot compare '[Num]' and 'Num'"},
+			},
+		},
+	})
+}
+
 func TestParenthesizedExpressions(t *testing.T) {
 	tests := []test{
 		{
@@ -868,3 +1009,578 @@ func TestMemberAccess(t *testing.T) {
 		},
 	})
 }
+
+func TestChainedMemberAccess(t *testing.T) {
+	uppercase := checker.StrType.GetProperty("uppercase").(checker.FunctionType)
+	name := Identifier{Name: "name", Type: checker.StrType}
+	runTests(t, []test{
+		{
+			name: "Each step is checked against the type of the previous one",
+			input: `
+				let name = "joe"
+				name.uppercase().size`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name:  "name",
+						Type:  checker.StrType,
+						Value: StrLiteral{Value: `"joe"`},
+					},
+					MemberAccess{
+						Target: MemberAccess{
+							Target:     name,
+							AccessType: Instance,
+							Member: FunctionCall{
+								Name: "uppercase",
+								Args: []Expression{},
+								Type: uppercase,
+							},
+						},
+						AccessType: Instance,
+						Member:     Identifier{Name: "size", Type: checker.NumType},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "A step that returns the wrong type for the next",
+			input: `
+				let name = "joe"
+				name.size.uppercase()`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.UnknownMember, Msg: "Method 'uppercase' not found on Num"},
+			},
+		},
+	})
+}
+
+func TestStrContains(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Searching for a substring",
+			input: `
+				let msg = "hello world"
+				let found: Bool = msg.contains("world")`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "The substring must be a Str",
+			input: `
+				let msg = "hello world"
+				msg.contains(1)`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Str, got Num"},
+			},
+		},
+	})
+}
+
+func TestStrMethods(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Splitting into a list of Str",
+			input: `
+				let csv = "a,b,c"
+				let parts: [Str] = csv.split(",")`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "The separator must be a Str",
+			input: `
+				let csv = "a,b,c"
+				csv.split(1)`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Str, got Num"},
+			},
+		},
+		{
+			name: "Trimming returns a Str",
+			input: `
+				let padded = "  hi  "
+				let trimmed: Num = padded.trim()`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Num, got Str"},
+			},
+		},
+		{
+			name: "Slicing returns a Str",
+			input: `
+				let greeting = "hello world"
+				let word: Str = greeting.slice(0, 5)
+				let rest: Str = greeting.slice(6)`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Slicing bounds must be Num",
+			input: `
+				let greeting = "hello world"
+				greeting.slice("h")`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Num, got Str"},
+			},
+		},
+		{
+			name: "Slicing takes a start and an optional end",
+			input: `
+				let greeting = "hello world"
+				greeting.slice(0, 5, 1)`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.ArgumentCount, Msg: "Expected 1 to 2 arguments, got 3"},
+			},
+		},
+	})
+}
+
+func TestNumConversions(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Formatting a number",
+			input: `
+				let count = 5
+				let label: Str = count.toStr()`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "Parsing a number",
+			input: `let parsed = Num.from("5")`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name: "parsed",
+						Type: checker.NumFrom.ReturnType,
+						Value: MemberAccess{
+							Target:     Identifier{Name: "Num", Type: checker.NumType},
+							AccessType: Static,
+							Member: FunctionCall{
+								Name: "from",
+								Args: []Expression{StrLiteral{Value: `"5"`}},
+								Type: checker.NumFrom,
+							},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "A parsed number is optional",
+			input: `
+				let maybe: Option<Num> = Num.from("5")
+				let count: Num = Num.from("5")`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Num, got Option<Num>"},
+			},
+		},
+		{
+			name:  "Only Str can be parsed",
+			input: `Num.from(5)`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Str, got Num"},
+			},
+		},
+		{
+			name:  "Unknown members of Num",
+			input: `Num.parse("5")`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.UnknownMember, Msg: "No member 'parse' on Num"},
+			},
+		},
+	})
+}
+
+func TestCasts(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "Formatting a Num as a Str",
+			input: `let label = 42 as Str`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name: "label",
+						Type: checker.StrType,
+						Value: Cast{
+							Expr: NumLiteral{Value: "42"},
+							Type: checker.StrType,
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Narrowing an optional",
+			input: `
+				let maybe: Option<Num> = some(42)
+				let count: Num = maybe as Num
+				let label: Str = maybe as Num`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Str, got Num"},
+			},
+		},
+		{
+			name: "Wrapping as an optional",
+			input: `
+				let flag = true
+				let maybe: Option<Bool> = flag as Option<Bool>`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "Nonsensical casts",
+			input: `let flag = "yes" as Bool`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidCast, Msg: "Cannot cast Str to Bool"},
+			},
+		},
+	})
+}
+
+func TestStrIndexing(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Reading a character",
+			input: `
+				let msg = "hello"
+				msg[0]`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name:  "msg",
+						Type:  checker.StrType,
+						Value: StrLiteral{Value: `"hello"`},
+					},
+					IndexAccess{
+						Target: Identifier{Name: "msg", Type: checker.StrType},
+						Index:  NumLiteral{Value: "0"},
+						Type:   checker.StrType,
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "A character is a Str",
+			input: `
+				let msg = "hello"
+				let first: Num = msg[0]`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Num, got Str"},
+			},
+		},
+		{
+			name: "The index must be a Num",
+			input: `
+				let msg = "hello"
+				msg["first"]`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidIndex, Msg: "A string index must be a 'Num'"},
+			},
+		},
+		{
+			name: "Characters can't be assigned",
+			input: `
+				mut msg = "hello"
+				msg[0] = "j"`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidAssignment, Msg: "Cannot assign to 'msg[0]'"},
+			},
+		},
+	})
+}
+
+func TestBlockExpressions(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "A block evaluates to its last expression",
+			input: `
+				let x = {
+					let a = 1
+					a + 1
+				}`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name: "x",
+						Type: checker.NumType,
+						Value: BlockExpression{
+							Type: checker.NumType,
+							Body: []Statement{
+								VariableDeclaration{
+									Name:  "a",
+									Type:  checker.NumType,
+									Value: NumLiteral{Value: "1"},
+								},
+								BinaryExpression{
+									Left:     Identifier{Name: "a", Type: checker.NumType},
+									Operator: Plus,
+									Right:    NumLiteral{Value: "1"},
+								},
+							},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "An empty block is Void",
+			input: `
+				fn noop() {
+					{}
+				}`,
+			output: Program{
+				Statements: []Statement{
+					FunctionDeclaration{
+						Name:       "noop",
+						Parameters: []Parameter{},
+						ReturnType: checker.VoidType,
+						Body: []Statement{
+							BlockExpression{Type: checker.VoidType, Body: []Statement{}},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Declarations inside a block do not leak",
+			input: `
+				let x = {
+					let a = 1
+					a
+				}
+				a`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Undefined: 'a'"},
+			},
+		},
+	})
+}
+
+func TestConditionalExpressions(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "Branches of the same type",
+			input: `let sign = 1 > 0 ? "+" : "-"`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name: "sign",
+						Type: checker.StrType,
+						Value: ConditionalExpression{
+							Condition: BinaryExpression{
+								Left:     NumLiteral{Value: "1"},
+								Operator: GreaterThan,
+								Right:    NumLiteral{Value: "0"},
+							},
+							Consequent:  StrLiteral{Value: `"+"`},
+							Alternative: StrLiteral{Value: `"-"`},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "The condition must be a Bool",
+			input: `let sign = 1 ? "+" : "-"`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "A conditional expression's condition must be a 'Bool' expression"},
+			},
+		},
+		{
+			name:  "Branches must have the same type",
+			input: `let sign = true ? "+" : 1`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected Str, got Num"},
+			},
+		},
+		{
+			name: "A branch that never finishes takes the type of the other",
+			input: `
+				let sign = false ? panic("no sign") : "+"
+				let size: Num = sign.size`,
+			diagnostics: []checker.Diagnostic{},
+		},
+	})
+}
+
+func TestMatchingOnBooleans(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Both values are handled",
+			input: `
+				let is_on = true
+				match is_on {
+					true => "on",
+					false => "off"
+				}`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name:  "is_on",
+						Type:  checker.BoolType,
+						Value: BoolLiteral{Value: true},
+					},
+					MatchExpression{
+						Subject: Identifier{Name: "is_on", Type: checker.BoolType},
+						Cases: []MatchCase{
+							{
+								Pattern: BoolLiteral{Value: true},
+								Body:    []Statement{StrLiteral{Value: `"on"`}},
+								Type:    checker.StrType,
+							},
+							{
+								Pattern: BoolLiteral{Value: false},
+								Body:    []Statement{StrLiteral{Value: `"off"`}},
+								Type:    checker.StrType,
+							},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Matching must be exhaustive",
+			input: `
+				let is_on = true
+				match is_on {
+					true => "on"
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Match is not exhaustive: missing false"},
+			},
+		},
+		{
+			name: "A wildcard covers the remaining value",
+			input: `
+				let is_on = true
+				match is_on {
+					true => "on",
+					_ => "off"
+				}`,
+			diagnostics: []checker.Diagnostic{},
+		},
+	})
+}
+
+func TestMatchSubjects(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Numbers can't be matched",
+			input: `
+				let count = 1
+				match count {
+					_ => "any"
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Cannot match on 'Num', only on an enum, a Bool or an optional"},
+			},
+		},
+		{
+			name: "Strings can't be matched",
+			input: `
+				let name = "Joe"
+				match name {
+					_ => "any"
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Cannot match on 'Str', only on an enum, a Bool or an optional"},
+			},
+		},
+	})
+}
+
+func TestMatchingOnOptionals(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Both arms are handled",
+			input: `
+				let maybe: Option<Num> = some(42)
+				match maybe {
+					some(x) => x * 2,
+					none => 0
+				}`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Matching must be exhaustive",
+			input: `
+				let maybe: Option<Num> = some(42)
+				match maybe {
+					some(x) => x * 2
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Match is not exhaustive: missing none"},
+			},
+		},
+		{
+			name: "The binding has the inner type",
+			input: `
+				let maybe: Option<Str> = none()
+				match maybe {
+					some(name) => name * 2,
+					none => 0
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "The '*' operator can only be used between instances of 'Num'"},
+			},
+		},
+	})
+}
+
+func TestTypeQuery(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "Reporting the type of an expression",
+			input: `let total = @type(1 + 2)`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name: "total",
+						Type: checker.NumType,
+						Value: TypeQuery{
+							Expr: BinaryExpression{
+								Left:     NumLiteral{Value: "1"},
+								Operator: Plus,
+								Right:    NumLiteral{Value: "2"},
+							},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeOf, Msg: "type of expression is Num", Severity: checker.Info},
+			},
+		},
+		{
+			name: "Reporting an inferred list type",
+			input: `
+				let names = ["Alice", "Bob"]
+				@type(names)`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeOf, Msg: "type of expression is [Str]", Severity: checker.Info},
+			},
+		},
+	})
+}
+
+func TestStatementsAsExpressions(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "A declaration in expression position",
+			input: `let x = (let y = 1)`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.NotAnExpression, Msg: "Expected an expression"},
+			},
+		},
+		{
+			name:  "A while loop in expression position",
+			input: `let x = (while true {})`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.NotAnExpression, Msg: "Expected an expression"},
+			},
+		},
+	})
+}
@@ -102,6 +102,103 @@ func TestFunctionDeclaration(t *testing.T) {
 	runTests(t, tests)
 }
 
+func TestParameterReassignment(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:        "Reading a parameter",
+			input:       `fn double(x: Num) Num { x * 2 }`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Reassigning a parameter",
+			input: `
+				fn double(x: Num) Num {
+					x = x * 2
+					x
+				}`,
+			diagnostics: []checker.Diagnostic{{Msg: "Cannot reassign parameter 'x'"}},
+		},
+		{
+			name: "Incrementing a parameter",
+			input: `
+				fn next(x: Num) Num {
+					x =+ 1
+					x
+				}`,
+			diagnostics: []checker.Diagnostic{{Msg: "Cannot reassign parameter 'x'"}},
+		},
+		{
+			name:        "Reassigning a parameter of an anonymous function",
+			input:       `let reset = (x: Num) { x = 0 }`,
+			diagnostics: []checker.Diagnostic{{Msg: "Cannot reassign parameter 'x'"}},
+		},
+		{
+			name: "Copying a parameter into a mutable variable",
+			input: `
+				fn next(x: Num) Num {
+					mut y = x
+					y =+ 1
+					y
+				}`,
+			diagnostics: []checker.Diagnostic{},
+		},
+	})
+}
+
+func TestMultipleReturnValues(t *testing.T) {
+	minmax := `fn minmax(xs: [Num]) (Num, Num) { (0, 0) }`
+	runTests(t, []test{
+		{
+			name:        "Returning a tuple",
+			input:       minmax,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Destructuring the returned tuple",
+			input: minmax + `
+				let (lo, hi) = minmax([3, 1, 2])
+				let range: Num = hi - lo`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Each element keeps its own type",
+			input: `
+				fn oldest() (Str, Num) { ("Alice", 30) }
+				let (name, age) = oldest()
+				let greeting: Str = name
+				let years: Num = age`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:        "Returning a tuple of the wrong types",
+			input:       `fn minmax(xs: [Num]) (Num, Num) { (0, "none") }`,
+			diagnostics: []checker.Diagnostic{{Msg: "Type mismatch: expected (Num, Num), got (Num, Str)"}},
+		},
+		{
+			name:        "Returning a tuple of the wrong size",
+			input:       `fn minmax(xs: [Num]) (Num, Num) { (0, 0, 0) }`,
+			diagnostics: []checker.Diagnostic{{Msg: "Type mismatch: expected (Num, Num), got (Num, Num, Num)"}},
+		},
+		{
+			name: "Binding fewer names than the tuple has elements",
+			input: minmax + `
+				let (lo) = minmax([3, 1, 2])`,
+			diagnostics: []checker.Diagnostic{{Msg: "Cannot bind 1 names from a tuple of 2 elements"}},
+		},
+		{
+			name: "Binding more names than the tuple has elements",
+			input: minmax + `
+				let (lo, hi, mid) = minmax([3, 1, 2])`,
+			diagnostics: []checker.Diagnostic{{Msg: "Cannot bind 3 names from a tuple of 2 elements"}},
+		},
+		{
+			name:        "A tuple pattern needs a tuple",
+			input:       `let (first, second) = [1, 2]`,
+			diagnostics: []checker.Diagnostic{{Msg: "Cannot destructure a '[Num]' as a tuple"}},
+		},
+	})
+}
+
 func TestFunctionCalls(t *testing.T) {
 	get_name := checker.FunctionType{
 		Name:       "get_name",
@@ -233,6 +330,142 @@ func TestFunctionCalls(t *testing.T) {
 	runTests(t, tests)
 }
 
+func TestPrint(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:        "Printing a string",
+			input:       `print("hello")`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Printing a number",
+			input: `
+				let count = 42
+				print(count)`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "print takes exactly one argument",
+			input: `print("hello", "world")`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Expected 1 arguments, got 2"},
+			},
+		},
+	})
+}
+
+func TestFunctionTypeDiagnostics(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Assigning an incompatible function",
+			input: `
+				mut callback = (x: Num) { x }
+				callback = (x: Str) { x }`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Expected a '(Num) Num' and received '(Str) Str'"},
+			},
+		},
+		{
+			name: "Mutating functions are not assignable to pure function types",
+			input: `
+				fn mut double(x: Num) Num { x * 2 }
+				mut callback = (x: Num) { x }
+				callback = double`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Expected a '(Num) Num' and received 'mut (Num) Num'"},
+			},
+		},
+	})
+}
+
+func TestTodoAndPanic(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "todo() satisfies any return type",
+			input: `
+				fn get_name() Str { todo() }
+				let name: Str = get_name()`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:        "panic() requires a message",
+			input:       `fn get_name() Str { panic() }`,
+			diagnostics: []checker.Diagnostic{{Msg: "Expected 1 arguments, got 0"}},
+		},
+		{
+			name:        "panic() with a message",
+			input:       `fn get_name() Str { panic("not yet") }`,
+			diagnostics: []checker.Diagnostic{},
+		},
+	})
+}
+
+func TestAssert(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "assert() with a message",
+			input: `
+				let count = 42
+				assert(count > 0, "count must be positive")`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:        "assert() without a message",
+			input:       `assert(1 < 2)`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:        "The condition must be a Bool",
+			input:       `assert(42, "not a condition")`,
+			diagnostics: []checker.Diagnostic{{Msg: "Type mismatch: expected Bool, got Num"}},
+		},
+		{
+			name:        "The message must be a Str",
+			input:       `assert(true, 42)`,
+			diagnostics: []checker.Diagnostic{{Msg: "Type mismatch: expected Str, got Num"}},
+		},
+	})
+}
+
+func TestMathBuiltins(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "min(), max() and abs() return a Num",
+			input: `
+				let low: Num = min(3, 7)
+				let high: Num = max(3, 7)
+				let distance: Num = abs(low - high)`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:        "min() takes exactly two arguments",
+			input:       `min(1, 2, 3)`,
+			diagnostics: []checker.Diagnostic{{Msg: "Expected 2 arguments, got 3"}},
+		},
+		{
+			name:        "max() only takes numbers",
+			input:       `max(1, "2")`,
+			diagnostics: []checker.Diagnostic{{Msg: "Type mismatch: expected Num, got Str"}},
+		},
+		{
+			name:        "abs() only takes numbers",
+			input:       `abs(true)`,
+			diagnostics: []checker.Diagnostic{{Msg: "Type mismatch: expected Num, got Bool"}},
+		},
+		{
+			name: "programs can declare their own min(), max() and assert()",
+			input: `
+				let max = 10
+				let limit: Num = max
+				fn min(xs: [Num]) Num { xs[0] }
+				let lowest: Num = min([3, 1])
+				fn assert(ok: Bool) Bool { ok }
+				let checked: Bool = assert(true)`,
+			diagnostics: []checker.Diagnostic{},
+		},
+	})
+}
+
 func TestAnonymousFunctions(t *testing.T) {
 	tests := []test{
 		{
@@ -288,3 +521,268 @@ func TestAnonymousFunctions(t *testing.T) {
 
 	runTests(t, tests)
 }
+
+func TestGenericFunctions(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "The type parameter is inferred from a list argument",
+			input: `
+				fn first<T>(xs: [T]) T { xs[0] }
+				let num: Num = first([1, 2, 3])
+				let str: Str = first(["a", "b"])`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "The inferred type flows to the result",
+			input: `
+				fn first<T>(xs: [T]) T { xs[0] }
+				let str: Str = first([1, 2, 3])`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected Str, got Num"},
+			},
+		},
+		{
+			name: "Inconsistent instantiations",
+			input: `
+				fn either<T>(a: T, b: T) T { a }
+				either(1, "two")`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected Num, got Str"},
+			},
+		},
+		{
+			name: "Members of an annotated list parameter",
+			input: `
+				fn count(xs: [Num]) Num { xs.size }
+				fn doubled(xs: [Num]) [Num] { xs.map((x) { x * 2 }) }
+				fn labels<T>(xs: [T]) [Str] { xs.map((x) { "item" }) }`,
+			diagnostics: []checker.Diagnostic{},
+		},
+	})
+}
+
+func TestResults(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Propagating an error with ?",
+			input: `
+				fn parse(input: Str) Result<Num> {
+					if input.size == 0 { err("empty input") }
+					ok(42)
+				}
+				fn double(input: Str) Result<Num> {
+					let num = parse(input)?
+					ok(num * 2)
+				}`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "? unwraps the ok type",
+			input: `
+				fn parse(input: Str) Result<Num> { ok(42) }
+				fn describe(input: Str) Result<Str> {
+					let num: Str = parse(input)?
+					ok(num)
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected Str, got Num"},
+			},
+		},
+		{
+			name: "? outside a Result returning function",
+			input: `
+				fn parse(input: Str) Result<Num> { ok(42) }
+				fn double(input: Str) Num {
+					parse(input)? * 2
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "'?' can only be used in a function that returns a Result"},
+			},
+		},
+		{
+			name: "? on a value that isn't a Result",
+			input: `
+				fn double(input: Num) Result<Num> {
+					ok(input? * 2)
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "'?' can only be used on a Result, got 'Num'"},
+			},
+		},
+		{
+			name: "? in an anonymous function inside a Result returning function",
+			input: `
+				fn parse(input: Str) Result<Num> { ok(42) }
+				fn total(inputs: [Str]) Result<[Num]> {
+					ok(inputs.map((input) { parse(input)? }))
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "'?' can only be used in a function that returns a Result"},
+			},
+		},
+		{
+			name: "? after an anonymous function in a Result returning function",
+			input: `
+				fn parse(input: Str) Result<Num> { ok(42) }
+				fn first(inputs: [Str]) Result<Num> {
+					let sizes = inputs.map((input) { input.size })
+					let num = parse(inputs[0])?
+					ok(num)
+				}`,
+			diagnostics: []checker.Diagnostic{},
+		},
+	})
+}
+
+func TestForwardReferences(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Calling a function declared further down",
+			input: `
+				fn greet(name: Str) Str { format(name) }
+				fn format(name: Str) Str { name }`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Mutually recursive functions",
+			input: `
+				fn is_even(n: Num) Bool { n == 0 or is_odd(n - 1) }
+				fn is_odd(n: Num) Bool { n > 0 and is_even(n - 1) }
+				is_even(4)`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "A signature using a struct declared further down",
+			input: `
+				fn make_point() Point { Point{ x: 0, y: 0 } }
+				struct Point { x: Num, y: Num }`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Forward calls are still type checked",
+			input: `
+				fn total() Num { add("1", 2) }
+				fn add(x: Num, y: Num) Num { x + y }`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected Num, got Str"},
+			},
+		},
+	})
+}
+
+func TestDuplicateTopLevelNames(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Declaring a function twice",
+			input: `
+				fn greet() Str { "hi" }
+				fn greet() Str { "hello" }`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.Duplicate, Msg: "'greet' is already declared at line 2"},
+			},
+		},
+		{
+			name: "A function and a struct sharing a name",
+			input: `
+				struct Greeting { text: Str }
+
+				fn Greeting() Str { "hi" }`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.Duplicate, Msg: "'Greeting' is already declared at line 2"},
+			},
+		},
+		{
+			name: "Declaring a function without a return annotation twice",
+			input: `
+				fn greet() { print("hi") }
+				fn greet() { print("hello") }`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.Duplicate, Msg: "'greet' is already declared at line 2"},
+			},
+		},
+		{
+			name: "Declaring a struct twice",
+			input: `
+				struct Greeting { text: Str }
+				struct Greeting { words: [Str] }`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.Duplicate, Msg: "'Greeting' is already declared at line 2"},
+			},
+		},
+		{
+			name: "Distinct names",
+			input: `
+				struct Greeting { text: Str }
+				fn greet() Greeting { Greeting{ text: "hi" } }`,
+			diagnostics: []checker.Diagnostic{},
+		},
+	})
+}
+
+func TestDefer(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Deferring in a function body",
+			input: `
+				fn work() Num {
+					defer print("done")
+					defer print("cleaning up")
+					42
+				}
+				let cleanup = () {
+					defer print("done")
+				}`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "Deferring at the top level",
+			input: `defer print("done")`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.NotInFunction, Msg: "'defer' can only be used directly in a function body"},
+			},
+		},
+		{
+			name: "Deferring in a nested block",
+			input: `
+				fn work(ready: Bool) {
+					if ready {
+						defer print("done")
+					}
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.NotInFunction, Msg: "'defer' can only be used directly in a function body"},
+			},
+		},
+	})
+}
+
+func TestInfiniteRecursion(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Unconditionally calling itself with the same arguments",
+			input: `
+				fn loop(n: Num) Num { loop(n) }`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InfiniteRecursion, Msg: "Possible infinite recursion", Severity: checker.Warning},
+			},
+		},
+		{
+			name: "A guarded recursive function",
+			input: `
+				fn count_down(n: Num) Num {
+					let done = n == 0
+					match done {
+						true => 0,
+						false => count_down(n - 1)
+					}
+				}`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Recursing with different arguments",
+			input: `
+				fn forever(n: Num) Num { forever(n + 1) }`,
+			diagnostics: []checker.Diagnostic{},
+		},
+	})
+}
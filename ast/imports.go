@@ -0,0 +1,78 @@
+package ast
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/akonwi/ard/checker"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// `use lib/util` makes the module at lib/util.kon available as `util`
+type Import struct {
+	BaseNode
+	Path string
+	Name string
+}
+
+func (i Import) String() string {
+	return fmt.Sprintf("Import(%s)", i.Path)
+}
+
+// makes @module importable as `use @importPath`. modules must be added before parsing
+func (p *Parser) AddModule(importPath string, module checker.ModuleType) {
+	p.modules[importPath] = module
+}
+
+// the module other files see when they import this one, made of its top-level functions, structs and enums
+func (p *Parser) Module(name string) checker.ModuleType {
+	members := make(map[string]checker.Type)
+	p.scope.Each(func(symbol checker.Symbol, node *tree_sitter.Node) {
+		// builtins have no declaring node
+		if node == nil {
+			return
+		}
+		switch symbol := symbol.(type) {
+		case checker.FunctionType, checker.StructType, checker.EnumType:
+			members[symbol.GetName()] = symbol.GetType()
+		}
+	})
+	return checker.ModuleType{Name: name, Members: members}
+}
+
+func (p *Parser) parseImport(node *tree_sitter.Node) (Statement, error) {
+	pathNode := p.mustChild(node, "path")
+	importPath := p.text(pathNode)
+	name := path.Base(importPath)
+
+	module, ok := p.modules[importPath]
+	if !ok {
+		msg := fmt.Sprintf("Cannot find module '%s'", importPath)
+		p.typeErrors = append(p.typeErrors, checker.MakeError(checker.Undefined, msg, pathNode))
+	}
+	module.Name = name
+	p.declare(name, module, pathNode)
+
+	return Import{
+		BaseNode: BaseNode{TSNode: node},
+		Path:     importPath,
+		Name:     name,
+	}, nil
+}
+
+// the paths imported by a program, in source order.
+// this only reads the syntax tree so modules can be resolved before any of them are type checked
+func ImportPaths(source []byte, tree *tree_sitter.Tree) []string {
+	rootNode := tree.RootNode()
+	paths := []string{}
+	for i := range rootNode.NamedChildCount() {
+		child := rootNode.NamedChild(i).NamedChild(0)
+		if child == nil || child.GrammarName() != "import" {
+			continue
+		}
+		if pathNode := child.ChildByFieldName("path"); pathNode != nil {
+			paths = append(paths, string(source[pathNode.StartByte():pathNode.EndByte()]))
+		}
+	}
+	return paths
+}
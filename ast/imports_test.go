@@ -0,0 +1,74 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/akonwi/ard/checker"
+)
+
+// parses @source as the module `util`, to be imported by other tests
+func parseUtil(t *testing.T, source string) checker.ModuleType {
+	t.Helper()
+	parser := NewParser([]byte(source), tsParser.Parse([]byte(source), nil))
+	if _, err := parser.Parse(); err != nil {
+		t.Fatalf("Error parsing module: %v", err)
+	}
+	return parser.Module("util")
+}
+
+func TestQualifiedModuleAccess(t *testing.T) {
+	util := parseUtil(t, `
+fn greet(name: Str) Str { "Hello, {{name}}" }
+struct Person { name: Str }
+let internal = 1`)
+	if _, ok := util.Members["greet"].(checker.FunctionType); !ok {
+		t.Errorf("Expected 'greet' to be a member of the module, got %v", util.Members)
+	}
+	if _, ok := util.Members["internal"]; ok {
+		t.Errorf("Top-level variables should not be module members")
+	}
+
+	modules := map[string]checker.ModuleType{"lib/util": util}
+	runTests(t, []test{
+		{
+			name: "Calling an imported function",
+			input: `
+use lib/util
+util.greet("Alice")`,
+			diagnostics: []checker.Diagnostic{},
+			modules:     modules,
+		},
+		{
+			name: "Arguments are checked against the imported signature",
+			input: `
+use lib/util
+util.greet(42)`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Str, got Num"},
+			},
+			modules: modules,
+		},
+		{
+			name: "Calling a function the module doesn't declare",
+			input: `
+use lib/util
+util.farewell("Alice")`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.UnknownMember, Msg: "No member 'farewell' in 'util' module"},
+			},
+			modules: modules,
+		},
+	})
+}
+
+func TestUnresolvedImport(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "Importing a module that wasn't provided",
+			input: `use lib/missing`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.Undefined, Msg: "Cannot find module 'lib/missing'"},
+			},
+		},
+	})
+}
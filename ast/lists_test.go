@@ -30,7 +30,7 @@ func TestVariables(t *testing.T) {
 					VariableDeclaration{
 						Mutable: false,
 						Name:    "strings",
-						Type:    &checker.ListType{ItemType: checker.StrType},
+						Type:    checker.ListType{ItemType: checker.StrType},
 						Value: ListLiteral{
 							Type: checker.ListType{ItemType: checker.NumType},
 							Items: []Expression{
@@ -54,7 +54,7 @@ func TestVariables(t *testing.T) {
 					VariableDeclaration{
 						Mutable: false,
 						Name:    "numbers",
-						Type:    &checker.ListType{ItemType: checker.NumType},
+						Type:    checker.ListType{ItemType: checker.NumType},
 						Value: ListLiteral{
 							Type: checker.ListType{ItemType: checker.NumType},
 							Items: []Expression{
@@ -146,7 +146,276 @@ func TestListApi(t *testing.T) {
 				{Msg: "Type mismatch: expected (Num) Out?, got (Str) Str"},
 			},
 		},
+		{
+			name: ".map returns a list of the callback's return type",
+			input: `
+				let list = [1,2,3]
+				let strings: [Str] = list.map((num) { "{{num}}" })
+				let bad: [Num] = list.map((num) { "{{num}}" })`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected [Num], got [Str]"},
+			},
+		},
+		{
+			name: ".filter preserves the element type",
+			input: `
+				let list = [1,2,3]
+				let evens: [Num] = list.filter((num) { num % 2 == 0 })`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: ".filter callback must return a Bool",
+			input: `
+				let list = [1,2,3]
+				list.filter((num) { num * 2 })`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected (Num) Bool, got (Num) Num"},
+			},
+		},
+		{
+			name: ".reduce returns the accumulator type",
+			input: `
+				let list = [1,2,3]
+				let sum: Num = list.reduce(0, (acc, num) { acc + num })
+				let joined: Num = list.reduce("", (acc, num) { "{{acc}}{{num}}" })`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected Num, got Str"},
+			},
+		},
+		{
+			name: ".contains takes an element and returns a Bool",
+			input: `
+				let list = [1,2,3]
+				let found: Bool = list.contains(2)`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: ".join combines a list of Str",
+			input: `
+				let names = ["jane", "joe"]
+				let joined: Str = names.join(", ")`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: ".join requires a list of Str",
+			input: `
+				let list = [1,2,3]
+				list.join(", ")`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Cannot join a list of 'Num', only a list of 'Str'"},
+			},
+		},
+		{
+			name: ".contains must be given an element",
+			input: `
+				let list = [1,2,3]
+				list.contains("two")`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Num, got Str"},
+			},
+		},
+		{
+			name: ".slice returns a list of the same items",
+			input: `
+				let list = [1,2,3]
+				let middle: [Num] = list.slice(1, 2)
+				let tail: [Num] = list.slice(1)
+				let wrong: [Str] = list.slice(1)`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected [Str], got [Num]"},
+			},
+		},
+		{
+			name: ".slice bounds must be Num",
+			input: `
+				let list = [1,2,3]
+				list.slice(0, "end")`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Num, got Str"},
+			},
+		},
 	}
 
 	runTests(t, tests)
 }
+
+func TestListSpread(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Spreading a list into a list literal",
+			input: `
+				let xs = [1, 2, 3]
+				let more: [Num] = [...xs, 4]
+				let front: [Num] = [0, ...xs]`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Spreading a list of other items",
+			input: `
+				let names = ["a", "b"]
+				[1, ...names]`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Cannot spread a '[Str]' into a list of 'Num'"},
+			},
+		},
+		{
+			name: "Spreading something that isn't a list",
+			input: `
+				let count = 3
+				[1, ...count]`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Cannot spread a 'Num' into a list"},
+			},
+		},
+	})
+}
+
+func TestListDestructuring(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Destructuring a list binds the element type",
+			input: `
+				let list = [1, 2, 3]
+				let [first, second] = list
+				let sum: Num = first + second`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Destructuring a tuple binds positional types",
+			input: `
+				let pair = ("Alice", 30)
+				let [name, age] = pair
+				let greeting: Str = name
+				let years: Num = age`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Destructuring an annotated list",
+			input: `
+				let list: List<Num> = [1, 2, 3]
+				let [first, second] = list
+				let sum: Num = first + second`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Binding more names than a tuple has elements",
+			input: `
+				let pair = ("Alice", 30)
+				let [name, age, height] = pair`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Cannot bind 3 names from a tuple of 2 elements"},
+			},
+		},
+	})
+}
+
+func TestListElementAssignment(t *testing.T) {
+	numList := checker.MakeList(checker.NumType)
+	runTests(t, []test{
+		{
+			name: "Valid element assignment",
+			input: `
+				mut items = [1, 2, 3]
+				items[0] = 5`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Mutable: true,
+						Name:    "items",
+						Type:    numList,
+						Value: ListLiteral{
+							Type: numList,
+							Items: []Expression{
+								NumLiteral{Value: "1"},
+								NumLiteral{Value: "2"},
+								NumLiteral{Value: "3"},
+							},
+						},
+					},
+					IndexAssignment{
+						Target:   Identifier{Name: "items", Type: numList},
+						Index:    NumLiteral{Value: "0"},
+						Operator: Assign,
+						Value:    NumLiteral{Value: "5"},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Assigning the wrong type",
+			input: `
+				mut items = [1, 2, 3]
+				items[0] = "five"`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Expected a 'Num' and received 'Str'"},
+			},
+		},
+		{
+			name: "The index must be a Num",
+			input: `
+				mut items = [1, 2, 3]
+				items["first"] = 5`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "A list index must be a 'Num'"},
+			},
+		},
+		{
+			name: "Assigning through an immutable list",
+			input: `
+				let items = [1, 2, 3]
+				items[0] = 5`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "'items' is not mutable"},
+			},
+		},
+		{
+			name: "Incrementing an element",
+			input: `
+				mut items = [1, 2, 3]
+				items[0] =+ 1`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Mutable: true,
+						Name:    "items",
+						Type:    numList,
+						Value: ListLiteral{
+							Type: numList,
+							Items: []Expression{
+								NumLiteral{Value: "1"},
+								NumLiteral{Value: "2"},
+								NumLiteral{Value: "3"},
+							},
+						},
+					},
+					IndexAssignment{
+						Target:   Identifier{Name: "items", Type: numList},
+						Index:    NumLiteral{Value: "0"},
+						Operator: Increment,
+						Value:    NumLiteral{Value: "1"},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Decrementing an element of an immutable list",
+			input: `
+				let items = [1, 2, 3]
+				items[0] =- 1`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "'items' is not mutable"},
+			},
+		},
+		{
+			name: "Incrementing a Str element",
+			input: `
+				mut names = ["a", "b"]
+				names[0] =+ "c"`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidOperator, Msg: "'=+' can only be used with 'Num'"},
+			},
+		},
+	})
+}
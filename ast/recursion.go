@@ -0,0 +1,52 @@
+package ast
+
+import "github.com/akonwi/ard/checker"
+
+// warns when a function calls itself with its own arguments before anything could stop it.
+// this is a heuristic: only straight-line statements may precede the call,
+// so any branch, loop, or change to a parameter is treated as a possible base case
+func (p *Parser) checkRecursion(decl FunctionDeclaration) {
+	params := make(map[string]bool, len(decl.Parameters))
+	for _, param := range decl.Parameters {
+		params[param.Name] = true
+	}
+
+	for _, statement := range decl.Body {
+		var call FunctionCall
+		switch statement := statement.(type) {
+		case FunctionCall:
+			call = statement
+		case VariableDeclaration:
+			if params[statement.Name] {
+				return
+			}
+			value, ok := statement.Value.(FunctionCall)
+			if !ok {
+				continue
+			}
+			call = value
+		case Comment:
+			continue
+		default:
+			return
+		}
+
+		if call.Name == decl.Name && passesParameters(call, decl.Parameters) {
+			p.typeErrors = append(p.typeErrors, checker.MakeWarning(checker.InfiniteRecursion, "Possible infinite recursion", call.TSNode))
+			return
+		}
+	}
+}
+
+// whether each argument of @call is the matching parameter, unchanged
+func passesParameters(call FunctionCall, params []Parameter) bool {
+	if len(call.Args) != len(params) {
+		return false
+	}
+	for i, arg := range call.Args {
+		if identifier, ok := arg.(Identifier); !ok || identifier.Name != params[i].Name {
+			return false
+		}
+	}
+	return true
+}
@@ -20,6 +20,10 @@ func TestStructDefinitions(t *testing.T) {
 			"employed": checker.BoolType,
 		},
 	}
+	pointStruct := checker.StructType{
+		Name:   "Point",
+		Fields: map[string]checker.Type{"x": checker.NumType, "y": checker.NumType},
+	}
 
 	tests := []test{
 		{
@@ -50,6 +54,43 @@ func TestStructDefinitions(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "A field of a struct declared further down",
+			input: `
+				struct Line { start: Point, end: Point }
+				struct Point { x: Num, y: Num }`,
+			output: Program{
+				Statements: []Statement{
+					StructDefinition{
+						Type: checker.StructType{
+							Name: "Line",
+							Fields: map[string]checker.Type{
+								"start": pointStruct,
+								"end":   pointStruct,
+							},
+						},
+					},
+					StructDefinition{
+						Type: pointStruct,
+					},
+				},
+			},
+		},
+		{
+			name: "A struct that refers to itself",
+			input: `
+				struct Tree { value: Num, children: [Tree] }
+				fn count(tree: Tree) Num { tree.children.size }`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "A field of an undefined type",
+			input: `
+				struct Line { start: Point }`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.Undefined, Msg: "Undefined: 'Point'"},
+			},
+		},
 	}
 
 	runTests(t, tests)
@@ -104,6 +145,18 @@ func TestInstantiatingStructs(t *testing.T) {
 				{Msg: "Missing field 'age' in struct 'Person'"},
 			},
 		},
+		{
+			name: "Missing several fields",
+			input: fmt.Sprintf(`%s
+				Person { size: "xl" }
+			`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "'size' is not a field of 'Person'"},
+				{Msg: "Missing field 'age' in struct 'Person'"},
+				{Msg: "Missing field 'employed' in struct 'Person'"},
+				{Msg: "Missing field 'name' in struct 'Person'"},
+			},
+		},
 		{
 			name: "Correctly instantiating a struct with fields",
 			input: fmt.Sprintf(`%s
@@ -125,11 +178,98 @@ func TestInstantiatingStructs(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Fields given by variables and expressions",
+			input: fmt.Sprintf(`%s
+				let name = "John"
+				let years = 22
+				Person { name: name, age: years + 1, employed: years > 18 }
+			`, personStructCode),
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "A field given an expression of the wrong type",
+			input: fmt.Sprintf(`%s
+				let years = 22
+				Person { name: years + 1, age: years, employed: true }
+			`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected Str, got Num"},
+			},
+		},
 	}
 
 	runTests(t, tests)
 }
 
+func TestStructDefaults(t *testing.T) {
+	personStruct := checker.StructType{
+		Name: "Person",
+		Fields: map[string]checker.Type{
+			"name":     checker.StrType,
+			"age":      checker.NumType,
+			"employed": checker.BoolType,
+		},
+	}
+	defaults := []StructValue{
+		{Name: "age", Value: NumLiteral{Value: "0"}},
+		{Name: "employed", Value: BoolLiteral{Value: false}},
+	}
+
+	runTests(t, []test{
+		{
+			name: "Omitted fields take their defaults",
+			input: `
+				struct Person {
+					name: Str,
+					age: Num = 0,
+					employed: Bool = false
+				}
+				Person { name: "Joe", employed: true }`,
+			output: Program{
+				Statements: []Statement{
+					StructDefinition{
+						Type:     personStruct,
+						Defaults: defaults,
+					},
+					StructInstance{
+						Type: personStruct,
+						Properties: []StructValue{
+							{Name: "name", Value: StrLiteral{Value: `"Joe"`}},
+							{Name: "employed", Value: BoolLiteral{Value: true}},
+							{Name: "age", Value: NumLiteral{Value: "0"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "Fields without defaults are still required",
+			input: `
+				struct Person {
+					name: Str,
+					age: Num = 0,
+					employed: Bool = false
+				}
+				Person { age: 30 }`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Missing field 'name' in struct 'Person'"},
+			},
+		},
+		{
+			name: "A default must match the field's type",
+			input: `
+				struct Person {
+					name: Str,
+					age: Num = "zero"
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected Num, got Str"},
+			},
+		},
+	})
+}
+
 func TestStructFieldAccess(t *testing.T) {
 	personStructCode := `
 		struct Person {
@@ -200,3 +340,376 @@ func TestStructFieldAccess(t *testing.T) {
 
 	runTests(t, tests)
 }
+
+func TestStructDestructuring(t *testing.T) {
+	personStructCode := `
+		struct Person {
+			name: Str,
+			age: Num,
+			employed: Bool
+		}`
+	tests := []test{
+		{
+			name: "Valid destructuring",
+			input: fmt.Sprintf(`%s
+				let person = Person { name: "Bobby", age: 12, employed: false }
+				let { name, age } = person
+				let greeting: Str = name
+				let years: Num = age`, personStructCode),
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Destructuring unknown fields",
+			input: fmt.Sprintf(`%s
+				let person = Person { name: "Bobby", age: 12, employed: false }
+				let { name, height } = person`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "No field 'height' in 'Person' struct"},
+			},
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestFrozenStructs(t *testing.T) {
+	personStructCode := `
+		struct Person {
+			name: Str,
+			age: Num
+		}`
+	tests := []test{
+		{
+			name: "Fields of a let struct cannot be reassigned",
+			input: fmt.Sprintf(`%s
+				let person = Person { name: "Bobby", age: 12 }
+				person.age = 13`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "'person' is not mutable"},
+			},
+		},
+		{
+			name: "Fields of a mut struct can be reassigned",
+			input: fmt.Sprintf(`%s
+				mut person = Person { name: "Bobby", age: 12 }
+				person.age = 13`, personStructCode),
+			diagnostics: []checker.Diagnostic{},
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestStructFieldAssignment(t *testing.T) {
+	personStructCode := `
+		struct Person {
+			name: Str,
+			age: Num
+		}`
+	personStruct := checker.StructType{
+		Name: "Person",
+		Fields: map[string]checker.Type{
+			"name": checker.StrType,
+			"age":  checker.NumType,
+		},
+	}
+	tests := []test{
+		{
+			name: "Valid field assignment",
+			input: fmt.Sprintf(`%s
+				mut person = Person { name: "Bobby", age: 12 }
+				person.age = 31`, personStructCode),
+			output: Program{
+				Statements: []Statement{
+					StructDefinition{Type: personStruct},
+					VariableDeclaration{
+						Mutable: true,
+						Name:    "person",
+						Type:    personStruct,
+						Value: StructInstance{
+							Type: personStruct,
+							Properties: []StructValue{
+								{Name: "name", Value: StrLiteral{Value: `"Bobby"`}},
+								{Name: "age", Value: NumLiteral{Value: "12"}},
+							},
+						},
+					},
+					MemberAssignment{
+						Target:   Identifier{Name: "person", Type: personStruct},
+						Member:   "age",
+						Operator: Assign,
+						Value:    NumLiteral{Value: "31"},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Assigning through an immutable binding",
+			input: fmt.Sprintf(`%s
+				let person = Person { name: "Bobby", age: 12 }
+				person.name = "Bob"`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "'person' is not mutable"},
+			},
+		},
+		{
+			name: "Assigning the wrong type",
+			input: fmt.Sprintf(`%s
+				mut person = Person { name: "Bobby", age: 12 }
+				person.age = "old"`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Expected a 'Num' and received 'Str'"},
+			},
+		},
+		{
+			name: "Assigning an unknown field",
+			input: fmt.Sprintf(`%s
+				mut person = Person { name: "Bobby", age: 12 }
+				person.height = 6`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "No field 'height' in 'Person' struct"},
+			},
+		},
+		{
+			name: "Incrementing a field",
+			input: fmt.Sprintf(`%s
+				mut person = Person { name: "Bobby", age: 12 }
+				person.age =+ 1`, personStructCode),
+			output: Program{
+				Statements: []Statement{
+					StructDefinition{Type: personStruct},
+					VariableDeclaration{
+						Mutable: true,
+						Name:    "person",
+						Type:    personStruct,
+						Value: StructInstance{
+							Type: personStruct,
+							Properties: []StructValue{
+								{Name: "name", Value: StrLiteral{Value: `"Bobby"`}},
+								{Name: "age", Value: NumLiteral{Value: "12"}},
+							},
+						},
+					},
+					MemberAssignment{
+						Target:   Identifier{Name: "person", Type: personStruct},
+						Member:   "age",
+						Operator: Increment,
+						Value:    NumLiteral{Value: "1"},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Decrementing a field through an immutable binding",
+			input: fmt.Sprintf(`%s
+				let person = Person { name: "Bobby", age: 12 }
+				person.age =- 1`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "'person' is not mutable"},
+			},
+		},
+		{
+			name: "Incrementing a Str field",
+			input: fmt.Sprintf(`%s
+				mut person = Person { name: "Bobby", age: 12 }
+				person.name =+ "by"`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidOperator, Msg: "'=+' can only be used with 'Num'"},
+			},
+		},
+		{
+			name: "Incrementing a list property",
+			input: `
+				mut items = [1, 2, 3]
+				items.size =+ 1`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidAssignment, Msg: "Cannot assign to 'items.size'"},
+			},
+		},
+	}
+
+	runTests(t, tests)
+}
+
+func TestStructEquality(t *testing.T) {
+	pointStructCode := `
+		struct Point { x: Num, y: Num }
+		struct Size { width: Num, height: Num }`
+	runTests(t, []test{
+		{
+			name: "Comparing instances of the same struct",
+			input: fmt.Sprintf(`%s
+				let origin = Point{x: 0, y: 0}
+				origin == Point{x: 1, y: 2}
+				origin != Point{x: 0, y: 0}`, pointStructCode),
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Comparing instances of different structs",
+			input: fmt.Sprintf(`%s
+				Point{x: 0, y: 0} == Size{width: 0, height: 0}`, pointStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidOperator, Msg: "Cannot compare 'Struct(Point)' and 'Struct(Size)'"},
+			},
+		},
+	})
+}
+
+func TestStructSpread(t *testing.T) {
+	personStructCode := `
+		struct Person {
+			name: Str,
+			age: Num,
+			employed: Bool = false
+		}
+		struct Pet { name: Str }
+		let base = Person{ name: "Joe", age: 30 }`
+	runTests(t, []test{
+		{
+			name: "Spreading an instance fills in the fields that aren't given",
+			input: fmt.Sprintf(`%s
+				let older = Person{ ...base, age: 31 }`, personStructCode),
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "The given fields are still checked",
+			input: fmt.Sprintf(`%s
+				Person{ ...base, age: "old", height: 2 }`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected Num, got Str"},
+				{Msg: "'height' is not a field of 'Person'"},
+			},
+		},
+		{
+			name: "Spreading an instance of another struct",
+			input: fmt.Sprintf(`%s
+				let pet = Pet{ name: "Rex" }
+				Person{ ...pet, age: 3 }`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Cannot spread a 'Struct(Pet)' into 'Person'"},
+			},
+		},
+	})
+}
+
+func TestObjectLiterals(t *testing.T) {
+	personType := checker.StructType{Fields: map[string]checker.Type{
+		"name": checker.StrType,
+		"age":  checker.NumType,
+	}}
+	runTests(t, []test{
+		{
+			name:  "An object literal is an anonymous struct",
+			input: `let person = { name: "Joe", age: 42 }`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name: "person",
+						Type: personType,
+						Value: StructInstance{
+							Type: personType,
+							Properties: []StructValue{
+								{Name: "name", Value: StrLiteral{Value: `"Joe"`}},
+								{Name: "age", Value: NumLiteral{Value: "42"}},
+							},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Accessing the fields of an object literal",
+			input: `
+				let person = { name: "Joe", age: 42 }
+				let name: Str = person.name
+				person.height`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.UnknownMember, Msg: "No field 'height' in '{age: Num, name: Str}' struct"},
+			},
+		},
+		{
+			name: "Object literals with the same fields are assignable to each other",
+			input: `
+				mut person = { name: "Joe", age: 42 }
+				person = { age: 30, name: "Jane" }
+				person = { name: "Jim" }`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Expected a '{age: Num, name: Str}' and received '{name: Str}'"},
+			},
+		},
+		{
+			name: "Fields given by variables and expressions",
+			input: `
+				let name = "Joe"
+				let person = { name: name, age: 40 + 2 }
+				let age: Num = person.age
+				let greeting: Str = person.name`,
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "Duplicate fields",
+			input: `let point = { x: 1, x: 2 }`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.Duplicate, Msg: "Duplicate field 'x' in object literal"},
+			},
+		},
+	})
+}
+
+func TestOptionalMemberAccess(t *testing.T) {
+	personStructCode := `
+		struct Person {
+			name: Str,
+			age: Num
+		}`
+	tests := []test{
+		{
+			name: "Accessing a field of an optional struct",
+			input: fmt.Sprintf(`%s
+				let person: Option<Person> = none()
+				let name: Option<Str> = person?.name`, personStructCode),
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "The result is optional",
+			input: fmt.Sprintf(`%s
+				let person: Option<Person> = none()
+				let age: Num = person?.age`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Num, got Option<Num>"},
+			},
+		},
+		{
+			name: "Accessing a non-existent field",
+			input: fmt.Sprintf(`%s
+				let person: Option<Person> = none()
+				person?.foobar`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.UnknownMember, Msg: "No field 'foobar' in 'Person' struct"},
+			},
+		},
+		{
+			name: "The target must be optional",
+			input: fmt.Sprintf(`%s
+				let person = Person { name: "Bobby", age: 12 }
+				person?.name`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.NotOptional, Msg: "'Struct(Person)' is not optional, use '.' instead of '?.'"},
+			},
+		},
+		{
+			name: "Members of an optional result need '?.' too",
+			input: fmt.Sprintf(`%s
+				let person: Option<Person> = none()
+				person?.name.size`, personStructCode),
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.UnknownMember, Msg: "'Option<Str>' is optional, use '?.' instead of '.'"},
+			},
+		},
+	}
+
+	runTests(t, tests)
+}
@@ -0,0 +1,59 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/akonwi/ard/checker"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+const ignoreDirective = "kon:ignore"
+
+// collects the codes silenced by `// kon:ignore K001 K002` comments, by row.
+// a directive applies to its own line and the line after it
+func (p *Parser) suppressions() map[uint]map[checker.Code]bool {
+	suppressed := make(map[uint]map[checker.Code]bool)
+	if p.tree == nil {
+		return suppressed
+	}
+
+	var visit func(node *tree_sitter.Node)
+	visit = func(node *tree_sitter.Node) {
+		if node.GrammarName() == "comment" {
+			codes := parseIgnoreDirective(p.text(node))
+			row := node.StartPosition().Row
+			for _, r := range []uint{row, row + 1} {
+				if suppressed[r] == nil {
+					suppressed[r] = make(map[checker.Code]bool)
+				}
+				for _, code := range codes {
+					suppressed[r][code] = true
+				}
+			}
+			return
+		}
+		for i := range node.ChildCount() {
+			visit(node.Child(i))
+		}
+	}
+	visit(p.tree.RootNode())
+
+	return suppressed
+}
+
+// the codes listed in a `// kon:ignore` comment, if @comment is one
+func parseIgnoreDirective(comment string) []checker.Code {
+	text := strings.TrimSpace(strings.TrimPrefix(comment, "//"))
+	if !strings.HasPrefix(text, ignoreDirective) {
+		return nil
+	}
+
+	fields := strings.FieldsFunc(text[len(ignoreDirective):], func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	codes := make([]checker.Code, len(fields))
+	for i, field := range fields {
+		codes[i] = checker.Code(field)
+	}
+	return codes
+}
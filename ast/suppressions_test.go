@@ -0,0 +1,80 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/akonwi/ard/checker"
+)
+
+func TestSuppressedDiagnostics(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []checker.Code
+	}{
+		{
+			name: "A directive on the preceding line",
+			input: `let count = 0
+fn run() Num {
+  // kon:ignore K031
+  let count = 1
+  count
+}`,
+			want: []checker.Code{},
+		},
+		{
+			name: "A directive on the same line",
+			input: `let count = 0
+fn run() Num {
+  let count = 1 // kon:ignore K031
+  count
+}`,
+			want: []checker.Code{},
+		},
+		{
+			name: "A directive for an unrelated code",
+			input: `let count = 0
+fn run() Num {
+  // kon:ignore K001
+  let count = 1
+  count
+}`,
+			want: []checker.Code{checker.Shadowing},
+		},
+		{
+			name: "A directive only covers the next line",
+			input: `let count = 0
+// kon:ignore K031
+fn run() Num {
+  let count = 1
+  count
+}`,
+			want: []checker.Code{checker.Shadowing},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := parseForTooling(t, tt.input)
+			diagnostics := parser.GetDiagnostics()
+			if len(diagnostics) != len(tt.want) {
+				t.Fatalf("Expected %d diagnostics, got %v", len(tt.want), diagnostics)
+			}
+			for i, code := range tt.want {
+				if diagnostics[i].Code != code {
+					t.Errorf("Expected %s, got %s", code, diagnostics[i].Code)
+				}
+			}
+		})
+	}
+}
+
+func TestParseIgnoreDirective(t *testing.T) {
+	codes := parseIgnoreDirective("// kon:ignore K030, K031")
+	if len(codes) != 2 || codes[0] != "K030" || codes[1] != "K031" {
+		t.Errorf("Expected [K030 K031], got %v", codes)
+	}
+	if codes := parseIgnoreDirective("// just a comment"); codes != nil {
+		t.Errorf("Expected no codes from a plain comment, got %v", codes)
+	}
+}
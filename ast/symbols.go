@@ -0,0 +1,118 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/akonwi/ard/checker"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// what editor tooling needs to know about a symbol in the source
+type SymbolInfo struct {
+	Name string
+	Type checker.Type
+	// the node that declared the symbol. nil for builtins
+	Declaration *tree_sitter.Node
+}
+
+type byteRange struct {
+	start uint
+	end   uint
+}
+
+func (r byteRange) contains(offset uint) bool {
+	return r.start <= offset && offset <= r.end
+}
+
+type nodeScope struct {
+	byteRange
+	scope *checker.Scope
+}
+
+// remembers the symbol named @name, as currently in scope, at the location of @node
+func (p *Parser) recordSymbol(node *tree_sitter.Node, name string) {
+	if node == nil {
+		return
+	}
+	symbol, declaration, ok := p.scope.LookupDeclaration(name)
+	if !ok {
+		return
+	}
+	p.symbols[byteRange{node.StartByte(), node.EndByte()}] = SymbolInfo{
+		Name:        name,
+		Type:        symbol.GetType(),
+		Declaration: declaration,
+	}
+}
+
+// finds the symbol under the byte @offset of the source.
+// only meaningful after the program has been parsed
+func (p *Parser) SymbolAt(offset uint) (SymbolInfo, bool) {
+	return p.symbolAround(p.tree.RootNode().NamedDescendantForByteRange(offset, offset))
+}
+
+// finds the symbol under the 0-based row and column of @point
+func (p *Parser) SymbolAtPoint(point tree_sitter.Point) (SymbolInfo, bool) {
+	return p.symbolAround(p.tree.RootNode().NamedDescendantForPointRange(point, point))
+}
+
+// walks out from the innermost @node to the first one that is a known symbol
+func (p *Parser) symbolAround(node *tree_sitter.Node) (SymbolInfo, bool) {
+	for node != nil {
+		if info, ok := p.symbols[byteRange{node.StartByte(), node.EndByte()}]; ok {
+			return info, true
+		}
+		node = node.Parent()
+	}
+	return SymbolInfo{}, false
+}
+
+// finds the node that declared the symbol under the byte @offset of the source.
+// builtins have no declaration
+func (p *Parser) DefinitionOf(offset uint) (*tree_sitter.Node, bool) {
+	symbol, ok := p.SymbolAt(offset)
+	if !ok || symbol.Declaration == nil {
+		return nil, false
+	}
+	return symbol.Declaration, true
+}
+
+// lists the symbols visible at the byte @offset of the source, sorted by name.
+// enum variants are included as `Enum::Variant`
+func (p *Parser) CompletionsAt(offset uint) []checker.Symbol {
+	// scopes are recorded as they're opened, so the last one containing the offset is the innermost
+	scope := p.scope
+	for _, candidate := range p.scopes {
+		if candidate.contains(offset) {
+			scope = candidate.scope
+		}
+	}
+
+	seen := make(map[string]bool)
+	completions := []checker.Symbol{}
+	for ; scope != nil; scope = scope.Parent {
+		scope.Each(func(symbol checker.Symbol, node *tree_sitter.Node) {
+			name := symbol.GetName()
+			// names aren't visible before they're declared
+			if seen[name] || (node != nil && node.StartByte() >= offset) {
+				return
+			}
+			seen[name] = true
+			completions = append(completions, symbol)
+			if enum, ok := symbol.(checker.EnumType); ok {
+				for _, variant := range enum.Variants {
+					completions = append(completions, checker.Variable{
+						Name: fmt.Sprintf("%s::%s", enum.Name, variant),
+						Type: enum,
+					})
+				}
+			}
+		})
+	}
+
+	sort.Slice(completions, func(i, j int) bool {
+		return completions[i].GetName() < completions[j].GetName()
+	})
+	return completions
+}
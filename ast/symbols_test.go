@@ -0,0 +1,143 @@
+package ast
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/akonwi/ard/checker"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+func parseForTooling(t *testing.T, input string) *Parser {
+	tree := tsParser.Parse([]byte(input), nil)
+	parser := NewParser([]byte(input), tree)
+	if _, err := parser.Parse(); err != nil {
+		t.Fatalf("Error parsing tree: %v", err)
+	}
+	return parser
+}
+
+func TestSymbolAt(t *testing.T) {
+	input := `let name = "Joe"
+mut count = 0
+let greeting = "Hi " + name
+count = count + 1`
+	parser := parseForTooling(t, input)
+
+	offset := uint(strings.LastIndex(input, "name"))
+	symbol, ok := parser.SymbolAt(offset + 1)
+	if !ok {
+		t.Fatalf("Expected a symbol at offset %d", offset)
+	}
+	if symbol.Name != "name" || symbol.Type != checker.StrType {
+		t.Errorf("Expected name: Str, got %s: %s", symbol.Name, symbol.Type)
+	}
+	if symbol.Declaration == nil || symbol.Declaration.StartPosition().Row != 0 {
+		t.Errorf("Expected 'name' to be declared on the first line")
+	}
+
+	symbol, ok = parser.SymbolAtPoint(tree_sitter.Point{Row: 3, Column: 9})
+	if !ok {
+		t.Fatalf("Expected a symbol at 3:9")
+	}
+	if symbol.Name != "count" || symbol.Type != checker.NumType {
+		t.Errorf("Expected count: Num, got %s: %s", symbol.Name, symbol.Type)
+	}
+
+	if _, ok := parser.SymbolAtPoint(tree_sitter.Point{Row: 0, Column: 12}); ok {
+		t.Errorf("Expected no symbol inside a string literal")
+	}
+}
+
+func TestSymbolAtBuiltin(t *testing.T) {
+	parser := parseForTooling(t, `print("hello")`)
+
+	symbol, ok := parser.SymbolAt(2)
+	if !ok {
+		t.Fatalf("Expected a symbol for print")
+	}
+	if symbol.Name != "print" || symbol.Declaration != nil {
+		t.Errorf("Expected the builtin print without a declaration, got %s", symbol.Name)
+	}
+	if _, ok := symbol.Type.(checker.FunctionType); !ok {
+		t.Errorf("Expected print to be a function, got %s", symbol.Type)
+	}
+}
+
+func TestDefinitionOf(t *testing.T) {
+	input := `fn add(x: Num, y: Num) Num {
+  x + y
+}
+let total = add(1, 2)
+print(total)`
+	parser := parseForTooling(t, input)
+
+	tests := []struct {
+		reference  string
+		declaredAt tree_sitter.Point
+	}{
+		{reference: "add(1", declaredAt: tree_sitter.Point{Row: 0, Column: 3}},
+		{reference: "total)", declaredAt: tree_sitter.Point{Row: 3, Column: 4}},
+		{reference: "x + y", declaredAt: tree_sitter.Point{Row: 0, Column: 7}},
+	}
+
+	for _, tt := range tests {
+		offset := uint(strings.Index(input, tt.reference))
+		declaration, ok := parser.DefinitionOf(offset)
+		if !ok {
+			t.Errorf("Expected a definition for %q", tt.reference)
+			continue
+		}
+		if declaration.StartPosition() != tt.declaredAt {
+			t.Errorf("%q: expected a definition at %v, got %v", tt.reference, tt.declaredAt, declaration.StartPosition())
+		}
+		name := input[declaration.StartByte():declaration.EndByte()]
+		if !strings.HasPrefix(tt.reference, name) {
+			t.Errorf("%q: resolved to '%s'", tt.reference, name)
+		}
+	}
+
+	if _, ok := parser.DefinitionOf(uint(strings.Index(input, "print"))); ok {
+		t.Errorf("Expected builtins to have no definition")
+	}
+}
+
+func TestCompletionsAt(t *testing.T) {
+	input := `enum Color { Red, Green }
+let prefix = "Hi "
+fn greet(name: Str) Str {
+  print(prefix)
+  let greeting = prefix + name
+  greeting
+}
+let later = 1`
+	parser := parseForTooling(t, input)
+
+	names := make(map[string]bool)
+	offset := uint(strings.Index(input, "prefix)"))
+	for _, symbol := range parser.CompletionsAt(offset) {
+		names[symbol.GetName()] = true
+	}
+
+	for _, name := range []string{"name", "prefix", "Color", "Color::Red", "Color::Green", "print"} {
+		if !names[name] {
+			t.Errorf("Expected '%s' to be a completion", name)
+		}
+	}
+	for _, name := range []string{"greeting", "later"} {
+		if names[name] {
+			t.Errorf("Expected '%s' not to be visible before its declaration", name)
+		}
+	}
+
+	names = make(map[string]bool)
+	for _, symbol := range parser.CompletionsAt(uint(len(input))) {
+		names[symbol.GetName()] = true
+	}
+	if !names["greet"] || !names["later"] {
+		t.Errorf("Expected top level declarations at the end of the program")
+	}
+	if names["name"] || names["greeting"] {
+		t.Errorf("Expected function locals not to leak out of the function")
+	}
+}
@@ -433,3 +433,256 @@ func TestVariableAssignment(t *testing.T) {
 
 	runTests(t, tests)
 }
+
+func TestShadowing(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Shadowing an outer declaration is a warning",
+			input: `
+				let count = 1
+				fn get_count() Num {
+					let count = 2
+					count
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "'count' shadows an existing declaration", Severity: checker.Warning},
+			},
+		},
+	})
+}
+
+func TestVoidAssignment(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "Assigning a block without a trailing expression",
+			input: `
+				let x = {
+					let a = 1
+				}`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.VoidValue, Msg: "Cannot assign a Void value"},
+			},
+		},
+		{
+			name: "Assigning the result of a Void function",
+			input: `
+				fn log(msg: Str) {
+					print(msg)
+				}
+				let result = log("hi")`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.VoidValue, Msg: "Cannot assign a Void value"},
+			},
+		},
+		{
+			name: "A declared type doesn't make Void assignable",
+			input: `
+				fn noop() {}
+				let count: Num = noop()`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.VoidValue, Msg: "Cannot assign a Void value"},
+			},
+		},
+	})
+}
+
+func TestTypeAliases(t *testing.T) {
+	personStruct := checker.StructType{
+		Name: "Person",
+		Fields: map[string]checker.Type{
+			"name": checker.StrType,
+		},
+	}
+	runTests(t, []test{
+		{
+			name: "An alias resolves to the aliased type",
+			input: `
+				type Id = Num
+				let id: Id = 5
+				let count: Num = id`,
+			output: Program{
+				Statements: []Statement{
+					TypeAlias{Name: "Id", Type: checker.NumType},
+					VariableDeclaration{
+						Name:  "id",
+						Type:  checker.NumType,
+						Value: NumLiteral{Value: "5"},
+					},
+					VariableDeclaration{
+						Name:  "count",
+						Type:  checker.NumType,
+						Value: Identifier{Name: "id", Type: checker.NumType},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name: "Aliases are checked like the aliased type",
+			input: `
+				type Id = Num
+				let id: Id = "five"`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected Num, got Str"},
+			},
+		},
+		{
+			name: "An alias to a struct",
+			input: `
+				struct Person { name: Str }
+				type User = Person
+				let user: User = Person { name: "Alice" }`,
+			output: Program{
+				Statements: []Statement{
+					StructDefinition{Type: personStruct},
+					TypeAlias{Name: "User", Type: personStruct},
+					VariableDeclaration{
+						Name: "user",
+						Type: personStruct,
+						Value: StructInstance{
+							Type: personStruct,
+							Properties: []StructValue{
+								{Name: "name", Value: StrLiteral{Value: `"Alice"`}},
+							},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+	})
+}
+
+func TestGenericTypeAnnotations(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "List<Num> annotation",
+			input: `let xs: List<Num> = [1, 2]`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name: "xs",
+						Type: checker.ListType{ItemType: checker.NumType},
+						Value: ListLiteral{
+							Type: checker.ListType{ItemType: checker.NumType},
+							Items: []Expression{
+								NumLiteral{Value: "1"},
+								NumLiteral{Value: "2"},
+							},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "Map<Str, Num> annotation",
+			input: `mut entries: Map<Str, Num> = [:]`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Mutable: true,
+						Name:    "entries",
+						Type:    checker.MakeMap(checker.NumType),
+						Value: MapLiteral{
+							Entries: []MapEntry{},
+							Type:    checker.MapType{KeyType: checker.StrType},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "Element type mismatch",
+			input: `let xs: List<Num> = ["a"]`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected [Num], got [Str]"},
+			},
+		},
+	})
+}
+
+func TestNestedGenericTypeAnnotations(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "List<List<Num>> annotation",
+			input: `let grid: List<List<Num>> = [[1, 2], [3]]`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name: "grid",
+						Type: checker.ListType{ItemType: checker.ListType{ItemType: checker.NumType}},
+						Value: ListLiteral{
+							Type: checker.ListType{ItemType: checker.ListType{ItemType: checker.NumType}},
+							Items: []Expression{
+								ListLiteral{
+									Type:  checker.ListType{ItemType: checker.NumType},
+									Items: []Expression{NumLiteral{Value: "1"}, NumLiteral{Value: "2"}},
+								},
+								ListLiteral{
+									Type:  checker.ListType{ItemType: checker.NumType},
+									Items: []Expression{NumLiteral{Value: "3"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "Nested list mismatch",
+			input: `let grid: List<List<Num>> = [["a"]]`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected [[Num]], got [[Str]]"},
+			},
+		},
+		{
+			name:  "Map<Str, List<Num>> annotation",
+			input: `let groups: Map<Str, List<Num>> = ["odds": [1, 3]]`,
+			output: Program{
+				Statements: []Statement{
+					VariableDeclaration{
+						Name: "groups",
+						Type: checker.MakeMap(checker.ListType{ItemType: checker.NumType}),
+						Value: MapLiteral{
+							Entries: []MapEntry{
+								{
+									Key: `"odds"`,
+									Value: ListLiteral{
+										Type:  checker.ListType{ItemType: checker.NumType},
+										Items: []Expression{NumLiteral{Value: "1"}, NumLiteral{Value: "3"}},
+									},
+								},
+							},
+							Type: checker.MakeMap(checker.ListType{ItemType: checker.NumType}),
+						},
+					},
+				},
+			},
+			diagnostics: []checker.Diagnostic{},
+		},
+		{
+			name:  "Map of lists mismatch",
+			input: `let groups: Map<Str, List<Num>> = ["odds": ["one"]]`,
+			diagnostics: []checker.Diagnostic{
+				{Msg: "Type mismatch: expected {Str:[Num]}, got {Str:[Str]}"},
+			},
+		},
+		{
+			name:  "Unknown generic type",
+			input: `let box: Box<Num> = 1`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.UnknownType, Msg: "Unknown type: 'Box'"},
+			},
+		},
+		{
+			name:  "List with too many type arguments",
+			input: `let xs: List<Num, Str> = [1]`,
+			diagnostics: []checker.Diagnostic{
+				{Code: checker.InvalidTypeArguments, Msg: "'List' expects 1 type arguments, got 2"},
+			},
+		},
+	})
+}
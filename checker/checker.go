@@ -2,6 +2,7 @@ package checker
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
@@ -11,7 +12,29 @@ type Type interface {
 	String() string
 	GetProperty(name string) Type
 	Equals(other Type) bool
-}
+	Kind() Kind
+}
+
+// what sort of type a Type is, for branching on types without asserting their Go type
+type Kind int
+
+const (
+	KindStr Kind = iota
+	KindNum
+	KindBool
+	KindVoid
+	KindNever
+	KindFunction
+	KindStruct
+	KindEnum
+	KindModule
+	KindGeneric
+	KindList
+	KindTuple
+	KindMap
+	KindOption
+	KindResult
+)
 
 type PrimitiveType struct {
 	Name string
@@ -23,10 +46,26 @@ func (p PrimitiveType) String() string {
 }
 func (p PrimitiveType) GetProperty(name string) Type {
 	switch p.Name {
+	case "Num":
+		switch name {
+		case "toStr":
+			return FunctionType{Name: name, Parameters: []Type{}, ReturnType: StrType}
+		default:
+			return nil
+		}
 	case "Str":
 		switch name {
 		case "size":
 			return NumType
+		case "uppercase", "lowercase", "trim":
+			return FunctionType{Name: name, Parameters: []Type{}, ReturnType: StrType}
+		case "contains":
+			return FunctionType{Name: name, Parameters: []Type{StrType}, ReturnType: BoolType}
+		case "split":
+			return FunctionType{Name: name, Parameters: []Type{StrType}, ReturnType: MakeList(StrType)}
+		case "slice":
+			// (start, end?) from start up to, but not including, end
+			return FunctionType{Name: name, Parameters: []Type{NumType, NumType}, Optional: 1, ReturnType: StrType}
 		default:
 			return nil
 		}
@@ -34,7 +73,25 @@ func (p PrimitiveType) GetProperty(name string) Type {
 	return nil
 }
 
+func (p PrimitiveType) Kind() Kind {
+	switch p.Name {
+	case "Str":
+		return KindStr
+	case "Num":
+		return KindNum
+	case "Bool":
+		return KindBool
+	case "Void":
+		return KindVoid
+	default:
+		return KindNever
+	}
+}
+
 func (p PrimitiveType) Equals(other Type) bool {
+	if p == NeverType || isNever(other) {
+		return true
+	}
 	if otherPrimitive, ok := other.(PrimitiveType); ok {
 		return p.Name == otherPrimitive.Name
 	}
@@ -46,13 +103,27 @@ var (
 	NumType  = PrimitiveType{"Num"}
 	BoolType = PrimitiveType{"Bool"}
 	VoidType = PrimitiveType{"Void"}
+	// the type of expressions that never produce a value, such as `todo()`.
+	// it is compatible with every other type
+	NeverType = PrimitiveType{"Never"}
 )
 
+// the signature of `Num.from(str)`, which is none when the string isn't a number
+var NumFrom = FunctionType{Name: "from", Parameters: []Type{StrType}, ReturnType: OptionType{Inner: NumType}}
+
+func isNever(t Type) bool {
+	return t == NeverType
+}
+
 type FunctionType struct {
 	Name       string
 	Mutates    bool
 	Parameters []Type
 	ReturnType Type
+	// how many of the last parameters a call can leave out, like the end of `slice(start, end)`
+	Optional int
+	// whether this is one of the language's builtins rather than a function the program declared
+	Builtin bool
 }
 
 func (f FunctionType) String() string {
@@ -63,13 +134,26 @@ func (f FunctionType) String() string {
 		}
 		params.WriteString(param.String())
 	}
-	return fmt.Sprintf("(%v) %v", params.String(), f.ReturnType)
+	signature := fmt.Sprintf("(%v) %v", params.String(), f.ReturnType)
+	if f.Mutates {
+		return "mut " + signature
+	}
+	return signature
 }
 func (f FunctionType) GetProperty(name string) Type {
 	return nil
 }
+func (f FunctionType) Kind() Kind {
+	return KindFunction
+}
 func (f FunctionType) Equals(other Type) bool {
+	if isNever(other) {
+		return true
+	}
 	if otherFunc, ok := other.(FunctionType); ok {
+		if f.Mutates != otherFunc.Mutates {
+			return false
+		}
 		if len(f.Parameters) != len(otherFunc.Parameters) {
 			return false
 		}
@@ -89,21 +173,44 @@ func (f FunctionType) GetType() Type {
 	return f
 }
 
+// anonymous structs, the types of object literals like `{ name: "Joe" }`, have no name.
+// they are spelled by their fields, which makes two with the same fields equal
 type StructType struct {
 	Name   string
 	Fields map[string]Type
 }
 
 func (s StructType) String() string {
+	if s.IsAnonymous() {
+		names := make([]string, 0, len(s.Fields))
+		for name := range s.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fields := make([]string, len(names))
+		for i, name := range names {
+			fields[i] = fmt.Sprintf("%s: %s", name, s.Fields[name])
+		}
+		return fmt.Sprintf("{%s}", strings.Join(fields, ", "))
+	}
 	return fmt.Sprintf("Struct(%s)", s.Name)
 }
+func (s StructType) IsAnonymous() bool {
+	return s.Name == ""
+}
 func (s StructType) GetProperty(name string) Type {
 	if field, ok := s.Fields[name]; ok {
 		return field
 	}
 	return nil
 }
+func (s StructType) Kind() Kind {
+	return KindStruct
+}
 func (s StructType) Equals(other Type) bool {
+	if isNever(other) {
+		return true
+	}
 	return s.String() == other.String()
 }
 func (s StructType) GetName() string {
@@ -116,6 +223,13 @@ func (s StructType) GetType() Type {
 type EnumType struct {
 	Name     string
 	Variants []string
+	// the types of the values carried by each variant that has any
+	Payloads map[string][]Type
+}
+
+// whether any variant carries values
+func (e EnumType) HasPayloads() bool {
+	return len(e.Payloads) > 0
 }
 
 func (e EnumType) HasVariant(variant string) bool {
@@ -137,7 +251,13 @@ func (e EnumType) String() string {
 func (e EnumType) GetProperty(name string) Type {
 	return nil
 }
+func (e EnumType) Kind() Kind {
+	return KindEnum
+}
 func (e EnumType) Equals(other Type) bool {
+	if isNever(other) {
+		return true
+	}
 	return e.String() == other.String()
 }
 func (e EnumType) GetName() string {
@@ -147,11 +267,40 @@ func (e EnumType) GetType() Type {
 	return e
 }
 
+// an imported file. its top-level declarations are accessed as members, e.g. `util.greet()`
+type ModuleType struct {
+	Name    string
+	Members map[string]Type
+}
+
+func (m ModuleType) String() string {
+	return m.Name
+}
+func (m ModuleType) GetProperty(name string) Type {
+	return m.Members[name]
+}
+func (m ModuleType) Kind() Kind {
+	return KindModule
+}
+func (m ModuleType) Equals(other Type) bool {
+	return m.String() == other.String()
+}
+func (m ModuleType) GetName() string {
+	return m.Name
+}
+func (m ModuleType) GetType() Type {
+	return m
+}
+
 type GenericType struct {
 	inner *Type
 	name  string
 }
 
+func MakeGeneric(name string) GenericType {
+	return GenericType{name: name}
+}
+
 func (g GenericType) String() string {
 	return fmt.Sprintf("%s?", g.name)
 }
@@ -161,6 +310,14 @@ func (g GenericType) GetType() Type {
 	}
 	return *g.inner
 }
+
+// an open generic has its own kind, a filled one is the kind of what it was filled with
+func (g GenericType) Kind() Kind {
+	if g.inner == nil {
+		return KindGeneric
+	}
+	return (*g.inner).Kind()
+}
 func (g GenericType) Equals(other Type) bool {
 	if g.inner == nil {
 		return true
@@ -177,6 +334,85 @@ func (g *GenericType) Fill(inner Type) {
 	g.inner = &inner
 }
 
+// records what each open generic in @expected is filled with by @actual
+func BindGenerics(expected, actual Type, bindings map[string]Type) {
+	if expected == nil || actual == nil {
+		return
+	}
+	switch expected := expected.(type) {
+	case GenericType:
+		if expected.inner != nil {
+			return
+		}
+		if _, isGeneric := actual.(GenericType); isGeneric {
+			return
+		}
+		if _, ok := bindings[expected.name]; !ok {
+			bindings[expected.name] = actual
+		}
+	case FunctionType:
+		if actualFn, ok := actual.(FunctionType); ok {
+			for i, param := range expected.Parameters {
+				if i < len(actualFn.Parameters) {
+					BindGenerics(param, actualFn.Parameters[i], bindings)
+				}
+			}
+			BindGenerics(expected.ReturnType, actualFn.ReturnType, bindings)
+		}
+	case ListType:
+		if actualList, ok := actual.(ListType); ok {
+			BindGenerics(expected.ItemType, actualList.ItemType, bindings)
+		}
+	case MapType:
+		if actualMap, ok := actual.(MapType); ok {
+			BindGenerics(expected.ValueType, actualMap.ValueType, bindings)
+		}
+	case ResultType:
+		if actualResult, ok := actual.(ResultType); ok {
+			BindGenerics(expected.OkType, actualResult.OkType, bindings)
+		}
+	case OptionType:
+		if actualOption, ok := actual.(OptionType); ok {
+			BindGenerics(expected.Inner, actualOption.Inner, bindings)
+		}
+	}
+}
+
+// returns @t with any open generics replaced by their entry in @bindings
+func ResolveGenerics(t Type, bindings map[string]Type) Type {
+	switch t := t.(type) {
+	case GenericType:
+		if t.inner != nil {
+			return *t.inner
+		}
+		if bound, ok := bindings[t.name]; ok {
+			return bound
+		}
+		return t
+	case FunctionType:
+		params := make([]Type, len(t.Parameters))
+		for i, param := range t.Parameters {
+			params[i] = ResolveGenerics(param, bindings)
+		}
+		return FunctionType{
+			Name:       t.Name,
+			Mutates:    t.Mutates,
+			Parameters: params,
+			ReturnType: ResolveGenerics(t.ReturnType, bindings),
+		}
+	case ListType:
+		return ListType{ItemType: ResolveGenerics(t.ItemType, bindings)}
+	case MapType:
+		return MapType{KeyType: t.KeyType, ValueType: ResolveGenerics(t.ValueType, bindings)}
+	case ResultType:
+		return ResultType{OkType: ResolveGenerics(t.OkType, bindings)}
+	case OptionType:
+		return OptionType{Inner: ResolveGenerics(t.Inner, bindings)}
+	default:
+		return t
+	}
+}
+
 type ListType struct {
 	ItemType Type
 }
@@ -204,6 +440,35 @@ func (l ListType) GetProperty(name string) Type {
 			// List probably needs to use a pointer to the inner type
 			ReturnType: MakeList(outType),
 		}
+	case "filter":
+		return FunctionType{
+			Mutates: false,
+			Name:    "filter",
+			Parameters: []Type{
+				FunctionType{
+					Name:       "callback",
+					Parameters: []Type{l.ItemType},
+					ReturnType: BoolType,
+				},
+			},
+			ReturnType: MakeList(l.ItemType),
+		}
+	case "reduce":
+		// (Acc?, (Acc?, Item) Acc?) Acc?
+		accType := GenericType{name: "Acc"}
+		return FunctionType{
+			Mutates: false,
+			Name:    "reduce",
+			Parameters: []Type{
+				accType,
+				FunctionType{
+					Name:       "callback",
+					Parameters: []Type{accType, l.ItemType},
+					ReturnType: accType,
+				},
+			},
+			ReturnType: accType,
+		}
 	case "pop":
 		// pop is a function that takes no arguments and returns the last item in the list
 		// (Item?) Num
@@ -222,13 +487,44 @@ func (l ListType) GetProperty(name string) Type {
 			Parameters: []Type{l.ItemType},
 			ReturnType: NumType,
 		}
+	case "contains":
+		// whether any item is equal to the argument
+		return FunctionType{
+			Mutates:    false,
+			Name:       "contains",
+			Parameters: []Type{l.ItemType},
+			ReturnType: BoolType,
+		}
+	case "join":
+		// only lists of Str can be joined, which the checker reports at the call
+		return FunctionType{
+			Mutates:    false,
+			Name:       "join",
+			Parameters: []Type{StrType},
+			ReturnType: StrType,
+		}
+	case "slice":
+		// (start, end?) the items from start up to, but not including, end
+		return FunctionType{
+			Mutates:    false,
+			Name:       "slice",
+			Parameters: []Type{NumType, NumType},
+			Optional:   1,
+			ReturnType: MakeList(l.ItemType),
+		}
 	case "size":
 		return NumType
 	default:
 		return nil
 	}
 }
+func (l ListType) Kind() Kind {
+	return KindList
+}
 func (l ListType) Equals(other Type) bool {
+	if isNever(other) {
+		return true
+	}
 	if otherList, ok := other.(ListType); ok {
 		// if either list is still open, then they are compatible
 		if l.ItemType == nil || otherList.ItemType == nil {
@@ -242,6 +538,47 @@ func MakeList(itemType Type) ListType {
 	return ListType{ItemType: itemType}
 }
 
+// a fixed length list where each position has its own type
+type TupleType struct {
+	Items []Type
+}
+
+func (t TupleType) String() string {
+	items := make([]string, len(t.Items))
+	for i, item := range t.Items {
+		items[i] = item.String()
+	}
+	return fmt.Sprintf("(%s)", strings.Join(items, ", "))
+}
+func (t TupleType) GetProperty(name string) Type {
+	switch name {
+	case "size":
+		return NumType
+	default:
+		return nil
+	}
+}
+func (t TupleType) Kind() Kind {
+	return KindTuple
+}
+func (t TupleType) Equals(other Type) bool {
+	if isNever(other) {
+		return true
+	}
+	if otherTuple, ok := other.(TupleType); ok {
+		if len(t.Items) != len(otherTuple.Items) {
+			return false
+		}
+		for i, item := range t.Items {
+			if !item.Equals(otherTuple.Items[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
 type MapType struct {
 	KeyType   Type
 	ValueType Type
@@ -262,7 +599,13 @@ func (m MapType) GetProperty(name string) Type {
 		return nil
 	}
 }
+func (m MapType) Kind() Kind {
+	return KindMap
+}
 func (m MapType) Equals(other Type) bool {
+	if isNever(other) {
+		return true
+	}
 	if otherMap, ok := other.(MapType); ok {
 		if !m.KeyType.Equals(otherMap.KeyType) {
 			return false
@@ -278,6 +621,54 @@ func MakeMap(valueType Type) MapType {
 	return MapType{KeyType: StrType, ValueType: valueType}
 }
 
+// a value that may be absent
+type OptionType struct {
+	Inner Type
+}
+
+func (o OptionType) String() string {
+	return fmt.Sprintf("Option<%s>", o.Inner)
+}
+func (o OptionType) GetProperty(name string) Type {
+	return nil
+}
+func (o OptionType) Kind() Kind {
+	return KindOption
+}
+func (o OptionType) Equals(other Type) bool {
+	if isNever(other) {
+		return true
+	}
+	if otherOption, ok := other.(OptionType); ok {
+		return o.Inner.Equals(otherOption.Inner)
+	}
+	return false
+}
+
+// the outcome of an operation that can fail. the error is always a Str
+type ResultType struct {
+	OkType Type
+}
+
+func (r ResultType) String() string {
+	return fmt.Sprintf("Result<%s>", r.OkType)
+}
+func (r ResultType) GetProperty(name string) Type {
+	return nil
+}
+func (r ResultType) Kind() Kind {
+	return KindResult
+}
+func (r ResultType) Equals(other Type) bool {
+	if isNever(other) {
+		return true
+	}
+	if otherResult, ok := other.(ResultType); ok {
+		return r.OkType.Equals(otherResult.OkType)
+	}
+	return false
+}
+
 type Symbol interface {
 	GetName() string
 	GetType() Type
@@ -287,6 +678,8 @@ type Variable struct {
 	Name    string
 	Type    Type
 	Mutable bool
+	// whether the variable is a parameter of a function, which can never be reassigned
+	Parameter bool
 }
 
 func (v Variable) GetName() string {
@@ -296,58 +689,223 @@ func (v Variable) GetType() Type {
 	return v.Type
 }
 
+// a named alias for another type. aliases are transparent, so its type is the aliased type
+type TypeAlias struct {
+	Name string
+	Type Type
+}
+
+func (t TypeAlias) GetName() string {
+	return t.Name
+}
+func (t TypeAlias) GetType() Type {
+	return t.Type
+}
+
 type ScopeOptions struct {
 	IsTop bool
 }
 
+// a symbol along with the node that declared it. builtins have no declaring node
+type declaration struct {
+	symbol Symbol
+	node   *tree_sitter.Node
+}
+
 type Scope struct {
-	parent  *Scope
-	symbols map[string]Symbol
+	Parent  *Scope
+	symbols map[string]declaration
 	structs map[string]StructType
+	// holds the builtins that programs can declare their own versions of, above the top-level scope
+	prelude bool
 }
 
-func (s Scope) GetParent() *Scope {
-	return s.parent
-}
 func NewScope(parent *Scope, options ScopeOptions) Scope {
 	scope := Scope{
-		parent:  parent,
-		symbols: make(map[string]Symbol),
+		Parent:  parent,
+		symbols: make(map[string]declaration),
 		structs: make(map[string]StructType),
 	}
 	if options.IsTop {
-		scope.Declare(FunctionType{
-			Name: "print",
-			Parameters: []Type{
-				StrType,
-			},
-			ReturnType: VoidType,
-		})
+		someValue := GenericType{name: "T"}
+		okValue := GenericType{name: "T"}
+		builtins := []FunctionType{
+			{Name: "todo", Parameters: []Type{}, ReturnType: NeverType},
+			{Name: "panic", Parameters: []Type{StrType}, ReturnType: NeverType},
+			{Name: "some", Parameters: []Type{someValue}, ReturnType: OptionType{Inner: someValue}},
+			{Name: "none", Parameters: []Type{}, ReturnType: OptionType{Inner: NeverType}},
+			{Name: "ok", Parameters: []Type{okValue}, ReturnType: ResultType{OkType: okValue}},
+			{Name: "err", Parameters: []Type{StrType}, ReturnType: ResultType{OkType: NeverType}},
+			// print accepts a value of any type
+			{Name: "print", Parameters: []Type{GenericType{name: "Value"}}, ReturnType: VoidType},
+		}
+		for _, builtin := range builtins {
+			builtin.Builtin = true
+			scope.Declare(builtin.Name, builtin, nil)
+		}
+		if parent == nil {
+			scope.Parent = newPrelude()
+		}
 	}
 	return scope
 }
 
-func (s *Scope) Declare(sym Symbol) error {
+// the scope of the builtins with ordinary names, which a program can shadow by declaring its own
+func newPrelude() *Scope {
+	prelude := NewScope(nil, ScopeOptions{})
+	prelude.prelude = true
+	builtins := []FunctionType{
+		// the message is optional
+		{Name: "assert", Parameters: []Type{BoolType, StrType}, Optional: 1, ReturnType: VoidType},
+		{Name: "min", Parameters: []Type{NumType, NumType}, ReturnType: NumType},
+		{Name: "max", Parameters: []Type{NumType, NumType}, ReturnType: NumType},
+		{Name: "abs", Parameters: []Type{NumType}, ReturnType: NumType},
+	}
+	for _, builtin := range builtins {
+		builtin.Builtin = true
+		prelude.Declare(builtin.Name, builtin, nil)
+	}
+	return &prelude
+}
+
+// whether this is the top-level scope of a program
+func (s *Scope) IsTop() bool {
+	return s.Parent == nil || s.Parent.prelude
+}
+
+// creates a scope nested in this one
+func (s *Scope) Child() *Scope {
+	child := NewScope(s, ScopeOptions{})
+	return &child
+}
+
+func (s *Scope) declare(sym Symbol, node *tree_sitter.Node) error {
 	if existing, ok := s.symbols[sym.GetName()]; ok {
-		return fmt.Errorf("symbol %s already declared as %v", existing.GetName(), existing.GetType())
+		return fmt.Errorf("symbol %s already declared as %v", existing.symbol.GetName(), existing.symbol.GetType())
 	}
-	s.symbols[sym.GetName()] = sym
+	s.symbols[sym.GetName()] = declaration{symbol: sym, node: node}
 	return nil
 }
 
-func (s *Scope) Lookup(name string) Symbol {
-	if sym, ok := s.symbols[name]; ok {
-		return sym
+// declares @name with type @t at @node.
+// named types (structs, enums and functions) declared under their own name are their own symbol,
+// anything else is an immutable variable
+func (s *Scope) Declare(name string, t Type, node *tree_sitter.Node) error {
+	if sym, ok := t.(Symbol); ok && sym.GetName() == name {
+		return s.declare(sym, node)
 	}
-	if s.parent != nil {
-		return s.parent.Lookup(name)
+	return s.declare(Variable{Name: name, Type: t}, node)
+}
+
+func (s *Scope) DeclareMutable(name string, t Type, node *tree_sitter.Node) error {
+	return s.declare(Variable{Name: name, Type: t, Mutable: true}, node)
+}
+
+func (s *Scope) DeclareParameter(name string, t Type, node *tree_sitter.Node) error {
+	return s.declare(Variable{Name: name, Type: t, Parameter: true}, node)
+}
+
+// declares @name as another name for @t
+func (s *Scope) DeclareAlias(name string, t Type, node *tree_sitter.Node) error {
+	return s.declare(TypeAlias{Name: name, Type: t}, node)
+}
+
+// finds the nearest symbol called @name, starting from this scope and walking up through its parents
+func (s *Scope) Lookup(name string) (Symbol, bool) {
+	symbol, _, ok := s.LookupDeclaration(name)
+	return symbol, ok
+}
+
+// calls @fn with every symbol declared directly in this scope and the node that declared it
+func (s *Scope) Each(fn func(symbol Symbol, node *tree_sitter.Node)) {
+	for _, decl := range s.symbols {
+		fn(decl.symbol, decl.node)
 	}
-	return nil
 }
 
+// like Lookup, but also returns the node that declared the symbol
+func (s *Scope) LookupDeclaration(name string) (Symbol, *tree_sitter.Node, bool) {
+	if decl, ok := s.symbols[name]; ok {
+		return decl.symbol, decl.node, true
+	}
+	if s.Parent != nil {
+		return s.Parent.LookupDeclaration(name)
+	}
+	return nil, nil, false
+}
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	// informs without suggesting anything is wrong, so it never fails a build
+	Info
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Info:
+		return "info"
+	default:
+		return "error"
+	}
+}
+
+// a stable identifier for a kind of diagnostic, so it can be looked up or suppressed
+type Code string
+
+const (
+	TypeMismatch          Code = "K001"
+	InvalidOperator       Code = "K002"
+	InvalidCondition      Code = "K003"
+	MissingTypeAnnotation Code = "K004"
+	InvalidDestructuring  Code = "K005"
+	InvalidTypeArguments  Code = "K006"
+	NotIterable           Code = "K007"
+	InvalidIndex          Code = "K008"
+	ArgumentCount         Code = "K009"
+	Undefined             Code = "K010"
+	NotAVariable          Code = "K011"
+	NotMutable            Code = "K012"
+	NotAStruct            Code = "K013"
+	UnknownMember         Code = "K014"
+	Duplicate             Code = "K015"
+	MissingField          Code = "K016"
+	MixedList             Code = "K017"
+	InvalidRange          Code = "K018"
+	NonExhaustiveMatch    Code = "K019"
+	UnreachableArm        Code = "K020"
+	InvalidTry            Code = "K021"
+	InvalidPattern        Code = "K022"
+	NotInLoop             Code = "K023"
+	UnknownLabel          Code = "K024"
+	InvalidAssignment     Code = "K025"
+	VoidValue             Code = "K026"
+	NotAnExpression       Code = "K027"
+	NotOptional           Code = "K028"
+	InvalidCast           Code = "K029"
+	NotInFunction         Code = "K030"
+	// codes are stable, so errors added after the warnings are numbered after them
+	UnknownType Code = "K035"
+
+	// warnings
+	Shadowing         Code = "K031"
+	InfiniteRecursion Code = "K032"
+	InfiniteLoop      Code = "K033"
+	UnsafeInteger     Code = "K034"
+
+	// information
+	TypeOf Code = "K041"
+)
+
 type Diagnostic struct {
-	Msg   string
-	Range tree_sitter.Range
+	Code     Code
+	Msg      string
+	Range    tree_sitter.Range
+	Severity Severity
 }
 
 // tree-sitter uses 0-based indexing, so make this human friendly when it's time to show it to humans
@@ -360,9 +918,28 @@ type Diagnostic struct {
 // 	Column: node.EndPosition().Column,
 // }
 
-func MakeError(msg string, node *tree_sitter.Node) Diagnostic {
+func MakeError(code Code, msg string, node *tree_sitter.Node) Diagnostic {
 	return Diagnostic{
+		Code:  code,
 		Msg:   msg,
 		Range: node.Range(),
 	}
 }
+
+func MakeWarning(code Code, msg string, node *tree_sitter.Node) Diagnostic {
+	return Diagnostic{
+		Code:     code,
+		Msg:      msg,
+		Range:    node.Range(),
+		Severity: Warning,
+	}
+}
+
+func MakeInfo(code Code, msg string, node *tree_sitter.Node) Diagnostic {
+	return Diagnostic{
+		Code:     code,
+		Msg:      msg,
+		Range:    node.Range(),
+		Severity: Info,
+	}
+}
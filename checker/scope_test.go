@@ -0,0 +1,80 @@
+package checker
+
+import "testing"
+
+func TestNestedLookup(t *testing.T) {
+	top := NewScope(nil, ScopeOptions{IsTop: true})
+	top.Declare("count", NumType, nil)
+	inner := top.Child().Child()
+
+	if inner.Parent.Parent != &top {
+		t.Fatalf("Expected the grandparent to be the top scope")
+	}
+
+	symbol, ok := inner.Lookup("count")
+	if !ok {
+		t.Fatalf("Expected to find 'count' in an outer scope")
+	}
+	if symbol.GetType() != NumType {
+		t.Errorf("Expected 'count' to be Num, got %s", symbol.GetType())
+	}
+
+	if _, ok := inner.Lookup("print"); !ok {
+		t.Errorf("Expected builtins to be visible from nested scopes")
+	}
+	if _, ok := inner.Lookup("missing"); ok {
+		t.Errorf("Expected 'missing' to be undefined")
+	}
+}
+
+func TestShadowedLookup(t *testing.T) {
+	top := NewScope(nil, ScopeOptions{})
+	top.Declare("name", NumType, nil)
+	inner := top.Child()
+	inner.DeclareMutable("name", StrType, nil)
+
+	symbol, _ := inner.Lookup("name")
+	if symbol.GetType() != StrType {
+		t.Errorf("Expected the innermost 'name' to be Str, got %s", symbol.GetType())
+	}
+	if variable, ok := symbol.(Variable); !ok || !variable.Mutable {
+		t.Errorf("Expected the innermost 'name' to be a mutable variable")
+	}
+
+	symbol, _ = top.Lookup("name")
+	if symbol.GetType() != NumType {
+		t.Errorf("Expected the outer 'name' to still be Num, got %s", symbol.GetType())
+	}
+
+	if err := inner.Declare("name", BoolType, nil); err == nil {
+		t.Errorf("Expected redeclaring 'name' in the same scope to fail")
+	}
+}
+
+func TestDeclaringParameters(t *testing.T) {
+	scope := NewScope(nil, ScopeOptions{})
+	scope.DeclareParameter("x", NumType, nil)
+
+	symbol, _ := scope.Lookup("x")
+	variable, ok := symbol.(Variable)
+	if !ok || !variable.Parameter || variable.Mutable {
+		t.Errorf("Expected 'x' to be an immutable parameter, got %#v", symbol)
+	}
+	if err := scope.DeclareParameter("x", StrType, nil); err == nil {
+		t.Errorf("Expected declaring 'x' twice to fail")
+	}
+}
+
+func TestDeclaringNamedTypes(t *testing.T) {
+	scope := NewScope(nil, ScopeOptions{})
+	person := StructType{Name: "Person", Fields: map[string]Type{"name": StrType}}
+	scope.Declare(person.Name, person, nil)
+	scope.DeclareAlias("Id", NumType, nil)
+
+	if symbol, _ := scope.Lookup("Person"); symbol.GetName() != "Person" || !symbol.GetType().Equals(person) {
+		t.Errorf("Expected 'Person' to resolve to its struct")
+	}
+	if symbol, _ := scope.Lookup("Id"); symbol.GetType() != NumType {
+		t.Errorf("Expected 'Id' to alias Num")
+	}
+}
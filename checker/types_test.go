@@ -133,6 +133,51 @@ func TestFunctionCompatibility(t *testing.T) {
 	}
 }
 
+func TestMutatingFunctionCompatibility(t *testing.T) {
+	pure := FunctionType{Name: "add", Parameters: []Type{NumType}, ReturnType: NumType}
+	mutating := FunctionType{Name: "add", Mutates: true, Parameters: []Type{NumType}, ReturnType: NumType}
+
+	if pure.Equals(mutating) {
+		t.Errorf("Expected (Num) Num != mut (Num) Num")
+	}
+	if mutating.Equals(pure) {
+		t.Errorf("Expected mut (Num) Num != (Num) Num")
+	}
+	if !mutating.Equals(mutating) {
+		t.Errorf("Expected mut (Num) Num == mut (Num) Num")
+	}
+}
+
+func TestFunctionSignatures(t *testing.T) {
+	tests := []struct {
+		fn   FunctionType
+		want string
+	}{
+		{
+			fn:   FunctionType{Name: "noop", Parameters: []Type{}, ReturnType: VoidType},
+			want: "() Void",
+		},
+		{
+			fn:   FunctionType{Name: "add", Parameters: []Type{NumType, NumType}, ReturnType: NumType},
+			want: "(Num, Num) Num",
+		},
+		{
+			fn:   FunctionType{Name: "join", Parameters: []Type{MakeList(StrType), StrType}, ReturnType: StrType},
+			want: "([Str], Str) Str",
+		},
+		{
+			fn:   FunctionType{Name: "push", Mutates: true, Parameters: []Type{NumType}, ReturnType: NumType},
+			want: "mut (Num) Num",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.fn.String(); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.fn.Name, got, tt.want)
+		}
+	}
+}
+
 func TestGenerics(t *testing.T) {
 	Foo := GenericType{name: "T"}
 	if !Foo.Equals(NumType) {
@@ -185,3 +230,55 @@ func TestListApi(t *testing.T) {
 		t.Errorf("List.size should be Num")
 	}
 }
+
+func TestKinds(t *testing.T) {
+	filled := MakeGeneric("T")
+	filled.Fill(NumType)
+
+	kinds := []struct {
+		t    Type
+		kind Kind
+	}{
+		{StrType, KindStr},
+		{NumType, KindNum},
+		{BoolType, KindBool},
+		{VoidType, KindVoid},
+		{NeverType, KindNever},
+		{FunctionType{Name: "f", Parameters: []Type{}, ReturnType: VoidType}, KindFunction},
+		{StructType{Name: "Person", Fields: map[string]Type{}}, KindStruct},
+		{EnumType{Name: "Color", Variants: []string{"Red"}}, KindEnum},
+		{ModuleType{Name: "util"}, KindModule},
+		{MakeGeneric("T"), KindGeneric},
+		{filled, KindNum},
+		{MakeList(NumType), KindList},
+		{TupleType{Items: []Type{NumType, StrType}}, KindTuple},
+		{MakeMap(NumType), KindMap},
+		{OptionType{Inner: StrType}, KindOption},
+		{ResultType{OkType: StrType}, KindResult},
+	}
+	for _, tt := range kinds {
+		if got := tt.t.Kind(); got != tt.kind {
+			t.Errorf("%s: expected kind %d, got %d", tt.t, tt.kind, got)
+		}
+	}
+}
+
+func TestAnonymousStructs(t *testing.T) {
+	point := StructType{Fields: map[string]Type{"x": NumType, "y": NumType}}
+	samePoint := StructType{Fields: map[string]Type{"y": NumType, "x": NumType}}
+	label := StructType{Fields: map[string]Type{"x": StrType, "y": NumType}}
+	named := StructType{Name: "Point", Fields: map[string]Type{"x": NumType, "y": NumType}}
+
+	if got := point.String(); got != "{x: Num, y: Num}" {
+		t.Errorf("Expected anonymous structs to be spelled by their fields, got %s", got)
+	}
+	if !point.Equals(samePoint) {
+		t.Errorf("{x: Num, y: Num} == {y: Num, x: Num}")
+	}
+	if point.Equals(label) {
+		t.Errorf("{x: Num, y: Num} != {x: Str, y: Num}")
+	}
+	if point.Equals(named) || named.Equals(point) {
+		t.Errorf("Anonymous structs are not equal to named ones with the same fields")
+	}
+}
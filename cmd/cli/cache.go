@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+
+	"github.com/akonwi/ard/javascript"
+)
+
+// the released version of the compiler
+const compilerVersion = "0.1.0"
+
+// identifies the exact compiler that is running, so output generated by any other build is never reused.
+// the version only changes with a release, so the executable itself is hashed.
+// if it can't be read, the module version and VCS revision it was built from are used instead
+func compilerBuild() string {
+	if path, err := os.Executable(); err == nil {
+		if file, err := os.Open(path); err == nil {
+			defer file.Close()
+			hash := sha256.New()
+			if _, err := io.Copy(hash, file); err == nil {
+				return hex.EncodeToString(hash.Sum(nil))
+			}
+		}
+	}
+	build := compilerVersion
+	if info, ok := debug.ReadBuildInfo(); ok {
+		build += "\x00" + info.Main.Version
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" || setting.Key == "vcs.modified" {
+				build += "\x00" + setting.Value
+			}
+		}
+	}
+	return build
+}
+
+// generated output stored on disk by the hash of everything that went into it
+type buildCache struct {
+	dir     string
+	version string
+}
+
+func newBuildCache() buildCache {
+	return buildCache{dir: ".kon-cache", version: compilerBuild()}
+}
+
+// identifies the output of compiling @source with @options.
+// @imports are the keys of the modules it imports, since their signatures affect its checking.
+// @warningsFail, under --strict or --fail-on-warning, is included because warnings that passed before would fail
+func (c buildCache) key(source []byte, imports []string, options javascript.Options, emit string, warningsFail bool) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%t\x00%q\x00%t\x00%t\x00%t\x00%s\x00", c.version, emit, warningsFail, options.Indent, options.JSDoc, options.Exports, options.Pretty, options.Target)
+	for _, imported := range imports {
+		fmt.Fprintf(hash, "%s\x00", imported)
+	}
+	hash.Write(source)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c buildCache) get(key string) (string, bool) {
+	output, err := os.ReadFile(filepath.Join(c.dir, key))
+	if err != nil {
+		return "", false
+	}
+	return string(output), true
+}
+
+func (c buildCache) put(key string, output string) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.dir, key), []byte(output), 0644)
+}
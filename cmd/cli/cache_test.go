@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/akonwi/ard/javascript"
+)
+
+func TestBuildCache(t *testing.T) {
+	cache := buildCache{dir: t.TempDir(), version: "a build"}
+	source := []byte(`let name = "Alice"`)
+	key := cache.key(source, nil, javascript.DefaultOptions, "js", false)
+
+	if _, ok := cache.get(key); ok {
+		t.Fatalf("Expected a miss before anything is cached")
+	}
+	if err := cache.put(key, "const name = \"Alice\"\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	if output, ok := cache.get(cache.key(source, nil, javascript.DefaultOptions, "js", false)); !ok || output != "const name = \"Alice\"\n" {
+		t.Errorf("Expected a hit for unchanged source, got %q", output)
+	}
+
+	edited := []byte(`let name = "Bob"`)
+	if _, ok := cache.get(cache.key(edited, nil, javascript.DefaultOptions, "js", false)); ok {
+		t.Errorf("Expected a miss after the source was edited")
+	}
+
+	rebuilt := buildCache{dir: cache.dir, version: "another build"}
+	if _, ok := rebuilt.get(rebuilt.key(source, nil, javascript.DefaultOptions, "js", false)); ok {
+		t.Errorf("Expected a miss with a different compiler build")
+	}
+
+	if _, ok := cache.get(cache.key(source, []string{"changed import"}, javascript.DefaultOptions, "js", false)); ok {
+		t.Errorf("Expected a miss when an import changed")
+	}
+
+	pretty := javascript.DefaultOptions
+	pretty.Pretty = true
+	if _, ok := cache.get(cache.key(source, nil, pretty, "js", false)); ok {
+		t.Errorf("Expected a miss with --pretty")
+	}
+
+	es5 := javascript.DefaultOptions
+	es5.Target = javascript.ES5
+	if _, ok := cache.get(cache.key(source, nil, es5, "js", false)); ok {
+		t.Errorf("Expected a miss with --target es5")
+	}
+}
+
+func TestCompilerBuild(t *testing.T) {
+	// the test binary is the running executable
+	if build := compilerBuild(); build != compilerBuild() || build == compilerVersion {
+		t.Errorf("Expected a stable identifier for this build, got %q", build)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// project settings are read from this file in the working directory
+const configFile = "kon.toml"
+
+// applies the config file at @path to @commands.
+// its settings replace the built-in defaults, and flags on the command line replace both
+func configure(path string, commands ...*flag.FlagSet) error {
+	config, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	if err := checkConfig(config, commands...); err != nil {
+		return err
+	}
+	for _, command := range commands {
+		if err := applyConfig(command, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reads the settings in the config file at @path, by name.
+// only flat `name = value` pairs are supported, where a name is the name of a command line flag.
+// a missing file is the same as an empty one
+func loadConfig(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s - %v", path, err)
+	}
+	defer file.Close()
+
+	config := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("%s:%d: Expected 'name = value'", path, number)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		config[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Error reading %s - %v", path, err)
+	}
+	return config, nil
+}
+
+// uses the settings in @config as the defaults of the matching flags in @flags.
+// it must happen before parsing so that flags given on the command line take precedence
+func applyConfig(flags *flag.FlagSet, config map[string]string) error {
+	for name, value := range config {
+		if flags.Lookup(name) == nil {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("Invalid value for '%s' in %s: %s", name, configFile, value)
+		}
+	}
+	return nil
+}
+
+// settings that no command has a flag for are most likely typos
+func checkConfig(config map[string]string, commands ...*flag.FlagSet) error {
+	for name := range config {
+		known := false
+		for _, command := range commands {
+			if command.Lookup(name) != nil {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("Unknown setting '%s' in %s", name, configFile)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), configFile)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `
+# project defaults
+indent = "tab"
+strict = true
+out-dir = "dist"
+`)
+	config, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"indent": "tab", "strict": "true", "out-dir": "dist"}
+	if len(config) != len(want) {
+		t.Errorf("Expected %v, got %v", want, config)
+	}
+	for name, value := range want {
+		if config[name] != value {
+			t.Errorf("Expected %s = %q, got %q", name, value, config[name])
+		}
+	}
+
+	if _, err := loadConfig(writeConfig(t, "[build]\n")); err == nil {
+		t.Errorf("Expected an error for a line that isn't a setting")
+	}
+
+	config, err = loadConfig(filepath.Join(t.TempDir(), configFile))
+	if err != nil || len(config) != 0 {
+		t.Errorf("A missing config file has no settings, got %v, %v", config, err)
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+indent = 4
+strict = true
+`)
+	command := flag.NewFlagSet("build", flag.ContinueOnError)
+	strict := command.Bool("strict", false, "")
+	indent := command.String("indent", "2", "")
+	emit := command.String("emit", "js", "")
+
+	if err := configure(path, command); err != nil {
+		t.Fatal(err)
+	}
+	if err := command.Parse([]string{"--indent", "tab", "main.kon"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !*strict {
+		t.Errorf("Expected the config file to enable strict")
+	}
+	if *indent != "tab" {
+		t.Errorf("Expected the command line to override the config's indent, got %q", *indent)
+	}
+	if *emit != "js" {
+		t.Errorf("Expected the built-in default for emit, got %q", *emit)
+	}
+}
+
+func TestUnknownConfigSetting(t *testing.T) {
+	path := writeConfig(t, `colour = "blue"`)
+	command := flag.NewFlagSet("build", flag.ContinueOnError)
+	command.Bool("strict", false, "")
+	if err := configure(path, command); err == nil {
+		t.Errorf("Expected an error for an unknown setting")
+	}
+
+	path = writeConfig(t, `strict = "sometimes"`)
+	if err := configure(path, command); err == nil {
+		t.Errorf("Expected an error for an invalid value")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// lists the files changed since @ref, relative to the working directory, along with new files git doesn't track yet.
+// deleted files are left out since there's nothing left to check
+func gitDiff(ref string) (string, error) {
+	changed, err := exec.Command("git", "diff", "--name-only", "--relative", "--diff-filter=d", ref).Output()
+	if err != nil {
+		return "", err
+	}
+	untracked, err := exec.Command("git", "ls-files", "--others", "--exclude-standard").Output()
+	return string(changed) + string(untracked), err
+}
+
+// the .kon files in the output of `git diff --name-only`
+func konFiles(diffOutput string) []string {
+	files := []string{}
+	for _, line := range strings.Split(diffOutput, "\n") {
+		line = strings.TrimSpace(line)
+		if filepath.Ext(line) == ".kon" {
+			files = append(files, filepath.FromSlash(line))
+		}
+	}
+	return files
+}
+
+// the files to check with `--since @ref`: the @entries that changed or import a module that changed,
+// or every such file when there are no @entries. @importsOf reads the imports of a file.
+// @diff lists the changes. if it fails, e.g. outside of a git repository, nothing can be ruled out so everything is checked
+func changedSince(ref string, entries []string, diff func(ref string) (string, error), importsOf func(path string) ([]string, error)) ([]string, error) {
+	output, err := diff(ref)
+	if err != nil {
+		// stderr, so that machine readable diagnostics on stdout aren't interrupted
+		fmt.Fprintf(os.Stderr, "Unable to find changes since %s, checking every file\n", ref)
+		if len(entries) > 0 {
+			return entries, nil
+		}
+		return allKonFiles(".")
+	}
+
+	changed := konFiles(output)
+	isChanged := make(map[string]bool, len(changed))
+	for _, path := range changed {
+		isChanged[filepath.Clean(path)] = true
+	}
+	candidates := entries
+	if len(entries) == 0 {
+		if candidates, err = allKonFiles("."); err != nil {
+			return nil, err
+		}
+		// the changed files come first, then the files that import them
+		candidates = append(changed, candidates...)
+	}
+
+	// each file's imports are read once, however many files depend on it
+	imports := map[string][]string{}
+	seen := map[string]bool{}
+	affected := []string{}
+	for _, path := range candidates {
+		if seen[filepath.Clean(path)] {
+			continue
+		}
+		seen[filepath.Clean(path)] = true
+		if isAffected(path, isChanged, func(path string) ([]string, error) {
+			if found, ok := imports[path]; ok {
+				return found, nil
+			}
+			found, err := importsOf(path)
+			imports[path] = found
+			return found, err
+		}) {
+			affected = append(affected, path)
+		}
+	}
+	return affected, nil
+}
+
+// whether the file at @path, or any module it imports, is one of the @changed files.
+// a file whose imports can't be resolved is affected, so that checking it reports why
+func isAffected(path string, changed map[string]bool, importsOf func(path string) ([]string, error)) bool {
+	if changed[filepath.Clean(path)] {
+		return true
+	}
+	modules, err := resolveModules(path, importsOf)
+	if err != nil {
+		return true
+	}
+	for _, module := range modules {
+		if changed[filepath.Clean(module)] {
+			return true
+		}
+	}
+	return false
+}
+
+// every .kon file under @root, skipping hidden directories like .git and the build cache
+func allKonFiles(root string) ([]string, error) {
+	files := []string{}
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) == ".kon" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func stubDiff(output string) func(ref string) (string, error) {
+	return func(ref string) (string, error) {
+		return output, nil
+	}
+}
+
+func TestKonFiles(t *testing.T) {
+	output := "README.md\nmain.kon\nlib/util.kon\ncmd/cli/main.go\n\n"
+	want := []string{"main.kon", filepath.Join("lib", "util.kon")}
+	if got := konFiles(output); !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestChangedSince(t *testing.T) {
+	diff := stubDiff("main.kon\nlib/util.kon\nnotes.txt\n")
+
+	got, err := changedSince("main", nil, diff, stubImports(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"main.kon", filepath.Join("lib", "util.kon")}; !slices.Equal(got, want) {
+		t.Errorf("Without entries, every changed file is checked. Expected %v, got %v", want, got)
+	}
+
+	got, err = changedSince("main", []string{"./main.kon", "other.kon"}, diff, stubImports(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"./main.kon"}; !slices.Equal(got, want) {
+		t.Errorf("Only changed entries are checked. Expected %v, got %v", want, got)
+	}
+}
+
+func TestChangedSinceImporters(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{"main.kon", "app.kon", "other.kon", "lib/util.kon", "lib/new.kon"} {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	util := filepath.Join("lib", "util.kon")
+	imports := stubImports(map[string][]string{
+		"main.kon": {"app.kon"},
+		"app.kon":  {util},
+	})
+	diff := stubDiff("lib/util.kon\nlib/new.kon\n")
+
+	got, err := changedSince("main", nil, diff, imports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{util, filepath.Join("lib", "new.kon"), "app.kon", "main.kon"}; !slices.Equal(got, want) {
+		t.Errorf("Files that import a changed module, directly or not, are checked. Expected %v, got %v", want, got)
+	}
+
+	got, err = changedSince("main", []string{"main.kon", "other.kon"}, diff, imports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"main.kon"}; !slices.Equal(got, want) {
+		t.Errorf("Entries that import a changed module are checked. Expected %v, got %v", want, got)
+	}
+}
+
+func TestChangedSinceOutsideGit(t *testing.T) {
+	failing := func(ref string) (string, error) {
+		return "", errors.New("fatal: not a git repository")
+	}
+
+	entries := []string{"main.kon", "other.kon"}
+	got, err := changedSince("main", entries, failing, stubImports(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, entries) {
+		t.Errorf("Expected every entry to be checked, got %v", got)
+	}
+}
+
+func TestAllKonFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{"main.kon", "lib/util.kon", "lib/notes.txt", ".kon-cache/stale.kon"} {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(""), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := allKonFiles(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(root, "lib", "util.kon"), filepath.Join(root, "main.kon")}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// keeps the last parsed source and tree for a file so that subsequent parses
+// can reuse the unchanged parts of the previous tree
+type incrementalParser struct {
+	source []byte
+	tree   *tree_sitter.Tree
+	// the tree-sitter parser to use instead of the shared one, for parsing on another goroutine
+	parser *tree_sitter.Parser
+}
+
+// parses @source, incrementally if there is a previous tree to build from
+func (ip *incrementalParser) parse(source []byte) (*tree_sitter.Tree, error) {
+	parser := ip.parser
+	if parser == nil {
+		shared, err := konParser()
+		if err != nil {
+			return nil, err
+		}
+		parser = shared
+	}
+
+	if ip.tree != nil {
+		edit := computeEdit(ip.source, source)
+		ip.tree.Edit(&edit)
+	}
+
+	tree := parser.Parse(source, ip.tree)
+	if tree == nil {
+		return nil, nil
+	}
+	ip.source = source
+	ip.tree = tree
+	return tree, nil
+}
+
+// describes the change from @old to @new as a single edit spanning everything
+// between their common prefix and common suffix
+func computeEdit(old, new []byte) tree_sitter.InputEdit {
+	start := 0
+	for start < len(old) && start < len(new) && old[start] == new[start] {
+		start++
+	}
+
+	oldEnd, newEnd := len(old), len(new)
+	for oldEnd > start && newEnd > start && old[oldEnd-1] == new[newEnd-1] {
+		oldEnd--
+		newEnd--
+	}
+
+	return tree_sitter.InputEdit{
+		StartByte:      uint(start),
+		OldEndByte:     uint(oldEnd),
+		NewEndByte:     uint(newEnd),
+		StartPosition:  pointAt(old, start),
+		OldEndPosition: pointAt(old, oldEnd),
+		NewEndPosition: pointAt(new, newEnd),
+	}
+}
+
+// the row and column of the byte at @offset in @source
+func pointAt(source []byte, offset int) tree_sitter.Point {
+	point := tree_sitter.Point{}
+	for _, b := range source[:offset] {
+		if b == '\n' {
+			point.Row++
+			point.Column = 0
+		} else {
+			point.Column++
+		}
+	}
+	return point
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/akonwi/ard/ast"
+	ts_ard "github.com/akonwi/tree-sitter-ard/bindings/go"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestComputeEdit(t *testing.T) {
+	edit := computeEdit([]byte("let x = 1\nlet y = 2"), []byte("let x = 1\nlet y = 42"))
+	if edit.StartByte != 18 || edit.OldEndByte != 18 || edit.NewEndByte != 19 {
+		t.Errorf("Unexpected byte range: %d, %d, %d", edit.StartByte, edit.OldEndByte, edit.NewEndByte)
+	}
+	want := tree_sitter.Point{Row: 1, Column: 8}
+	if edit.StartPosition != want {
+		t.Errorf("Expected start position %v, got %v", want, edit.StartPosition)
+	}
+}
+
+func programString(t *testing.T, source []byte, tree *tree_sitter.Tree) []string {
+	program, err := ast.NewParser(source, tree).Parse()
+	if err != nil {
+		t.Fatalf("Error parsing tree: %v", err)
+	}
+	statements := make([]string, len(program.Statements))
+	for i, statement := range program.Statements {
+		statements[i] = statement.String()
+	}
+	return statements
+}
+
+func TestIncrementalReparse(t *testing.T) {
+	before := []byte(`
+fn add(x: Num, y: Num) Num { x + y }
+let total = add(1, 2)`)
+	after := []byte(`
+fn add(x: Num, y: Num) Num { x + y }
+mut count = 0
+let total = add(count, 20)`)
+
+	incremental := &incrementalParser{}
+	if _, err := incremental.parse(before); err != nil {
+		t.Fatal(err)
+	}
+	incrementalTree, err := incremental.parse(after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fullParser, err := ts_ard.MakeParser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fullTree := fullParser.Parse(after, nil)
+
+	if got, want := incrementalTree.RootNode().ToSexp(), fullTree.RootNode().ToSexp(); got != want {
+		t.Errorf("Incremental tree does not match a full reparse:\n%s\n%s", got, want)
+	}
+
+	incrementalAst := programString(t, after, incrementalTree)
+	fullAst := programString(t, after, fullTree)
+	if len(incrementalAst) != len(fullAst) {
+		t.Fatalf("Expected %d statements, got %d", len(fullAst), len(incrementalAst))
+	}
+	for i := range fullAst {
+		if incrementalAst[i] != fullAst[i] {
+			t.Errorf("Statement %d differs: %s != %s", i, incrementalAst[i], fullAst[i])
+		}
+	}
+}
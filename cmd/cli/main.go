@@ -3,23 +3,60 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/akonwi/ard/ast"
+	"github.com/akonwi/ard/checker"
 	"github.com/akonwi/ard/javascript"
 	ts_ard "github.com/akonwi/tree-sitter-ard/bindings/go"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 )
 
 func main() {
 	buildCmd := flag.NewFlagSet("build", flag.ExitOnError)
+	buildStrict := buildCmd.Bool("strict", false, "Treat warnings as errors")
+	buildIndent := buildCmd.String("indent", "2", "Indentation of generated code: a number of spaces or 'tab'")
+	buildNoCheck := buildCmd.Bool("no-check", false, "Print the generated JS to stdout even if there are errors")
+	buildJSDoc := buildCmd.Bool("jsdoc", false, "Annotate generated functions with JSDoc types")
+	buildPretty := buildCmd.Bool("pretty", false, "Normalize the whitespace of generated JS")
+	buildTarget := buildCmd.String("target", string(javascript.ES2020), "The JS version to generate: 'es2020' or 'es5'")
+	buildEmit := buildCmd.String("emit", "js", "What to generate: 'js' or 'dts' for a TypeScript declaration file")
+	buildOutDir := buildCmd.String("out-dir", "./build", "Where generated files are written")
+	buildStdinFilename := buildCmd.String("stdin-filename", stdinFilename, "The name of the file being read from stdin, for diagnostics and resolving imports")
+	buildQuiet := buildCmd.Bool("quiet", false, "Only print errors")
+	buildFailOnWarning := buildCmd.Bool("fail-on-warning", false, "Exit with an error if there are warnings, without reporting them as errors")
+	buildTrace := buildCmd.Bool("trace", false, "Print the time spent parsing, checking and generating each file to stderr")
+	checkCmd := flag.NewFlagSet("check", flag.ExitOnError)
+	checkStrict := checkCmd.Bool("strict", false, "Treat warnings as errors")
+	checkSince := checkCmd.String("since", "", "Only check files changed since this git ref")
+	checkFormat := checkCmd.String("diagnostics-format", "text", "How diagnostics are printed: 'text' or 'sarif'")
+	checkQuiet := checkCmd.Bool("quiet", false, "Only print errors")
+	checkFailOnWarning := checkCmd.Bool("fail-on-warning", false, "Exit with an error if there are warnings, without reporting them as errors")
+	checkShowTypes := checkCmd.Bool("show-types", false, "Print the inferred type of each top-level declaration")
+	watchCmd := flag.NewFlagSet("watch", flag.ExitOnError)
+	watchStrict := watchCmd.Bool("strict", false, "Treat warnings as errors")
+	watchIndent := watchCmd.String("indent", "2", "Indentation of generated code: a number of spaces or 'tab'")
+	watchTarget := watchCmd.String("target", string(javascript.ES2020), "The JS version to generate: 'es2020' or 'es5'")
+	watchOutDir := watchCmd.String("out-dir", "./build", "Where generated files are written")
+	watchQuiet := watchCmd.Bool("quiet", false, "Only print errors")
+	watchFailOnWarning := watchCmd.Bool("fail-on-warning", false, "Fail a rebuild if there are warnings, without reporting them as errors")
 
 	if len(os.Args) < 2 {
 		fmt.Println("Please provide a command")
 		os.Exit(1)
 	}
 
+	if err := configure(configFile, buildCmd, checkCmd, watchCmd); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	switch os.Args[1] {
 	case "build":
 		buildCmd.Parse(os.Args[2:])
@@ -28,62 +65,451 @@ func main() {
 			fmt.Println("Expected filepath argument")
 			os.Exit(1)
 		}
+		if *buildTrace {
+			trace.out = os.Stderr
+		}
 
-		inputPath := buildCmd.Arg(0)
-		sourceCode, err := os.ReadFile(inputPath)
+		indent, err := parseIndent(*buildIndent)
 		if err != nil {
-			fmt.Printf("Error reading file %s - %v\n", inputPath, err)
+			fmt.Println(err)
 			os.Exit(1)
 		}
-
-		tree, err := ts_ard.Parse(sourceCode)
+		target, err := parseTarget(*buildTarget)
 		if err != nil {
-			fmt.Println("Error parsing source code with tree-sitter")
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		options := javascript.Options{Indent: indent, JSDoc: *buildJSDoc, Pretty: *buildPretty, Target: target}
+		if *buildEmit != "js" && *buildEmit != "dts" {
+			fmt.Printf("Invalid --emit value: %s\n", *buildEmit)
 			os.Exit(1)
 		}
 
-		astParser := ast.NewParser(sourceCode, tree)
-		ast, err := astParser.Parse()
-		if err != nil {
-			fmt.Printf("Error parsing tree: %v\n", err)
+		if buildCmd.Arg(0) == "-" {
+			os.Exit(buildStdin(os.Stdin, *buildStdinFilename, *buildStrict, *buildQuiet, *buildFailOnWarning, !*buildNoCheck, options, *buildEmit, *buildOutDir, os.Stdout, os.Stderr))
+		}
+
+		if *buildNoCheck {
+			os.Exit(buildUnchecked(buildCmd.Arg(0), *buildQuiet, options, os.Stdout, os.Stderr))
+		}
+
+		if !build(buildCmd.Arg(0), *buildStrict, *buildQuiet, *buildFailOnWarning, options, *buildEmit, *buildOutDir, parsers{}) {
 			os.Exit(1)
-			return
-		}
-		diagnostics := astParser.GetDiagnostics()
-		if len(diagnostics) > 0 {
-			for _, diagnostic := range diagnostics {
-				fmt.Printf(
-					"[%d, %d] %s",
-					diagnostic.Range.StartPoint.Row,
-					diagnostic.Range.StartPoint.Column,
-					diagnostic.Msg,
-				)
-			}
+		}
+
+	case "check":
+		checkCmd.Parse(os.Args[2:])
+
+		if *checkFormat != "text" && *checkFormat != "sarif" {
+			fmt.Printf("Invalid --diagnostics-format value: %s\n", *checkFormat)
 			os.Exit(1)
 		}
 
-		jsSource := javascript.GenerateJS(ast)
+		entries := checkCmd.Args()
+		if *checkSince != "" {
+			changed, err := changedSince(*checkSince, entries, gitDiff, fileImports)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			if len(changed) == 0 {
+				if *checkFormat == "text" && !*checkQuiet {
+					fmt.Printf("No files changed since %s\n", *checkSince)
+				}
+				return
+			}
+			entries = changed
+		} else if len(entries) < 1 {
+			fmt.Println("Expected filepath argument")
+			os.Exit(1)
+		}
 
-		buildDir := "./build"
-		err = os.MkdirAll(buildDir, 0755)
-		if err != nil {
-			fmt.Printf("Error creating build directory: %v\n", err)
+		if !checkFiles(entries, *checkStrict, *checkQuiet, *checkFailOnWarning, *checkShowTypes, *checkFormat) {
 			os.Exit(1)
 		}
 
-		filename := filepath.Base(strings.TrimSuffix(inputPath, filepath.Ext(inputPath))) + ".js"
-		outputPath := filepath.Join(buildDir, filename)
+	case "watch":
+		watchCmd.Parse(os.Args[2:])
+
+		if watchCmd.NArg() < 1 {
+			fmt.Println("Expected filepath argument")
+			os.Exit(1)
+		}
 
-		err = os.WriteFile(outputPath, []byte(jsSource), 0644)
+		indent, err := parseIndent(*watchIndent)
 		if err != nil {
-			fmt.Printf("Error writing file %s - %v\n", outputPath, err)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		target, err := parseTarget(*watchTarget)
+		if err != nil {
+			fmt.Println(err)
 			os.Exit(1)
 		}
 
-		fmt.Printf("Successfully built to %s\n", outputPath)
+		inputPath := watchCmd.Arg(0)
+		options := javascript.Options{Indent: indent, Target: target}
+		cache := parsers{}
+		build(inputPath, *watchStrict, *watchQuiet, *watchFailOnWarning, options, "js", *watchOutDir, cache)
+		watch(inputPath, func() {
+			build(inputPath, *watchStrict, *watchQuiet, *watchFailOnWarning, options, "js", *watchOutDir, cache)
+		})
 
 	default:
 		fmt.Printf("Unknown command: %s\n", os.Args[1])
 		os.Exit(1)
 	}
 }
+
+// the incremental parser for each file of a build, kept between rebuilds
+type parsers map[string]*incrementalParser
+
+func (p parsers) get(path string) *incrementalParser {
+	if _, ok := p[path]; !ok {
+		p[path] = &incrementalParser{}
+	}
+	return p[path]
+}
+
+// compiles the file at @inputPath and every module it imports.
+// modules whose source and imports are unchanged since a previous build are reused from the cache,
+// the rest are checked concurrently.
+// with @quiet, only errors are printed. with @failOnWarning, warnings fail the build.
+// returns whether the build succeeded
+func build(inputPath string, strict bool, quiet bool, failOnWarning bool, options javascript.Options, emit string, outDir string, parsers parsers) bool {
+	imports := map[string][]string{}
+	modules, err := resolveModules(inputPath, recordImports(imports))
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	cache := newBuildCache()
+	keys := map[string]string{}
+	moduleOptions := map[string]javascript.Options{}
+	outputs := map[string]string{}
+	misses := []string{}
+	for _, path := range modules {
+		pathOptions := options
+		// the entry module is the only one nothing imports
+		pathOptions.Exports = path != inputPath
+		moduleOptions[path] = pathOptions
+
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Error reading file %s - %v\n", path, err)
+			return false
+		}
+		importKeys := make([]string, len(imports[path]))
+		for i, imported := range imports[path] {
+			importKeys[i] = keys[imported]
+		}
+		keys[path] = cache.key(source, importKeys, moduleOptions[path], emit, strict || failOnWarning)
+
+		if output, ok := cache.get(keys[path]); ok {
+			outputs[path] = output
+		} else {
+			misses = append(misses, path)
+		}
+	}
+
+	analyses := analyzeAll(misses, imports, parsers)
+	if !report(os.Stdout, analyses, strict, quiet, failOnWarning) {
+		return false
+	}
+	for _, result := range analyses {
+		start := time.Now()
+		output := generate(result.program, moduleOptions[result.path], emit)
+		trace.record(result.path, codegenPhase, start)
+		outputs[result.path] = output
+		if err := cache.put(keys[result.path], output); err != nil {
+			fmt.Printf("Error writing to the build cache - %v\n", err)
+		}
+	}
+
+	root := sourceRoot(modules)
+	for _, path := range modules {
+		if !writeOutput(os.Stdout, outDir, root, path, emit, outputs[path], quiet) {
+			return false
+		}
+	}
+	return true
+}
+
+// checks the files at @entries and every module they import, printing diagnostics file by file
+// as text, or as one SARIF document when @format is "sarif".
+// @quiet leaves out everything but errors from text. a SARIF document is always complete.
+// with @types, the type of each top-level declaration is printed before the diagnostics as text.
+// returns false if any file has errors, or warnings with @failOnWarning
+func checkFiles(entries []string, strict bool, quiet bool, failOnWarning bool, types bool, format string) bool {
+	imports := map[string][]string{}
+	modules := []string{}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		resolved, err := resolveModules(entry, recordImports(imports))
+		if err != nil {
+			fmt.Println(err)
+			return false
+		}
+		for _, path := range resolved {
+			if !seen[path] {
+				seen[path] = true
+				modules = append(modules, path)
+			}
+		}
+	}
+	analyses := analyzeAll(modules, imports, parsers{})
+	if format == "sarif" {
+		return reportSARIF(os.Stdout, analyses, strict, failOnWarning)
+	}
+	if types {
+		showTypes(os.Stdout, analyses)
+	}
+	return report(os.Stdout, analyses, strict, quiet, failOnWarning)
+}
+
+// reads the imports of each file and remembers them in @imports, by file
+func recordImports(imports map[string][]string) func(path string) ([]string, error) {
+	return func(path string) ([]string, error) {
+		found, err := fileImports(path)
+		imports[path] = found
+		return found, err
+	}
+}
+
+// prints the diagnostics of each analysis to @out, only the errors with @quiet.
+// returns false if any file failed or has errors, or warnings with @failOnWarning
+func report(out io.Writer, analyses []analysis, strict bool, quiet bool, failOnWarning bool) bool {
+	ok := true
+	for _, result := range analyses {
+		if result.err != nil {
+			fmt.Fprintln(out, result.err)
+			ok = false
+			continue
+		}
+		for _, diagnostic := range result.diagnostics {
+			// warnings that fail the build are worth printing even with @quiet
+			if shown(diagnostic, strict, quiet) || (failOnWarning && diagnostic.Severity == checker.Warning) {
+				fmt.Fprintln(out, formatDiagnostic(result.path, diagnostic, strict))
+			}
+		}
+		if exitCode(result.diagnostics, strict, failOnWarning) != 0 {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// the JS for @program, or its TypeScript declarations when @emit is "dts"
+func generate(program ast.Program, options javascript.Options, emit string) string {
+	if emit == "dts" {
+		return javascript.GenerateDTS(program, options)
+	}
+	return javascript.GenerateJSWithOptions(program, options)
+}
+
+// the closest directory containing every file of @modules.
+// outputs keep their paths relative to it, so imports between modules still resolve
+func sourceRoot(modules []string) string {
+	// the entry is resolved last
+	root, _ := filepath.Abs(filepath.Dir(modules[len(modules)-1]))
+	for _, path := range modules {
+		path, _ = filepath.Abs(path)
+		for !isWithin(root, path) && root != filepath.Dir(root) {
+			root = filepath.Dir(root)
+		}
+	}
+	return root
+}
+
+// whether @path is inside the directory @dir
+func isWithin(dir string, path string) bool {
+	relative, err := filepath.Rel(dir, path)
+	return err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
+
+// writes the @output generated for the file at @inputPath to the build directory,
+// at the file's path relative to @root. progress and errors are printed to @out
+func writeOutput(out io.Writer, buildDir string, root string, inputPath string, emit string, output string, quiet bool) bool {
+	extension := ".js"
+	if emit == "dts" {
+		extension = ".d.ts"
+	}
+	absolute, err := filepath.Abs(strings.TrimSuffix(inputPath, filepath.Ext(inputPath)))
+	if err != nil {
+		fmt.Fprintf(out, "Error resolving the output path of %s - %v\n", inputPath, err)
+		return false
+	}
+	relative, err := filepath.Rel(root, absolute)
+	if err != nil {
+		fmt.Fprintf(out, "Error resolving the output path of %s - %v\n", inputPath, err)
+		return false
+	}
+	outputPath := filepath.Join(buildDir, relative+extension)
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		fmt.Fprintf(out, "Error creating build directory: %v\n", err)
+		return false
+	}
+	if err := os.WriteFile(outputPath, []byte(output), 0644); err != nil {
+		fmt.Fprintf(out, "Error writing file %s - %v\n", outputPath, err)
+		return false
+	}
+	if !quiet {
+		fmt.Fprintf(out, "Successfully built to %s\n", outputPath)
+	}
+	return true
+}
+
+// generates JS for the file at @inputPath regardless of its diagnostics.
+// diagnostics go to @stderr, only the errors with @quiet, and the JS to @stdout. returns the exit code
+func buildUnchecked(inputPath string, quiet bool, options javascript.Options, stdout, stderr io.Writer) int {
+	modules, err := resolveModules(inputPath, fileImports)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	exports := map[string]checker.ModuleType{}
+	var program ast.Program
+	for _, path := range modules {
+		parsed, diagnostics, err := analyze(path, &incrementalParser{}, exports)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		for _, diagnostic := range diagnostics {
+			if shown(diagnostic, false, quiet) {
+				fmt.Fprintln(stderr, formatDiagnostic(path, diagnostic, false))
+			}
+		}
+		program = parsed
+	}
+	// modules are in dependency order, so the last one is the entry
+	start := time.Now()
+	output := javascript.GenerateJSWithOptions(program, options)
+	trace.record(modules[len(modules)-1], codegenPhase, start)
+	fmt.Fprint(stdout, output)
+	return 0
+}
+
+// reads, parses and type checks the file at @inputPath.
+// @exports holds the modules analyzed so far by path, for resolving imports, and gains this one
+func analyze(inputPath string, parser *incrementalParser, exports map[string]checker.ModuleType) (ast.Program, []checker.Diagnostic, error) {
+	sourceCode, err := os.ReadFile(inputPath)
+	if err != nil {
+		return ast.Program{}, nil, fmt.Errorf("Error reading file %s - %v", inputPath, err)
+	}
+	return analyzeSource(inputPath, sourceCode, parser, exports)
+}
+
+// like analyze, for @sourceCode that was read from somewhere other than @inputPath, such as stdin
+func analyzeSource(inputPath string, sourceCode []byte, parser *incrementalParser, exports map[string]checker.ModuleType) (ast.Program, []checker.Diagnostic, error) {
+	start := time.Now()
+	tree, err := parser.parse(sourceCode)
+	trace.record(inputPath, parsePhase, start)
+	if err != nil {
+		return ast.Program{}, nil, fmt.Errorf("Error loading the tree-sitter parser: %v", err)
+	}
+	if tree == nil {
+		return ast.Program{}, nil, fmt.Errorf("Error parsing source code with tree-sitter")
+	}
+
+	astParser := ast.NewParser(sourceCode, tree)
+	for _, importPath := range ast.ImportPaths(sourceCode, tree) {
+		resolved := resolveImport(inputPath, importPath)
+		// a module reused from the build cache hasn't been analyzed yet
+		if _, ok := exports[resolved]; !ok {
+			if _, err := os.Stat(resolved); err == nil {
+				if _, _, err := analyze(resolved, &incrementalParser{parser: parser.parser}, exports); err != nil {
+					return ast.Program{}, nil, err
+				}
+			}
+		}
+		if module, ok := exports[resolved]; ok {
+			astParser.AddModule(importPath, module)
+		}
+	}
+	start = time.Now()
+	program, err := astParser.Parse()
+	trace.record(inputPath, checkPhase, start)
+	if err != nil {
+		return ast.Program{}, nil, fmt.Errorf("Error parsing tree: %v", err)
+	}
+	exports[inputPath] = astParser.Module(moduleName(inputPath))
+	return program, astParser.GetDiagnostics(), nil
+}
+
+var (
+	parserOnce   sync.Once
+	sharedParser *tree_sitter.Parser
+	parserErr    error
+)
+
+// the tree-sitter parser is created once and reused for every file compiled by this process
+func konParser() (*tree_sitter.Parser, error) {
+	parserOnce.Do(func() {
+		sharedParser, parserErr = ts_ard.MakeParser()
+	})
+	return sharedParser, parserErr
+}
+
+// in strict mode, warnings are reported and treated as errors
+func effectiveSeverity(diagnostic checker.Diagnostic, strict bool) checker.Severity {
+	if strict && diagnostic.Severity == checker.Warning {
+		return checker.Error
+	}
+	return diagnostic.Severity
+}
+
+// formats as `path:line:column: severity: [code] message`, with 1-based lines and columns
+func formatDiagnostic(path string, diagnostic checker.Diagnostic, strict bool) string {
+	msg := diagnostic.Msg
+	if diagnostic.Code != "" {
+		msg = fmt.Sprintf("[%s] %s", diagnostic.Code, msg)
+	}
+	return fmt.Sprintf(
+		"%s:%d:%d: %s: %s",
+		path,
+		diagnostic.Range.StartPoint.Row+1,
+		diagnostic.Range.StartPoint.Column+1,
+		effectiveSeverity(diagnostic, strict),
+		msg,
+	)
+}
+
+// with @quiet, only diagnostics that are errors are printed
+func shown(diagnostic checker.Diagnostic, strict bool, quiet bool) bool {
+	return !quiet || effectiveSeverity(diagnostic, strict) == checker.Error
+}
+
+// with @failOnWarning, warnings fail like errors while still being reported as warnings
+func exitCode(diagnostics []checker.Diagnostic, strict bool, failOnWarning bool) int {
+	for _, diagnostic := range diagnostics {
+		severity := effectiveSeverity(diagnostic, strict)
+		if severity == checker.Error || (failOnWarning && severity == checker.Warning) {
+			return 1
+		}
+	}
+	return 0
+}
+
+// @value is either a number of spaces or "tab"
+func parseIndent(value string) (string, error) {
+	if value == "tab" {
+		return "\t", nil
+	}
+	width, err := strconv.Atoi(value)
+	if err != nil || width < 1 {
+		return "", fmt.Errorf("Invalid --indent value: %s", value)
+	}
+	return strings.Repeat(" ", width), nil
+}
+
+func parseTarget(value string) (javascript.Target, error) {
+	switch target := javascript.Target(value); target {
+	case javascript.ES2020, javascript.ES5:
+		return target, nil
+	default:
+		return "", fmt.Errorf("Invalid --target value: %s", value)
+	}
+}
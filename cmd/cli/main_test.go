@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/akonwi/ard/checker"
+	"github.com/akonwi/ard/javascript"
+	ts_ard "github.com/akonwi/tree-sitter-ard/bindings/go"
+)
+
+func TestExitCode(t *testing.T) {
+	warnings := []checker.Diagnostic{
+		{Msg: "'x' shadows an existing declaration", Severity: checker.Warning},
+	}
+	errors := []checker.Diagnostic{
+		{Msg: "Undefined: 'x'", Severity: checker.Error},
+	}
+
+	if code := exitCode([]checker.Diagnostic{}, false, false); code != 0 {
+		t.Errorf("A clean program exits 0, got %d", code)
+	}
+	if code := exitCode(warnings, false, false); code != 0 {
+		t.Errorf("Warnings alone exit 0, got %d", code)
+	}
+	if code := exitCode(warnings, true, false); code == 0 {
+		t.Errorf("Warnings exit non-zero under --strict")
+	}
+	if code := exitCode(warnings, false, true); code == 0 {
+		t.Errorf("Warnings exit non-zero with --fail-on-warning")
+	}
+	if code := exitCode(errors, false, false); code == 0 {
+		t.Errorf("Errors exit non-zero")
+	}
+
+	info := []checker.Diagnostic{
+		{Code: checker.TypeOf, Msg: "type of expression is Num", Severity: checker.Info},
+	}
+	if code := exitCode(info, true, false); code != 0 {
+		t.Errorf("Information exits 0, even under --strict, got %d", code)
+	}
+}
+
+func TestFailOnWarning(t *testing.T) {
+	warnings := []analysis{{path: "main.kon", diagnostics: []checker.Diagnostic{
+		{Code: checker.Shadowing, Msg: "'x' shadows an existing declaration", Severity: checker.Warning},
+	}}}
+	info := []analysis{{path: "main.kon", diagnostics: []checker.Diagnostic{
+		{Code: checker.TypeOf, Msg: "type of expression is Num", Severity: checker.Info},
+	}}}
+	warningLine := "main.kon:1:1: warning: [K031] 'x' shadows an existing declaration\n"
+
+	var out bytes.Buffer
+	if !report(&out, warnings, false, false, false) {
+		t.Errorf("Warnings alone should succeed by default")
+	}
+	if got := out.String(); got != warningLine {
+		t.Errorf("Expected the warning by default, got %q", got)
+	}
+
+	out.Reset()
+	if report(&out, warnings, false, false, true) {
+		t.Errorf("Warnings should fail with --fail-on-warning")
+	}
+	if got := out.String(); got != warningLine {
+		t.Errorf("Expected the warning to still be a warning with --fail-on-warning, got %q", got)
+	}
+
+	out.Reset()
+	if report(&out, warnings, true, false, false) {
+		t.Errorf("Warnings should fail under --strict")
+	}
+	if got := out.String(); !strings.Contains(got, "error: [K031]") {
+		t.Errorf("Expected --strict to report the warning as an error, got %q", got)
+	}
+
+	out.Reset()
+	report(&out, warnings, false, true, true)
+	if got := out.String(); got != warningLine {
+		t.Errorf("Expected warnings that fail to be printed under --quiet, got %q", got)
+	}
+
+	if !report(&bytes.Buffer{}, info, false, false, true) {
+		t.Errorf("Information should not fail with --fail-on-warning")
+	}
+}
+
+func TestQuietReport(t *testing.T) {
+	warnings := []analysis{{path: "main.kon", diagnostics: []checker.Diagnostic{
+		{Code: checker.Shadowing, Msg: "'x' shadows an existing declaration", Severity: checker.Warning},
+	}}}
+	errors := []analysis{{path: "main.kon", diagnostics: []checker.Diagnostic{
+		{Code: checker.Shadowing, Msg: "'x' shadows an existing declaration", Severity: checker.Warning},
+		{Code: checker.Undefined, Msg: "Undefined: 'x'", Severity: checker.Error},
+	}}}
+
+	var out bytes.Buffer
+	if !report(&out, warnings, false, true, false) {
+		t.Errorf("Warnings alone should succeed under --quiet")
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no output for warnings under --quiet, got %q", out.String())
+	}
+
+	out.Reset()
+	if report(&out, errors, false, true, false) {
+		t.Errorf("Errors should fail under --quiet")
+	}
+	if got := out.String(); got != "main.kon:1:1: error: [K010] Undefined: 'x'\n" {
+		t.Errorf("Expected only the error under --quiet, got %q", got)
+	}
+
+	out.Reset()
+	if report(&out, warnings, true, true, false) {
+		t.Errorf("Warnings should fail under --strict and --quiet")
+	}
+	if !strings.Contains(out.String(), "error: [K031]") {
+		t.Errorf("Expected warnings promoted by --strict to be printed under --quiet, got %q", out.String())
+	}
+}
+
+func TestFormatDiagnostic(t *testing.T) {
+	warning := checker.Diagnostic{Code: checker.Shadowing, Msg: "'x' shadows an existing declaration", Severity: checker.Warning}
+
+	if got := formatDiagnostic("main.kon", warning, false); got != "main.kon:1:1: warning: [K031] 'x' shadows an existing declaration" {
+		t.Errorf("Unexpected format: %s", got)
+	}
+	if got := formatDiagnostic("main.kon", warning, true); got != "main.kon:1:1: error: [K031] 'x' shadows an existing declaration" {
+		t.Errorf("Unexpected format under --strict: %s", got)
+	}
+
+	mismatch := checker.Diagnostic{Code: checker.TypeMismatch, Msg: "Type mismatch: expected Str, got Num"}
+	mismatch.Range.StartPoint.Row = 2
+	mismatch.Range.StartPoint.Column = 4
+	if got := formatDiagnostic("main.kon", mismatch, false); got != "main.kon:3:5: error: [K001] Type mismatch: expected Str, got Num" {
+		t.Errorf("Unexpected format: %s", got)
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	outDir := filepath.Join(t.TempDir(), "build")
+	modules := []string{
+		filepath.Join(src, "a", "util.kon"),
+		filepath.Join(src, "b", "util.kon"),
+		filepath.Join(src, "..", "shared.kon"),
+		filepath.Join(src, "main.kon"),
+	}
+	root := sourceRoot(modules)
+	for _, path := range modules {
+		if !writeOutput(io.Discard, outDir, root, path, "js", path, true) {
+			t.Fatalf("Failed to write the output of %s", path)
+		}
+	}
+
+	for _, path := range []string{"src/a/util.js", "src/b/util.js", "shared.js", "src/main.js"} {
+		if _, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(path))); err != nil {
+			t.Errorf("Expected %s in the build directory: %v", path, err)
+		}
+	}
+}
+
+func TestParseIndent(t *testing.T) {
+	for input, want := range map[string]string{"2": "  ", "4": "    ", "tab": "\t"} {
+		got, err := parseIndent(input)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", input, err)
+		}
+		if got != want {
+			t.Errorf("parseIndent(%q) = %q, want %q", input, got, want)
+		}
+	}
+	if _, err := parseIndent("wide"); err == nil {
+		t.Errorf("Expected an error for a non-numeric indent")
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	for input, want := range map[string]javascript.Target{"es2020": javascript.ES2020, "es5": javascript.ES5} {
+		got, err := parseTarget(input)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", input, err)
+		}
+		if got != want {
+			t.Errorf("parseTarget(%q) = %q, want %q", input, got, want)
+		}
+	}
+	if _, err := parseTarget("es3"); err == nil {
+		t.Errorf("Expected an error for an unsupported target")
+	}
+}
+
+func benchmarkSources() [][]byte {
+	sources := make([][]byte, 50)
+	for i := range sources {
+		sources[i] = []byte(fmt.Sprintf(`
+fn add(x: Num, y: Num) Num { x + y }
+let total = add(%d, 1)`, i))
+	}
+	return sources
+}
+
+func BenchmarkParseWithSharedParser(b *testing.B) {
+	sources := benchmarkSources()
+	b.ResetTimer()
+	for range b.N {
+		parser, err := konParser()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, source := range sources {
+			parser.Parse(source, nil)
+		}
+	}
+}
+
+func BenchmarkParseWithParserPerFile(b *testing.B) {
+	sources := benchmarkSources()
+	b.ResetTimer()
+	for range b.N {
+		for _, source := range sources {
+			parser, err := ts_ard.MakeParser()
+			if err != nil {
+				b.Fatal(err)
+			}
+			parser.Parse(source, nil)
+		}
+	}
+}
+
+func TestBuildUnchecked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.kon")
+	if err := os.WriteFile(path, []byte(`let name: Str = 42`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := buildUnchecked(path, false, javascript.DefaultOptions, &stdout, &stderr); code != 0 {
+		t.Errorf("Expected --no-check to exit 0, got %d", code)
+	}
+	if got := stdout.String(); got != "const name = 42\n" {
+		t.Errorf("Unexpected JS output: %q", got)
+	}
+	if !strings.Contains(stderr.String(), "[K001] Type mismatch: expected Str, got Num") {
+		t.Errorf("Expected the type mismatch on stderr, got %q", stderr.String())
+	}
+}
+
+func TestBuildStdin(t *testing.T) {
+	stdin := strings.NewReader(`let name: Str = 42`)
+	var stdout, stderr bytes.Buffer
+	if code := buildStdin(stdin, "src/greeting.kon", false, false, false, true, javascript.DefaultOptions, "js", t.TempDir(), &stdout, &stderr); code == 0 {
+		t.Errorf("Expected errors to exit non-zero")
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Expected no output with errors, got %q", stdout.String())
+	}
+	if !strings.HasPrefix(stderr.String(), "src/greeting.kon:1:") || !strings.Contains(stderr.String(), "[K001]") {
+		t.Errorf("Expected the diagnostic to name the stdin file, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := buildStdin(strings.NewReader(`let name = "Joe"`), stdinFilename, false, false, false, true, javascript.DefaultOptions, "js", t.TempDir(), &stdout, &stderr); code != 0 {
+		t.Errorf("Expected a clean build to exit 0, got %d: %s", code, stderr.String())
+	}
+	if got := stdout.String(); got != "const name = \"Joe\"\n" {
+		t.Errorf("Unexpected JS output: %q", got)
+	}
+}
+
+func TestBuildStdinImports(t *testing.T) {
+	src := t.TempDir()
+	util := filepath.Join(src, "lib", "util.kon")
+	if err := os.MkdirAll(filepath.Dir(util), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(util, []byte(`fn greet() Str { "Hello" }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outDir := t.TempDir()
+	stdin := strings.NewReader("use lib/util\nutil.greet()")
+	var stdout, stderr bytes.Buffer
+	if code := buildStdin(stdin, filepath.Join(src, "main.kon"), false, false, false, true, javascript.DefaultOptions, "js", outDir, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected a clean build to exit 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `import * as util from "./lib/util.js"`) {
+		t.Errorf("Expected the import in the output, got %q", stdout.String())
+	}
+	// the output imports the module from where it was built
+	if _, err := os.Stat(filepath.Join(outDir, "lib", "util.js")); err != nil {
+		t.Errorf("Expected the imported module in the build directory: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/akonwi/ard/ast"
+)
+
+// the name a module is imported by, e.g. `util` for lib/util.kon
+func moduleName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// the file that `use @importPath` refers to from the module at @importer
+func resolveImport(importer, importPath string) string {
+	return filepath.Join(filepath.Dir(importer), filepath.FromSlash(importPath)+".kon")
+}
+
+// reads the imports of the file at @path, resolved to file paths
+func fileImports(path string) ([]string, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading file %s - %v", path, err)
+	}
+	return sourceImports(path, source)
+}
+
+// the imports in @source, resolved to file paths relative to @path
+func sourceImports(path string, source []byte) ([]string, error) {
+	parser, err := konParser()
+	if err != nil {
+		return nil, fmt.Errorf("Error loading the tree-sitter parser: %v", err)
+	}
+	tree := parser.Parse(source, nil)
+	if tree == nil {
+		return nil, fmt.Errorf("Error parsing source code with tree-sitter")
+	}
+	defer tree.Close()
+
+	imports := ast.ImportPaths(source, tree)
+	for i, importPath := range imports {
+		imports[i] = resolveImport(path, importPath)
+	}
+	return imports, nil
+}
+
+// walks the imports of @entry and returns every module it depends on, each after its own imports.
+// an import cycle is an error because neither module could be checked first
+func resolveModules(entry string, importsOf func(path string) ([]string, error)) ([]string, error) {
+	order := []string{}
+	visited := map[string]bool{}
+	// the chain of imports currently being walked
+	stack := []string{}
+
+	var visit func(path string) error
+	visit = func(path string) error {
+		for i, ancestor := range stack {
+			if ancestor == path {
+				names := make([]string, 0, len(stack)-i+1)
+				for _, module := range stack[i:] {
+					names = append(names, moduleName(module))
+				}
+				names = append(names, moduleName(path))
+				return fmt.Errorf("Import cycle detected: %s", strings.Join(names, " -> "))
+			}
+		}
+		if visited[path] {
+			return nil
+		}
+
+		imports, err := importsOf(path)
+		if err != nil {
+			return err
+		}
+		stack = append(stack, path)
+		for _, imported := range imports {
+			if err := visit(imported); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+
+		visited[path] = true
+		order = append(order, path)
+		return nil
+	}
+
+	if err := visit(entry); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// resolves imports from a fixed graph of module paths
+func stubImports(graph map[string][]string) func(string) ([]string, error) {
+	return func(path string) ([]string, error) {
+		return graph[path], nil
+	}
+}
+
+func TestResolveModules(t *testing.T) {
+	order, err := resolveModules("main.kon", stubImports(map[string][]string{
+		"main.kon":     {"lib/util.kon", "lib/io.kon"},
+		"lib/util.kon": {"lib/io.kon"},
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []string{"lib/io.kon", "lib/util.kon", "main.kon"}
+	if !slices.Equal(order, want) {
+		t.Errorf("Expected modules in dependency order %v, got %v", want, order)
+	}
+}
+
+func TestImportCycle(t *testing.T) {
+	_, err := resolveModules("a.kon", stubImports(map[string][]string{
+		"a.kon": {"b.kon"},
+		"b.kon": {"a.kon"},
+	}))
+	if err == nil {
+		t.Fatal("Expected an import cycle error")
+	}
+	if err.Error() != "Import cycle detected: a -> b -> a" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"runtime"
+	"sort"
+
+	"github.com/akonwi/ard/ast"
+	"github.com/akonwi/ard/checker"
+	ts_ard "github.com/akonwi/tree-sitter-ard/bindings/go"
+)
+
+// the outcome of analyzing one file
+type analysis struct {
+	path        string
+	program     ast.Program
+	diagnostics []checker.Diagnostic
+	err         error
+	exports     checker.ModuleType
+}
+
+type analysisJob struct {
+	path   string
+	parser *incrementalParser
+	// the modules analyzed so far. each job gets its own copy
+	exports map[string]checker.ModuleType
+}
+
+// analyzes @modules concurrently with up to GOMAXPROCS workers.
+// a module is only started once the modules it imports, according to @imports, are done,
+// so that their signatures are known. results are sorted by path regardless of completion order
+func analyzeAll(modules []string, imports map[string][]string, parsers parsers) []analysis {
+	if len(modules) == 0 {
+		return []analysis{}
+	}
+
+	jobs := make(chan analysisJob, len(modules))
+	results := make(chan analysis, len(modules))
+	for range min(runtime.GOMAXPROCS(0), len(modules)) {
+		go analysisWorker(jobs, results)
+	}
+	defer close(jobs)
+
+	pending := make(map[string]bool, len(modules))
+	for _, path := range modules {
+		pending[path] = true
+	}
+	exports := map[string]checker.ModuleType{}
+	started := map[string]bool{}
+	// starts every pending module whose imports have all been analyzed
+	startReady := func() {
+		for _, path := range modules {
+			if started[path] {
+				continue
+			}
+			ready := true
+			for _, imported := range imports[path] {
+				if pending[imported] {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+			snapshot := make(map[string]checker.ModuleType, len(exports))
+			for path, module := range exports {
+				snapshot[path] = module
+			}
+			started[path] = true
+			jobs <- analysisJob{path: path, parser: parsers.get(path), exports: snapshot}
+		}
+	}
+
+	startReady()
+	analyses := make([]analysis, 0, len(modules))
+	for range modules {
+		result := <-results
+		analyses = append(analyses, result)
+		delete(pending, result.path)
+		if result.err == nil {
+			exports[result.path] = result.exports
+		}
+		startReady()
+	}
+
+	sort.Slice(analyses, func(i, j int) bool {
+		return analyses[i].path < analyses[j].path
+	})
+	return analyses
+}
+
+// tree-sitter parsers aren't safe to share between goroutines, so each worker makes its own
+func analysisWorker(jobs <-chan analysisJob, results chan<- analysis) {
+	parser, parserErr := ts_ard.MakeParser()
+	if parserErr == nil {
+		defer parser.Close()
+	}
+	for job := range jobs {
+		if parserErr != nil {
+			results <- analysis{path: job.path, err: parserErr}
+			continue
+		}
+		job.parser.parser = parser
+		program, diagnostics, err := analyze(job.path, job.parser, job.exports)
+		results <- analysis{
+			path:        job.path,
+			program:     program,
+			diagnostics: diagnostics,
+			err:         err,
+			exports:     job.exports[job.path],
+		}
+	}
+}
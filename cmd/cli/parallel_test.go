@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeAllConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	modules := []string{}
+	for i := range 8 {
+		path := filepath.Join(dir, fmt.Sprintf("file_%d.kon", i))
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("let name: Str = %d", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modules = append(modules, path)
+	}
+
+	for range 5 {
+		var out bytes.Buffer
+		if report(&out, analyzeAll(modules, map[string][]string{}, parsers{}), false, false, false) {
+			t.Fatalf("Expected the files to have errors")
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != len(modules) {
+			t.Fatalf("Expected a diagnostic for each file, got:\n%s", out.String())
+		}
+		for i, line := range lines {
+			if !strings.HasPrefix(line, modules[i]+":") || !strings.HasSuffix(line, "[K001] Type mismatch: expected Str, got Num") {
+				t.Fatalf("Diagnostics are out of order, expected %s at line %d:\n%s", modules[i], i+1, out.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/akonwi/ard/checker"
+)
+
+// the subset of SARIF v2.1.0 needed to report diagnostics, e.g. to GitHub code scanning
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIF lines and columns are 1-based, and the end column is exclusive like tree-sitter's
+type sarifRegion struct {
+	StartLine   uint `json:"startLine"`
+	StartColumn uint `json:"startColumn"`
+	EndLine     uint `json:"endLine"`
+	EndColumn   uint `json:"endColumn"`
+}
+
+// builds a SARIF log with a result for each diagnostic in @analyses.
+// a file that couldn't be analyzed is reported as an error without a region
+func makeSARIF(analyses []analysis, strict bool) sarifLog {
+	results := []sarifResult{}
+	codes := map[checker.Code]bool{}
+	for _, result := range analyses {
+		artifact := sarifArtifactLocation{URI: filepath.ToSlash(result.path)}
+		if result.err != nil {
+			results = append(results, sarifResult{
+				Level:     "error",
+				Message:   sarifMessage{Text: result.err.Error()},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: artifact}}},
+			})
+			continue
+		}
+		for _, diagnostic := range result.diagnostics {
+			if diagnostic.Code != "" {
+				codes[diagnostic.Code] = true
+			}
+			start, end := diagnostic.Range.StartPoint, diagnostic.Range.EndPoint
+			results = append(results, sarifResult{
+				RuleID:  string(diagnostic.Code),
+				Level:   sarifLevel(effectiveSeverity(diagnostic, strict)),
+				Message: sarifMessage{Text: diagnostic.Msg},
+				Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: artifact,
+					Region: &sarifRegion{
+						StartLine:   start.Row + 1,
+						StartColumn: start.Column + 1,
+						EndLine:     end.Row + 1,
+						EndColumn:   end.Column + 1,
+					},
+				}}},
+			})
+		}
+	}
+
+	rules := make([]sarifRule, 0, len(codes))
+	for code := range codes {
+		rules = append(rules, sarifRule{ID: string(code)})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+
+	return sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "kon", Version: compilerVersion, Rules: rules}},
+			Results: results,
+		}},
+	}
+}
+
+// SARIF calls informational results notes
+func sarifLevel(severity checker.Severity) string {
+	if severity == checker.Info {
+		return "note"
+	}
+	return severity.String()
+}
+
+// like report, but prints the diagnostics to @out as a SARIF document
+func reportSARIF(out io.Writer, analyses []analysis, strict bool, failOnWarning bool) bool {
+	encoded, err := json.MarshalIndent(makeSARIF(analyses, strict), "", "  ")
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return false
+	}
+	fmt.Fprintln(out, string(encoded))
+
+	for _, result := range analyses {
+		if result.err != nil || exitCode(result.diagnostics, strict, failOnWarning) != 0 {
+			return false
+		}
+	}
+	return true
+}
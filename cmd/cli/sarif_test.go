@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/akonwi/ard/checker"
+)
+
+func TestSARIF(t *testing.T) {
+	mismatch := checker.Diagnostic{Code: checker.TypeMismatch, Msg: "Expected a 'Num' and received 'Str'"}
+	mismatch.Range.StartPoint.Row, mismatch.Range.StartPoint.Column = 2, 4
+	mismatch.Range.EndPoint.Row, mismatch.Range.EndPoint.Column = 2, 9
+	shadowing := checker.Diagnostic{Code: checker.Shadowing, Msg: "'x' shadows an existing declaration", Severity: checker.Warning}
+
+	analyses := []analysis{
+		{path: "lib/util.kon", diagnostics: []checker.Diagnostic{shadowing, mismatch}},
+		{path: "main.kon", err: errors.New("Error parsing source code with tree-sitter")},
+	}
+
+	var out bytes.Buffer
+	if reportSARIF(&out, analyses, false, false) {
+		t.Errorf("Expected a failure with errors present")
+	}
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region *struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+							EndLine     int `json:"endLine"`
+							EndColumn   int `json:"endColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, out.String())
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("Expected a single SARIF 2.1.0 run, got %s", out.String())
+	}
+	run := log.Runs[0]
+	if rules := run.Tool.Driver.Rules; len(rules) != 2 || rules[0].ID != "K001" || rules[1].ID != "K031" {
+		t.Errorf("Expected rules K001 and K031, got %+v", rules)
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(run.Results))
+	}
+
+	warning := run.Results[0]
+	if warning.RuleID != "K031" || warning.Level != "warning" || warning.Message.Text != shadowing.Msg {
+		t.Errorf("Unexpected warning result: %+v", warning)
+	}
+
+	typeError := run.Results[1]
+	if typeError.RuleID != "K001" || typeError.Level != "error" {
+		t.Errorf("Unexpected error result: %+v", typeError)
+	}
+	location := typeError.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "lib/util.kon" {
+		t.Errorf("Expected the file's path as its uri, got %s", location.ArtifactLocation.URI)
+	}
+	if region := location.Region; region == nil || region.StartLine != 3 || region.StartColumn != 5 || region.EndLine != 3 || region.EndColumn != 10 {
+		t.Errorf("Expected a 1-based region of 3:5-3:10, got %+v", region)
+	}
+
+	failure := run.Results[2]
+	if failure.RuleID != "" || failure.Level != "error" || failure.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("Expected a file that failed to be reported without a rule or region, got %+v", failure)
+	}
+}
+
+func TestSARIFUnderStrict(t *testing.T) {
+	shadowing := checker.Diagnostic{Code: checker.Shadowing, Msg: "'x' shadows an existing declaration", Severity: checker.Warning}
+	log := makeSARIF([]analysis{{path: "main.kon", diagnostics: []checker.Diagnostic{shadowing}}}, true)
+	if level := log.Runs[0].Results[0].Level; level != "error" {
+		t.Errorf("Warnings are errors under --strict, got %s", level)
+	}
+}
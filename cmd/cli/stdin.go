@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/akonwi/ard/checker"
+	"github.com/akonwi/ard/javascript"
+)
+
+// what diagnostics call source read from stdin when it isn't given a name
+const stdinFilename = "<stdin>"
+
+// compiles the source read from @stdin as if it were the file at @filename, which is used in diagnostics
+// and to resolve its imports. since there is no file to write, the output goes to @stdout and diagnostics to @stderr.
+// the modules it imports are built to @outDir, where the output expects to find them.
+// with @check, nothing is output if there are errors, or warnings with @failOnWarning.
+// with @quiet, only errors are reported. returns the exit code
+func buildStdin(stdin io.Reader, filename string, strict bool, quiet bool, failOnWarning bool, check bool, options javascript.Options, emit string, outDir string, stdout, stderr io.Writer) int {
+	source, err := io.ReadAll(stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error reading stdin - %v\n", err)
+		return 1
+	}
+
+	// the modules it imports are read from disk like any other build
+	imports, err := sourceImports(filename, source)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	modules := []string{}
+	seen := map[string]bool{}
+	for _, imported := range imports {
+		resolved, err := resolveModules(imported, fileImports)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		for _, path := range resolved {
+			if !seen[path] {
+				seen[path] = true
+				modules = append(modules, path)
+			}
+		}
+	}
+
+	exports := map[string]checker.ModuleType{}
+	analyses := make([]analysis, 0, len(modules)+1)
+	for _, path := range modules {
+		program, diagnostics, err := analyze(path, &incrementalParser{}, exports)
+		analyses = append(analyses, analysis{path: path, program: program, diagnostics: diagnostics, err: err})
+	}
+	program, diagnostics, err := analyzeSource(filename, source, &incrementalParser{}, exports)
+	analyses = append(analyses, analysis{path: filename, program: program, diagnostics: diagnostics, err: err})
+
+	if !report(stderr, analyses, strict, quiet, failOnWarning) && check {
+		return 1
+	}
+	if err != nil {
+		return 1
+	}
+
+	root := sourceRoot(append(modules, filename))
+	moduleOptions := options
+	moduleOptions.Exports = true
+	for _, result := range analyses[:len(modules)] {
+		if result.err != nil {
+			return 1
+		}
+		start := time.Now()
+		output := generate(result.program, moduleOptions, emit)
+		trace.record(result.path, codegenPhase, start)
+		if !writeOutput(stderr, outDir, root, result.path, emit, output, true) {
+			return 1
+		}
+	}
+
+	start := time.Now()
+	output := generate(program, options, emit)
+	trace.record(filename, codegenPhase, start)
+	fmt.Fprint(stdout, output)
+	return 0
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// the compiler phases timed with --trace
+const (
+	parsePhase   = "parse"
+	checkPhase   = "check"
+	codegenPhase = "codegen"
+)
+
+// prints how long each phase of compiling a file took.
+// files are analyzed concurrently, so lines are written one at a time
+type tracer struct {
+	mu sync.Mutex
+	// nil unless --trace is given
+	out io.Writer
+}
+
+// set up by `build --trace`
+var trace = &tracer{}
+
+// prints the time since @start spent in @phase for the file at @path
+func (t *tracer) record(path string, phase string, start time.Time) {
+	elapsed := time.Since(start)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.out != nil {
+		fmt.Fprintf(t.out, "trace: %s %s %s\n", path, phase, elapsed)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akonwi/ard/javascript"
+)
+
+func TestTraceRecord(t *testing.T) {
+	var out bytes.Buffer
+	recorder := &tracer{}
+	recorder.record("main.kon", parsePhase, time.Now())
+	recorder.out = &out
+	recorder.record("main.kon", checkPhase, time.Now())
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected only phases recorded while tracing, got %q", out.String())
+	}
+	if !strings.HasPrefix(lines[0], "trace: main.kon check ") {
+		t.Errorf("Unexpected trace line: %q", lines[0])
+	}
+}
+
+func TestTraceBuild(t *testing.T) {
+	var out bytes.Buffer
+	trace.out = &out
+	defer func() { trace.out = nil }()
+
+	var stdout, stderr bytes.Buffer
+	if code := buildStdin(strings.NewReader(`let name = "Joe"`), "main.kon", false, false, false, true, javascript.DefaultOptions, "js", t.TempDir(), &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected a clean build to exit 0, got %d: %s", code, stderr.String())
+	}
+	for _, phase := range []string{parsePhase, checkPhase, codegenPhase} {
+		if !strings.Contains(out.String(), "trace: main.kon "+phase+" ") {
+			t.Errorf("Expected a %s line in the trace, got %q", phase, out.String())
+		}
+	}
+}
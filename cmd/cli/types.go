@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/akonwi/ard/ast"
+	"github.com/akonwi/ard/checker"
+)
+
+// prints the inferred type of each top-level binding of every analysis to @out, file by file.
+// files that failed to analyze have nothing to show
+func showTypes(out io.Writer, analyses []analysis) {
+	for _, result := range analyses {
+		if result.err != nil {
+			continue
+		}
+		fmt.Fprintln(out, result.path)
+		for _, binding := range topLevelBindings(result.program) {
+			// a binding whose type couldn't be inferred
+			spelling := "?"
+			if binding.t != nil {
+				spelling = binding.t.String()
+			}
+			fmt.Fprintf(out, "  %s: %s\n", binding.name, spelling)
+		}
+	}
+}
+
+type binding struct {
+	name string
+	t    checker.Type
+}
+
+// the variables and functions declared at the top level of @program, in order
+func topLevelBindings(program ast.Program) []binding {
+	bindings := []binding{}
+	for _, statement := range program.Statements {
+		switch statement := statement.(type) {
+		case ast.VariableDeclaration:
+			bindings = append(bindings, binding{statement.Name, statement.Type})
+		case ast.FunctionDeclaration:
+			parameters := make([]checker.Type, len(statement.Parameters))
+			for i, param := range statement.Parameters {
+				parameters[i] = param.Type
+			}
+			fnType := checker.FunctionType{Name: statement.Name, Parameters: parameters, ReturnType: statement.ReturnType}
+			bindings = append(bindings, binding{statement.Name, fnType})
+		case ast.StructDestructuring:
+			if structType, ok := statement.Value.GetType().(checker.StructType); ok {
+				for _, name := range statement.Names {
+					bindings = append(bindings, binding{name, structType.Fields[name]})
+				}
+			}
+		case ast.ListDestructuring:
+			for i, name := range statement.Names {
+				bindings = append(bindings, binding{name, destructuredItemType(statement.Value.GetType(), i)})
+			}
+		}
+	}
+	return bindings
+}
+
+// the type of the item at @index of a list or tuple
+func destructuredItemType(t checker.Type, index int) checker.Type {
+	switch t := t.(type) {
+	case checker.ListType:
+		return t.ItemType
+	case checker.TupleType:
+		if index < len(t.Items) {
+			return t.Items[index]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/akonwi/ard/ast"
+	"github.com/akonwi/ard/checker"
+)
+
+func TestShowTypes(t *testing.T) {
+	add := ast.FunctionDeclaration{
+		Name: "add",
+		Parameters: []ast.Parameter{
+			{Name: "x", Type: checker.NumType},
+			{Name: "y", Type: checker.NumType},
+		},
+		ReturnType: checker.NumType,
+	}
+	program := ast.Program{Statements: []ast.Statement{
+		ast.Comment{Value: "// a greeting"},
+		ast.VariableDeclaration{Name: "name", Value: ast.StrLiteral{Value: `"Joe"`}, Type: checker.StrType},
+		add,
+		ast.VariableDeclaration{Name: "scores", Type: checker.MakeList(checker.NumType)},
+		ast.ListDestructuring{Names: []string{"first", "second"}, Value: ast.Identifier{Name: "scores", Type: checker.MakeList(checker.NumType)}},
+	}}
+	analyses := []analysis{
+		{path: "broken.kon", err: fmt.Errorf("Error parsing source code with tree-sitter")},
+		{path: "main.kon", program: program},
+	}
+
+	var out bytes.Buffer
+	showTypes(&out, analyses)
+	want := `main.kon
+  name: Str
+  add: (Num, Num) Num
+  scores: [Num]
+  first: Num
+  second: Num
+`
+	if got := out.String(); got != want {
+		t.Errorf("Expected:\n%s\nGot:\n%s", want, got)
+	}
+}
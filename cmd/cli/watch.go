@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	pollInterval = 250 * time.Millisecond
+	// rapid saves within this window only trigger one rebuild
+	debounceInterval = 100 * time.Millisecond
+)
+
+// tracks a file's modification time between polls
+type fileWatcher struct {
+	path    string
+	exists  bool
+	modTime time.Time
+}
+
+func newFileWatcher(path string) *fileWatcher {
+	w := &fileWatcher{path: path}
+	w.changed()
+	return w
+}
+
+// reports whether the file has been modified or recreated since the last call.
+// a deleted file is not a change, but its reappearance is
+func (w *fileWatcher) changed() bool {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		w.exists = false
+		return false
+	}
+
+	modTime := info.ModTime()
+	changed := !w.exists || !modTime.Equal(w.modTime)
+	w.exists = true
+	w.modTime = modTime
+	return changed
+}
+
+// tracks the files of a build: the entry and every module it imports
+type moduleWatcher struct {
+	entry string
+	// resolves the modules that @entry depends on, including itself
+	modules func(entry string) ([]string, error)
+	files   map[string]*fileWatcher
+}
+
+func newModuleWatcher(entry string, modules func(entry string) ([]string, error)) *moduleWatcher {
+	m := &moduleWatcher{entry: entry, modules: modules, files: map[string]*fileWatcher{}}
+	m.refresh()
+	return m
+}
+
+// resolves the modules again, since a rebuild can add or remove imports.
+// files that are still part of the build keep their state.
+// while the imports can't be resolved, e.g. because of a cycle, the files from before are kept
+func (m *moduleWatcher) refresh() {
+	paths, err := m.modules(m.entry)
+	if err != nil {
+		paths = []string{m.entry}
+		for path := range m.files {
+			paths = append(paths, path)
+		}
+	}
+
+	files := make(map[string]*fileWatcher, len(paths))
+	for _, path := range paths {
+		if w, ok := m.files[path]; ok {
+			files[path] = w
+		} else {
+			files[path] = newFileWatcher(path)
+		}
+	}
+	m.files = files
+}
+
+// reports whether any of the files has changed since the last call
+func (m *moduleWatcher) changed() bool {
+	changed := false
+	for _, w := range m.files {
+		// every file is checked so that each change is only reported once
+		if w.changed() {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// waits until changes to the files settle down
+func (m *moduleWatcher) settle() {
+	for {
+		time.Sleep(debounceInterval)
+		if !m.changed() {
+			return
+		}
+	}
+}
+
+// blocks forever, calling @onChange whenever the file at @path or one of the modules it imports changes
+func watch(path string, onChange func()) {
+	m := newModuleWatcher(path, func(entry string) ([]string, error) {
+		return resolveModules(entry, fileImports)
+	})
+	fmt.Printf("Watching %s and its imports for changes\n", path)
+	for {
+		time.Sleep(pollInterval)
+		if m.changed() {
+			m.settle()
+			onChange()
+			m.refresh()
+		}
+	}
+}
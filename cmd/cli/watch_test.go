@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.kon")
+	if err := os.WriteFile(path, []byte(`let x = 1`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := newFileWatcher(path)
+	if w.changed() {
+		t.Errorf("An untouched file has not changed")
+	}
+
+	later := time.Now().Add(time.Second)
+	if err := os.WriteFile(path, []byte(`let x = 2`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, later, later)
+	if !w.changed() {
+		t.Errorf("Expected a modified file to have changed")
+	}
+	if w.changed() {
+		t.Errorf("A change is only reported once")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if w.changed() {
+		t.Errorf("A deleted file is not a change")
+	}
+
+	if err := os.WriteFile(path, []byte(`let x = 3`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !w.changed() {
+		t.Errorf("Expected a recreated file to have changed")
+	}
+}
+
+func TestModuleWatcher(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, source string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	touch := func(path string, offset time.Duration) {
+		later := time.Now().Add(offset)
+		os.Chtimes(path, later, later)
+	}
+	main := write("main.kon", `use ./util`)
+	util := write("util.kon", `fn greet() {}`)
+	extra := write("extra.kon", `fn help() {}`)
+
+	modules := map[string][]string{main: {util, main}}
+	resolve := func(entry string) ([]string, error) {
+		if paths, ok := modules[entry]; ok {
+			return paths, nil
+		}
+		return nil, fmt.Errorf("Import cycle detected")
+	}
+
+	m := newModuleWatcher(main, resolve)
+	if m.changed() {
+		t.Errorf("Untouched files have not changed")
+	}
+
+	touch(util, time.Second)
+	if !m.changed() {
+		t.Errorf("Expected a change to an imported module to be seen")
+	}
+	if m.changed() {
+		t.Errorf("A change is only reported once")
+	}
+
+	touch(extra, 2*time.Second)
+	if m.changed() {
+		t.Errorf("A file that isn't imported is not watched")
+	}
+
+	// a rebuild that adds an import starts watching it
+	modules[main] = []string{util, extra, main}
+	m.refresh()
+	touch(extra, 3*time.Second)
+	if !m.changed() {
+		t.Errorf("Expected a newly imported module to be watched after a refresh")
+	}
+
+	// while the imports can't be resolved, the files from before are still watched
+	delete(modules, main)
+	m.refresh()
+	touch(util, 4*time.Second)
+	if !m.changed() {
+		t.Errorf("Expected the previous modules to be watched when resolving fails")
+	}
+}
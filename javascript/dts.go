@@ -0,0 +1,125 @@
+package javascript
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/akonwi/ard/ast"
+	"github.com/akonwi/ard/checker"
+)
+
+// GenerateDTS renders a TypeScript declaration file for the top-level functions, structs and enums of a program.
+// Kon has no visibility modifiers, so every top-level declaration is part of the generated script's surface.
+func GenerateDTS(program ast.Program, options Options) string {
+	g := jsGenerator{indent: options.Indent, renamed: make(map[string]string), helpers: make(map[string]bool)}
+	if g.indent == "" {
+		g.indent = DefaultOptions.Indent
+	}
+
+	declarations := make([]string, 0)
+	for _, statement := range program.Statements {
+		if declaration := g.generateDeclaration(statement); !declaration.IsEmpty() {
+			declarations = append(declarations, strings.TrimRight(declaration.String(), "\n"))
+		}
+	}
+	if len(declarations) == 0 {
+		return ""
+	}
+	return strings.Join(declarations, "\n\n") + "\n"
+}
+
+func (g jsGenerator) generateDeclaration(statement ast.Statement) ast.Document {
+	switch statement := statement.(type) {
+	case ast.FunctionDeclaration:
+		params := make([]string, len(statement.Parameters))
+		for i, param := range statement.Parameters {
+			params[i] = fmt.Sprintf("%s: %s", g.name(param.Name), g.tsType(param.Type))
+		}
+		return g.makeDoc(fmt.Sprintf(
+			"declare function %s(%s): %s;",
+			g.name(statement.Name),
+			strings.Join(params, ", "),
+			g.tsType(statement.ReturnType),
+		))
+	case ast.StructDefinition:
+		doc := g.makeDoc(fmt.Sprintf("interface %s {", g.name(statement.Type.Name)))
+		doc.Indent()
+		for _, name := range sortedKeys(statement.Type.Fields) {
+			doc.Line(fmt.Sprintf("%s: %s;", name, g.tsType(statement.Type.Fields[name])))
+		}
+		doc.Dedent()
+		doc.Line("}")
+		return doc
+	case ast.EnumDefinition:
+		// the generated object maps each variant to its index, which is what a numeric enum looks like at runtime
+		doc := g.makeDoc(fmt.Sprintf("declare enum %s {", g.name(statement.Type.Name)))
+		doc.Indent()
+		for index, name := range statement.Type.Variants {
+			doc.Line(fmt.Sprintf("%s = %d,", name, index))
+		}
+		doc.Dedent()
+		doc.Line("}")
+		return doc
+	default:
+		return g.makeDoc("")
+	}
+}
+
+// the TypeScript spelling of a Kon type, matching how values are represented at runtime
+func (g jsGenerator) tsType(t checker.Type) string {
+	if t == nil {
+		return "unknown"
+	}
+	switch t.Kind() {
+	case checker.KindNum:
+		return "number"
+	case checker.KindStr:
+		return "string"
+	case checker.KindBool:
+		return "boolean"
+	case checker.KindVoid:
+		return "void"
+	case checker.KindNever:
+		return "never"
+	}
+
+	switch t := t.(type) {
+	case checker.ListType:
+		return fmt.Sprintf("Array<%s>", g.tsType(t.ItemType))
+	case checker.MapType:
+		return fmt.Sprintf("Map<%s, %s>", g.tsType(t.KeyType), g.tsType(t.ValueType))
+	case checker.TupleType:
+		items := make([]string, len(t.Items))
+		for i, item := range t.Items {
+			items[i] = g.tsType(item)
+		}
+		return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+	case checker.OptionType:
+		return fmt.Sprintf("%s | null", g.tsType(t.Inner))
+	case checker.ResultType:
+		// failures are thrown, so a result is its ok value
+		return g.tsType(t.OkType)
+	case checker.StructType:
+		if t.IsAnonymous() {
+			fields := make([]string, 0, len(t.Fields))
+			for _, name := range sortedKeys(t.Fields) {
+				fields = append(fields, fmt.Sprintf("%s: %s", name, g.tsType(t.Fields[name])))
+			}
+			return fmt.Sprintf("{ %s }", strings.Join(fields, "; "))
+		}
+		return g.name(t.Name)
+	case checker.EnumType:
+		if t.HasPayloads() {
+			return fmt.Sprintf("{ index: %s; values: unknown[] }", g.name(t.Name))
+		}
+		return g.name(t.Name)
+	case checker.FunctionType:
+		params := make([]string, len(t.Parameters))
+		for i, param := range t.Parameters {
+			params[i] = fmt.Sprintf("arg%d: %s", i, g.tsType(param))
+		}
+		return fmt.Sprintf("(%s) => %s", strings.Join(params, ", "), g.tsType(t.ReturnType))
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,191 @@
+package javascript
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/akonwi/ard/ast"
+	"github.com/akonwi/ard/checker"
+)
+
+// copies the fields of a struct onto a new object, for runtimes without object spread or Object.assign
+const assignHelper = "$assign"
+
+// the generator for the body of a loop that declares @names.
+// under ES5 every binding is a `var` shared by all iterations, so the loop's bindings,
+// including the ones declared in its body, are remembered for closures made inside it to copy
+func (g jsGenerator) enterLoop(body []ast.Statement, names ...string) jsGenerator {
+	if g.target != ES5 {
+		return g
+	}
+	bindings := slices.Clone(g.loopBindings)
+	for _, name := range append(names, declaredNames(body)...) {
+		if name = g.name(name); !slices.Contains(bindings, name) {
+			bindings = append(bindings, name)
+		}
+	}
+	g.loopBindings = bindings
+	g.loopDepth++
+	return g
+}
+
+// the names declared by @body, including in the branches of its if statements
+func declaredNames(body []ast.Statement) []string {
+	names := []string{}
+	for _, statement := range body {
+		switch statement := statement.(type) {
+		case ast.VariableDeclaration:
+			names = append(names, statement.Name)
+		case ast.ListDestructuring:
+			names = append(names, statement.Names...)
+		case ast.StructDestructuring:
+			names = append(names, statement.Names...)
+		case ast.IfStatement:
+			names = append(names, declaredNames(statement.Body)...)
+			if statement.Else != nil {
+				names = append(names, declaredNames([]ast.Statement{statement.Else})...)
+			}
+		}
+	}
+	return names
+}
+
+// wraps @closure in a function that is called with the current values of the enclosing loops' bindings,
+// so the closure keeps this iteration's values the way it would with block scoped bindings.
+// the closure gets a copy, so it doesn't see the bindings change after it's made
+func (g jsGenerator) copyLoopBindings(closure string) string {
+	names := strings.Join(g.loopBindings, ", ")
+	doc := g.makeDoc("(" + g.function(names))
+	doc.Nest(g.makeDoc("return " + closure))
+	doc.Line(fmt.Sprintf("})(%s)", names))
+	return doc.String()
+}
+
+// a for...of loop over a list or string, walked by index instead.
+// an iterable that isn't a variable is only evaluated once, before the loop
+func (g jsGenerator) indexedForOf(loop ast.ForLoop) ast.Document {
+	doc := g.makeDoc("")
+	counter := fmt.Sprintf("$i%d", g.loopDepth)
+	iterable := g.toJSExpression(loop.Iterable)
+	if _, ok := loop.Iterable.(ast.Identifier); !ok {
+		items := fmt.Sprintf("$items%d", g.loopDepth)
+		doc.Line(fmt.Sprintf("var %s = %s", items, iterable))
+		iterable = items
+	}
+	doc.Line(g.labeled(loop.Label, fmt.Sprintf("for (var %s = 0; %s < %s.length; %s++) {", counter, counter, iterable, counter)))
+	doc.Indent()
+	doc.Line(fmt.Sprintf("var %s = %s[%s]", g.name(loop.Cursor.Name), iterable, counter))
+	doc.Dedent()
+	return doc
+}
+
+// declares each of @names as the item of @source at its position
+func (g jsGenerator) destructureList(names []string, source string) ast.Document {
+	doc := g.makeDoc("")
+	for i, name := range names {
+		doc.Line(fmt.Sprintf("var %s = %s[%d]", g.name(name), source, i))
+	}
+	return doc
+}
+
+// a destructuring declaration, which reads each value from @value.
+// a value that isn't a variable is only evaluated once
+func (g jsGenerator) destructure(value ast.Expression, read func(source string) ast.Document) ast.Document {
+	source := g.toJSExpression(value)
+	if _, ok := value.(ast.Identifier); ok {
+		return read(source)
+	}
+	doc := g.makeDoc(fmt.Sprintf("var $destructured = %s", source))
+	doc.Append(read("$destructured"))
+	return doc
+}
+
+// the chunks of an interpolated string added together, with the values that aren't strings converted
+func (g jsGenerator) concatenation(str ast.InterpolatedStr) string {
+	parts := make([]string, len(str.Chunks))
+	for i, chunk := range str.Chunks {
+		switch {
+		case isStrLiteral(chunk):
+			// template literals can span lines, string literals can't
+			parts[i] = `"` + strings.ReplaceAll(chunk.(ast.StrLiteral).Value, "\n", `\n`) + `"`
+		case chunk.GetType() == checker.StrType:
+			parts[i] = g.toJSOperand(chunk, ast.Plus, i > 0)
+		default:
+			parts[i] = fmt.Sprintf("String(%s)", g.toJSExpression(chunk))
+		}
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	// the whole string is a single operand, like the template literal it replaces
+	return "(" + strings.Join(parts, " + ") + ")"
+}
+
+func isStrLiteral(expr ast.Expression) bool {
+	_, ok := expr.(ast.StrLiteral)
+	return ok
+}
+
+// a list literal with spreads, built by concatenating the spread lists with runs of the other items
+func (g jsGenerator) concatList(items []ast.Expression) string {
+	lists := []string{}
+	run := []string{}
+	for _, item := range items {
+		spread, ok := item.(ast.Spread)
+		if !ok {
+			run = append(run, g.toJSExpression(item))
+			continue
+		}
+		if len(run) > 0 {
+			lists = append(lists, fmt.Sprintf("[%s]", strings.Join(run, ", ")))
+			run = []string{}
+		}
+		lists = append(lists, g.toJSExpression(spread.Expr))
+	}
+	if len(run) > 0 {
+		lists = append(lists, fmt.Sprintf("[%s]", strings.Join(run, ", ")))
+	}
+	return fmt.Sprintf("[].concat(%s)", strings.Join(lists, ", "))
+}
+
+// `target?.member`, as a conditional on whether the target is none.
+// a target that isn't a variable is only evaluated once, as the parameter of a function
+func (g jsGenerator) optionalMember(expr ast.OptionalMemberAccess) string {
+	target := expr.Target
+	identifier, ok := target.(ast.Identifier)
+	if !ok {
+		identifier = ast.Identifier{Name: "$target", Type: target.GetType()}
+	}
+	access := g.memberAccess(getJsMemberAccess(ast.MemberAccess{
+		Target:     identifier,
+		AccessType: ast.Instance,
+		Member:     expr.Member,
+	}), ".")
+	conditional := fmt.Sprintf("%s == null ? null : %s", g.name(identifier.Name), access)
+	if ok {
+		return "(" + conditional + ")"
+	}
+	return fmt.Sprintf("(%s)(%s)", g.lambda(identifier.Name, conditional), g.toJSExpression(target))
+}
+
+// the declaration of the helper that copies struct fields
+func (g jsGenerator) assignHelperDeclaration() string {
+	doc := g.makeDoc(fmt.Sprintf("function %s(base, fields) {", assignHelper))
+	doc.Indent()
+	doc.Line("var object = {}")
+	doc.Line("for (var key in base) {")
+	doc.Indent()
+	doc.Line("object[key] = base[key]")
+	doc.Dedent()
+	doc.Line("}")
+	doc.Line("for (var key in fields) {")
+	doc.Indent()
+	doc.Line("object[key] = fields[key]")
+	doc.Dedent()
+	doc.Line("}")
+	doc.Line("return object")
+	doc.Dedent()
+	doc.Line("}")
+	return strings.TrimRight(doc.String(), "\n")
+}
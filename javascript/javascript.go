@@ -2,7 +2,9 @@ package javascript
 
 import (
 	"fmt"
+	"io"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/akonwi/ard/ast"
@@ -50,41 +52,246 @@ func resolveOperator(operator ast.Operator) string {
 	}
 }
 
-func generateStatement(statement ast.Statement, _isReturn ...bool) ast.Document {
+// JS operator precedence, higher binds tighter
+// https://developer.mozilla.org/en-US/docs/Web/JavaScript/Reference/Operators/Operator_precedence
+func precedence(operator ast.Operator) int {
+	switch operator {
+	case ast.Or:
+		return 3
+	case ast.And:
+		return 4
+	case ast.Equal, ast.NotEqual:
+		return 8
+	case ast.LessThan, ast.LessThanOrEqual, ast.GreaterThan, ast.GreaterThanOrEqual:
+		return 9
+	case ast.Plus, ast.Minus:
+		return 11
+	case ast.Multiply, ast.Divide, ast.Modulo:
+		return 12
+	default:
+		return 0
+	}
+}
+
+// strings and lists have a `contains` method
+func isSearchable(t checker.Type) bool {
+	_, isList := t.(checker.ListType)
+	return isList || t == checker.StrType
+}
+
+func isSpread(expr ast.Expression) bool {
+	_, ok := expr.(ast.Spread)
+	return ok
+}
+
+// structs, lists, tuples, maps and enums with payloads are objects at runtime, which `===` would only compare by identity.
+// options are the value or null, so they're objects when the value is
+func isObject(t checker.Type) bool {
+	switch t := t.(type) {
+	case checker.StructType, checker.ListType, checker.TupleType, checker.MapType:
+		return true
+	case checker.OptionType:
+		return isObject(t.Inner)
+	case checker.EnumType:
+		return t.HasPayloads()
+	default:
+		return false
+	}
+}
+
+// the JS comparing @lhs and @rhs, which are both of type @t, by value.
+// @depth keeps the parameters of nested callbacks apart.
+// a struct or enum that contains itself can't be spelled out, so the values inside it are compared by a helper
+func (g jsGenerator) jsEquals(t checker.Type, lhs, rhs string, depth int) string {
+	if named, ok := t.(checker.Symbol); ok && isObject(t) {
+		if slices.Contains(g.comparing, named.GetName()) {
+			g.helpers[equalsHelper] = true
+			return fmt.Sprintf("%s(%s, %s)", equalsHelper, lhs, rhs)
+		}
+		g.comparing = append(slices.Clone(g.comparing), named.GetName())
+	}
+	switch t := t.(type) {
+	case checker.ListType:
+		item, index := fmt.Sprintf("item%d", depth), fmt.Sprintf("i%d", depth)
+		return fmt.Sprintf(
+			"%s.length === %s.length && %s.every(%s)",
+			lhs, rhs, lhs,
+			g.lambda(item+", "+index, g.jsEquals(t.ItemType, item, fmt.Sprintf("%s[%s]", rhs, index), depth+1)),
+		)
+	case checker.StructType:
+		if len(t.Fields) == 0 {
+			return "true"
+		}
+		fields := sortedKeys(t.Fields)
+		comparisons := make([]string, len(fields))
+		for i, name := range fields {
+			comparisons[i] = g.jsEquals(t.Fields[name], lhs+"."+name, rhs+"."+name, depth)
+		}
+		return strings.Join(comparisons, " && ")
+	case checker.TupleType:
+		if len(t.Items) == 0 {
+			return "true"
+		}
+		comparisons := make([]string, len(t.Items))
+		for i, itemType := range t.Items {
+			comparisons[i] = g.jsEquals(itemType, fmt.Sprintf("%s[%d]", lhs, i), fmt.Sprintf("%s[%d]", rhs, i), depth)
+		}
+		return strings.Join(comparisons, " && ")
+	case checker.OptionType:
+		if !isObject(t.Inner) {
+			break
+		}
+		// none is null, which is only equal to another none
+		return fmt.Sprintf("(%s === null || %s === null ? %s === %s : %s)", lhs, rhs, lhs, rhs, g.jsEquals(t.Inner, lhs, rhs, depth))
+	case checker.MapType:
+		key := fmt.Sprintf("key%d", depth)
+		return fmt.Sprintf(
+			"%s.size === %s.size && Array.from(%s.keys()).every(%s)",
+			lhs, rhs, lhs,
+			g.lambda(key, fmt.Sprintf("%s.has(%s) && %s", rhs, key, g.jsEquals(t.ValueType, lhs+".get("+key+")", rhs+".get("+key+")", depth+1))),
+		)
+	case checker.EnumType:
+		if !t.HasPayloads() {
+			break
+		}
+		// variants carry different types, so the values are compared as the types of the variant both sides are
+		variants := []string{}
+		for _, variant := range t.Variants {
+			payload := t.Payloads[variant]
+			if len(payload) == 0 {
+				continue
+			}
+			values := make([]string, len(payload))
+			for i, valueType := range payload {
+				values[i] = g.jsEquals(valueType, fmt.Sprintf("%s.values[%d]", lhs, i), fmt.Sprintf("%s.values[%d]", rhs, i), depth)
+			}
+			variants = append(variants, fmt.Sprintf("%s.index === %s.%s ? %s", lhs, g.name(t.Name), variant, strings.Join(values, " && ")))
+		}
+		return fmt.Sprintf("%s.index === %s.index && (%s : true)", lhs, rhs, strings.Join(variants, " : "))
+	}
+	return lhs + " === " + rhs
+}
+
+// wraps an operand of a binary expression in parens only when JS would otherwise group it differently
+func (g jsGenerator) toJSOperand(operand ast.Expression, parent ast.Operator, isRight bool) string {
+	js := g.toJSExpression(operand)
+	if isConditional(operand) {
+		return "(" + js + ")"
+	}
+	binary, ok := operand.(ast.BinaryExpression)
+	if !ok || binary.HasPrecedence {
+		return js
+	}
+	inner, outer := precedence(binary.Operator), precedence(parent)
+	if inner < outer || (isRight && inner == outer) {
+		return "(" + js + ")"
+	}
+	return js
+}
+
+// a conditional binds more loosely than any operator, so it's wrapped in parentheses wherever it's part of a larger expression
+func isConditional(expr ast.Expression) bool {
+	_, ok := expr.(ast.ConditionalExpression)
+	return ok
+}
+
+// the path of the built module imported as `use @importPath`, relative to the importer
+func modulePath(importPath string) string {
+	if strings.HasPrefix(importPath, "../") {
+		return importPath + ".js"
+	}
+	return "./" + importPath + ".js"
+}
+
+func (g jsGenerator) generateStatement(statement ast.Statement, _isReturn ...bool) ast.Document {
 	isReturn := len(_isReturn) > 0 && _isReturn[0]
 	switch statement.(type) {
-	case ast.StructDefinition: // skipped
+	case ast.StructDefinition, ast.TypeAlias: // skipped
+	case ast.Import:
+		imported := statement.(ast.Import)
+		// every module is built at the same path relative to the importer as its source
+		if g.target == ES5 {
+			return g.makeDoc(fmt.Sprintf(`var %s = require("%s")`, g.name(imported.Name), modulePath(imported.Path)))
+		}
+		return g.makeDoc(fmt.Sprintf(`import * as %s from "%s"`, g.name(imported.Name), modulePath(imported.Path)))
 	case ast.VariableDeclaration:
 		decl := statement.(ast.VariableDeclaration)
-		binding := "const"
-		if decl.Mutable {
-			binding = "let"
+		binding := g.binding(decl.Mutable)
+		return g.makeDoc(fmt.Sprintf("%s %s = %s", binding, g.name(decl.Name), g.toJSExpression(decl.Value)))
+	case ast.StructDestructuring:
+		decl := statement.(ast.StructDestructuring)
+		if g.target == ES5 {
+			return g.destructure(decl.Value, func(source string) ast.Document {
+				doc := g.makeDoc("")
+				for _, field := range decl.Names {
+					doc.Line(fmt.Sprintf("var %s = %s.%s", g.name(field), source, field))
+				}
+				return doc
+			})
 		}
-		return ast.MakeDoc(fmt.Sprintf("%s %s = %s", binding, decl.Name, toJSExpression(decl.Value)))
+		binding := g.binding(decl.Mutable)
+		names := make([]string, len(decl.Names))
+		for i, field := range decl.Names {
+			names[i] = field
+			// the field keeps its name, only the binding is renamed
+			if name := g.name(field); name != field {
+				names[i] = fmt.Sprintf("%s: %s", field, name)
+			}
+		}
+		return g.makeDoc(fmt.Sprintf("%s { %s } = %s", binding, strings.Join(names, ", "), g.toJSExpression(decl.Value)))
+	case ast.ListDestructuring:
+		decl := statement.(ast.ListDestructuring)
+		if g.target == ES5 {
+			return g.destructure(decl.Value, func(source string) ast.Document {
+				return g.destructureList(decl.Names, source)
+			})
+		}
+		binding := g.binding(decl.Mutable)
+		return g.makeDoc(fmt.Sprintf("%s [%s] = %s", binding, strings.Join(g.names(decl.Names), ", "), g.toJSExpression(decl.Value)))
+	case ast.MemberAssignment:
+		assignment := statement.(ast.MemberAssignment)
+		return g.makeDoc(fmt.Sprintf(
+			"%s.%s %s %s",
+			g.toJSExpression(assignment.Target),
+			assignment.Member,
+			resolveOperator(assignment.Operator),
+			g.toJSExpression(assignment.Value),
+		))
+	case ast.IndexAssignment:
+		assignment := statement.(ast.IndexAssignment)
+		return g.makeDoc(fmt.Sprintf(
+			"%s[%s] %s %s",
+			g.toJSExpression(assignment.Target),
+			g.toJSExpression(assignment.Index),
+			resolveOperator(assignment.Operator),
+			g.toJSExpression(assignment.Value),
+		))
 	case ast.VariableAssignment:
 		assignment := statement.(ast.VariableAssignment)
-		return ast.MakeDoc(fmt.Sprintf(
+		return g.makeDoc(fmt.Sprintf(
 			"%s %s %s",
-			assignment.Name,
+			g.name(assignment.Name),
 			resolveOperator(assignment.Operator),
-			toJSExpression(assignment.Value),
+			g.toJSExpression(assignment.Value),
 		))
 	case ast.FunctionDeclaration:
 		decl := statement.(ast.FunctionDeclaration)
 		params := make([]string, len(decl.Parameters))
 		for i, param := range decl.Parameters {
-			params[i] = param.Name
+			params[i] = g.name(param.Name)
 		}
-		doc := ast.MakeDoc(fmt.Sprintf("function %s(%s) {", decl.Name, strings.Join(params, ", ")))
-		for i, statement := range decl.Body {
-			doc.Nest(generateStatement(statement, i == len(decl.Body)-1))
+		doc := g.makeDoc("")
+		if g.jsdoc {
+			doc.Line(g.jsDocComment(decl))
 		}
+		doc.Line(fmt.Sprintf("function %s(%s) {", g.name(decl.Name), strings.Join(params, ", ")))
+		g.inFunction().nestFunctionBody(&doc, decl.Body)
 		doc.Line("}")
 		return doc
 	case ast.EnumDefinition:
 		{
 			enum := statement.(ast.EnumDefinition)
-			doc := ast.MakeDoc(fmt.Sprintf("const %s = Object.freeze({", enum.Type.Name))
+			doc := g.makeDoc(fmt.Sprintf("%s %s = Object.freeze({", g.binding(false), g.name(enum.Type.Name)))
 			doc.Indent()
 			for index, name := range enum.Type.Variants {
 				content := fmt.Sprintf("%s: %d", name, index)
@@ -100,27 +307,82 @@ func generateStatement(statement ast.Statement, _isReturn ...bool) ast.Document
 	case ast.WhileLoop:
 		{
 			loop := statement.(ast.WhileLoop)
-			doc := ast.MakeDoc(fmt.Sprintf("while (%s) {", toJSExpression(loop.Condition)))
+			doc := g.makeDoc(g.labeled(loop.Label, fmt.Sprintf("while (%s) {", g.toJSExpression(loop.Condition))))
+			body := g.enterLoop(loop.Body)
 			for _, statement := range loop.Body {
-				doc.Nest(generateStatement(statement))
+				doc.Nest(body.generateStatement(statement))
+			}
+			doc.Line("}")
+			return doc
+		}
+	case ast.Loop:
+		{
+			loop := statement.(ast.Loop)
+			doc := g.makeDoc(g.labeled(loop.Label, "while (true) {"))
+			body := g.enterLoop(loop.Body)
+			for _, statement := range loop.Body {
+				doc.Nest(body.generateStatement(statement))
 			}
 			doc.Line("}")
 			return doc
 		}
 	case ast.ForLoop:
 		{
-			doc := ast.MakeDoc("")
+			doc := g.makeDoc("")
 			loop := statement.(ast.ForLoop)
+			// cursors are only declared with `const` or with `let` in the loop header, never `var`,
+			// so every iteration gets its own binding and closures capture that iteration's value.
+			// ES5 only has `var`, so closures made in the body are given a copy of the loop's bindings instead
+			cursor := g.name(loop.Cursor.Name)
+			counter := g.binding(true)
+			names := []string{loop.Cursor.Name}
+			if loop.Index != nil {
+				names = append(names, loop.Index.Name)
+			}
+			body := g.enterLoop(loop.Body, names...)
 			if rangeExpr, ok := loop.Iterable.(ast.RangeExpression); ok {
-				doc.Line(
+				start, end := g.toJSExpression(rangeExpr.Start), g.toJSExpression(rangeExpr.End)
+				if descending, known := rangeDirection(rangeExpr); known {
+					comparison, step := "<", "++"
+					if descending {
+						comparison, step = ">", "--"
+					}
+					doc.Line(g.labeled(loop.Label,
+						fmt.Sprintf("for (%s %s = %s; %s %s %s; %s%s) {", counter, cursor, start, cursor, comparison, end, cursor, step)))
+					goto print_body_and_close
+				}
+				// the direction of a range with dynamic bounds is only known once they are evaluated,
+				// and the end is evaluated once, alongside the start
+				init := fmt.Sprintf("%s = %s", cursor, start)
+				if !isPlainValue(rangeExpr.End) {
+					bound := fmt.Sprintf("$end%d", g.loopDepth)
+					init += fmt.Sprintf(", %s = %s", bound, end)
+					end = bound
+				}
+				step := fmt.Sprintf("$step%d", g.loopDepth)
+				init += fmt.Sprintf(", %s = %s > %s ? -1 : 1", step, cursor, end)
+				doc.Line(g.labeled(loop.Label,
 					fmt.Sprintf(
-						"for (let %s = %s; %s < %s; %s++) {",
-						loop.Cursor.Name,
-						toJSExpression(rangeExpr.Start),
-						loop.Cursor.Name,
-						toJSExpression(rangeExpr.End),
-						loop.Cursor.Name,
-					))
+						"for (%s %s; %s > 0 ? %s < %s : %s > %s; %s += %s) {",
+						counter, init, step, cursor, end, cursor, end, cursor, step,
+					)))
+				goto print_body_and_close
+			}
+
+			// strings and lists are indexed the same way.
+			// an iterable that isn't a variable is only evaluated once, alongside the index
+			if loop.Index != nil {
+				index, iterable := g.name(loop.Index.Name), g.toJSExpression(loop.Iterable)
+				init := fmt.Sprintf("%s = 0", index)
+				if _, ok := loop.Iterable.(ast.Identifier); !ok {
+					items := fmt.Sprintf("$items%d", g.loopDepth)
+					init += fmt.Sprintf(", %s = %s", items, iterable)
+					iterable = items
+				}
+				doc.Line(g.labeled(loop.Label, fmt.Sprintf("for (%s %s; %s < %s.length; %s++) {", counter, init, index, iterable, index)))
+				doc.Indent()
+				doc.Line(fmt.Sprintf("%s %s = %s[%s]", g.binding(false), cursor, iterable, index))
+				doc.Dedent()
 				goto print_body_and_close
 			}
 
@@ -129,24 +391,31 @@ func generateStatement(statement ast.Statement, _isReturn ...bool) ast.Document
 					panic("Cannot iterate over a boolean")
 				}
 
-				if primitive == checker.StrType {
-					doc.Line(fmt.Sprintf("for (const %s of %s) {", loop.Cursor.Name, toJSExpression(loop.Iterable)))
+				if primitive == checker.StrType && g.target == ES5 {
+					doc.Append(g.indexedForOf(loop))
+				} else if primitive == checker.StrType {
+					doc.Line(g.labeled(loop.Label, fmt.Sprintf("for (%s %s of %s) {", g.binding(false), cursor, g.toJSExpression(loop.Iterable))))
 				} else {
-					doc.Line(
+					doc.Line(g.labeled(loop.Label,
 						fmt.Sprintf(
-							"for (let %s = 0; %s < %s; %s++) {",
-							loop.Cursor.Name,
-							loop.Cursor.Name,
-							toJSExpression(loop.Iterable),
-							loop.Cursor.Name,
+							"for (%s %s = 0; %s < %s; %s++) {",
+							counter,
+							cursor,
+							cursor,
+							g.toJSExpression(loop.Iterable),
+							cursor,
 						),
-					)
+					))
 				}
 				goto print_body_and_close
 			}
 
 			if _, ok := loop.Iterable.GetType().(checker.ListType); ok {
-				doc.Line(fmt.Sprintf("for (const %s of %s) {", loop.Cursor.Name, toJSExpression(loop.Iterable)))
+				if g.target == ES5 {
+					doc.Append(g.indexedForOf(loop))
+					goto print_body_and_close
+				}
+				doc.Line(g.labeled(loop.Label, fmt.Sprintf("for (%s %s of %s) {", g.binding(false), cursor, g.toJSExpression(loop.Iterable))))
 				goto print_body_and_close
 			}
 
@@ -154,80 +423,232 @@ func generateStatement(statement ast.Statement, _isReturn ...bool) ast.Document
 
 		print_body_and_close:
 			for _, statement := range loop.Body {
-				doc.Nest(generateStatement(statement))
+				doc.Nest(body.generateStatement(statement))
 			}
 			doc.Line("}")
 			return doc
 		}
 	case ast.IfStatement:
 		{
-			doc := ast.MakeDoc("")
+			doc := g.makeDoc("")
 			stmt := statement.(ast.IfStatement)
 			if stmt.Condition != nil {
-				doc.Line(fmt.Sprintf("if (%s) {", toJSExpression(stmt.Condition)))
+				doc.Line(fmt.Sprintf("if (%s) {", g.toJSExpression(stmt.Condition)))
 			} else {
 				start := stmt.TSNode.StartPosition()
 				panic(fmt.Errorf("[%d:%d] Condition is required for if statement", start.Row, start.Column))
 			}
 
 			for _, statement := range stmt.Body {
-				doc.Nest(generateStatement(statement))
+				doc.Nest(g.generateStatement(statement))
 			}
 
 			if stmt.Else != nil {
-				doc.Append(generateElseStatement(stmt.Else.(ast.IfStatement)))
+				doc.Append(g.generateElseStatement(stmt.Else.(ast.IfStatement)))
 			} else {
 				doc.Line("}")
 			}
 
 			return doc
 		}
+	case ast.Break:
+		if label := statement.(ast.Break).Label; label != "" {
+			return g.makeDoc(fmt.Sprintf("break %s", g.name(label)))
+		}
+		return g.makeDoc("break")
+	case ast.Continue:
+		if label := statement.(ast.Continue).Label; label != "" {
+			return g.makeDoc(fmt.Sprintf("continue %s", g.name(label)))
+		}
+		return g.makeDoc("continue")
+	case ast.Defer:
+		// function bodies queue their deferred expressions, so this is a `defer` the checker rejected
+		return g.generateStatement(statement.(ast.Defer).Expr)
 	case ast.Comment:
-		return ast.MakeDoc(statement.(ast.Comment).Value)
+		return g.makeDoc(statement.(ast.Comment).Value)
 	default:
 		if expr, ok := statement.(ast.Expression); ok {
-			js := toJSExpression(expr, true)
-			if isReturn {
-				return ast.MakeDoc("return " + js)
+			js := g.toJSExpression(expr, true)
+			if isReturn && expr.GetType() != checker.NeverType && !isAssertion(expr) {
+				return g.makeDoc("return " + js)
 			} else {
-				return ast.MakeDoc(js)
+				return g.makeDoc(js)
 			}
 		}
 		panic(fmt.Errorf("Unhandled statement node: [%s] - %s\n", reflect.TypeOf(statement), statement))
 	}
-	return ast.MakeDoc("")
+	return g.makeDoc("")
 }
 
-func generateElseStatement(stmt ast.IfStatement) ast.Document {
-	doc := ast.MakeDoc("")
+// prefixes the first line of a loop with its label, if it has one
+func (g jsGenerator) labeled(label string, line string) string {
+	if label == "" {
+		return line
+	}
+	return fmt.Sprintf("%s: %s", g.name(label), line)
+}
+
+// whether a range counts down, which is known at compile time when both bounds are constant
+func rangeDirection(expr ast.RangeExpression) (descending bool, known bool) {
+	start, ok := constantNum(expr.Start)
+	if !ok {
+		return false, false
+	}
+	end, ok := constantNum(expr.End)
+	if !ok {
+		return false, false
+	}
+	return start > end, true
+}
+
+func constantNum(expr ast.Expression) (float64, bool) {
+	switch expr.(type) {
+	case ast.NumLiteral:
+		return expr.(ast.NumLiteral).Float(), true
+	case ast.UnaryExpression:
+		unary := expr.(ast.UnaryExpression)
+		if unary.Operator != ast.Minus {
+			return 0, false
+		}
+		value, ok := constantNum(unary.Operand)
+		return -value, ok
+	default:
+		return 0, false
+	}
+}
+
+// nests the statements of a function body in @doc.
+// each `defer` queues its expression where it's reached, so it can read the locals declared before it
+// and it doesn't run if the function exits earlier. a `finally` runs the queue in reverse order however the function exits
+func (g jsGenerator) nestFunctionBody(doc *ast.Document, body []ast.Statement) {
+	if !slices.ContainsFunc(body, isDefer) {
+		for i, statement := range body {
+			doc.Nest(g.generateStatement(statement, i == len(body)-1))
+		}
+		return
+	}
+
+	doc.Indent()
+	doc.Line(fmt.Sprintf("%s %s = []", g.binding(false), deferredQueue))
+	doc.Line("try {")
+	for i, statement := range body {
+		if deferred, ok := statement.(ast.Defer); ok {
+			push := g.makeDoc(fmt.Sprintf("%s.push(%s", deferredQueue, g.function("")))
+			push.Nest(g.generateStatement(deferred.Expr))
+			push.Line("})")
+			doc.Nest(push)
+			continue
+		}
+		doc.Nest(g.generateStatement(statement, i == len(body)-1))
+	}
+	doc.Line("} finally {")
+	doc.Indent()
+	doc.Line(fmt.Sprintf("while (%s.length > 0) {", deferredQueue))
+	doc.Indent()
+	doc.Line(deferredQueue + ".pop()()")
+	doc.Dedent()
+	doc.Line("}")
+	doc.Dedent()
+	doc.Line("}")
+	doc.Dedent()
+}
+
+// the expressions deferred by the function being run, in the order they were reached
+const deferredQueue = "$deferred"
+
+func isDefer(statement ast.Statement) bool {
+	_, ok := statement.(ast.Defer)
+	return ok
+}
+
+func (g jsGenerator) generateElseStatement(stmt ast.IfStatement) ast.Document {
+	doc := g.makeDoc("")
 	if stmt.Condition != nil {
-		doc.Line(fmt.Sprintf("} else if (%s) {", toJSExpression(stmt.Condition)))
+		doc.Line(fmt.Sprintf("} else if (%s) {", g.toJSExpression(stmt.Condition)))
 	} else {
 		doc.Line("} else {")
 	}
 
-	body := ast.MakeDoc("")
+	body := g.makeDoc("")
 	for _, statement := range stmt.Body {
-		body.Append(generateStatement(statement))
+		body.Append(g.generateStatement(statement))
 	}
 
 	doc.Nest(body)
 	if stmt.Else != nil {
-		doc.Append(generateElseStatement(stmt.Else.(ast.IfStatement)))
+		doc.Append(g.generateElseStatement(stmt.Else.(ast.IfStatement)))
 	} else {
 		doc.Line("}")
 	}
 	return doc
 }
 
+// the JS names of Str methods that are spelled differently in Kon
+var jsStrMethods = map[string]string{
+	"uppercase": "toUpperCase",
+	"lowercase": "toLowerCase",
+	"contains":  "includes",
+}
+
+// the JS names of List methods that are spelled differently in Kon
+var jsListMethods = map[string]string{
+	"contains": "includes",
+}
+
+func (g jsGenerator) memberAccess(expr ast.MemberAccess, operator string) string {
+	target := g.toJSTarget(expr.Target)
+	// properties aren't bindings, so they keep their names
+	if member, ok := expr.Member.(ast.Identifier); ok {
+		return fmt.Sprintf("%s%s%s", target, operator, member.Name)
+	}
+	return fmt.Sprintf("%s%s%s", target, operator, g.toJSExpression(expr.Member))
+}
+
+// @expr as the target of a member access
+func (g jsGenerator) toJSTarget(expr ast.Expression) string {
+	if isConditional(expr) {
+		return "(" + g.toJSExpression(expr) + ")"
+	}
+	return g.toJSExpression(expr)
+}
+
 // rather than futzing with the AST to avoid adding runtime models
 func getJsMemberAccess(expr ast.MemberAccess) ast.MemberAccess {
-	if expr.Target.GetType().String() == checker.StrType.String() {
-		if expr.Member.(ast.Identifier).Name == "size" {
-			return ast.MemberAccess{
-				Target:     expr.Target,
-				AccessType: expr.AccessType,
-				Member:     ast.Identifier{Name: "length", Type: expr.Member.GetType()},
+	targetType := expr.Target.GetType()
+	if option, ok := targetType.(checker.OptionType); ok {
+		targetType = option.Inner
+	}
+	if targetType.String() == checker.StrType.String() {
+		switch member := expr.Member.(type) {
+		case ast.Identifier:
+			if member.Name == "size" {
+				return ast.MemberAccess{
+					Target:     expr.Target,
+					AccessType: expr.AccessType,
+					Member:     ast.Identifier{Name: "length", Type: expr.Member.GetType()},
+				}
+			}
+		case ast.FunctionCall:
+			if name, ok := jsStrMethods[member.Name]; ok {
+				member.Name = name
+				return ast.MemberAccess{
+					Target:     expr.Target,
+					AccessType: expr.AccessType,
+					Member:     member,
+				}
+			}
+		}
+	}
+	switch targetType.(type) {
+	case checker.ListType:
+		if member, ok := expr.Member.(ast.FunctionCall); ok {
+			if name, ok := jsListMethods[member.Name]; ok {
+				member.Name = name
+				return ast.MemberAccess{
+					Target:     expr.Target,
+					AccessType: expr.AccessType,
+					Member:     member,
+				}
 			}
 		}
 	}
@@ -235,39 +656,409 @@ func getJsMemberAccess(expr ast.MemberAccess) ast.MemberAccess {
 	return expr
 }
 
+// an assertion is generated as an `if` statement, which can't be returned
+func isAssertion(expr ast.Expression) bool {
+	call, ok := expr.(ast.FunctionCall)
+	return ok && call.Type.Builtin && call.Name == "assert"
+}
+
+// builtins that are functions of the Math object
+var jsMathFunctions = map[string]string{
+	"min": "Math.min",
+	"max": "Math.max",
+	"abs": "Math.abs",
+}
+
 func getJsFunctionCall(call ast.FunctionCall) ast.FunctionCall {
 	if call.Name == "print" {
 		call.Name = "console.log"
 	}
+	if name, ok := jsMathFunctions[call.Name]; ok && call.Type.Builtin {
+		call.Name = name
+	}
 
 	return call
 }
 
+type Options struct {
+	// a single level of indentation, e.g. "  " or "\t"
+	Indent string
+	// annotate functions with JSDoc comments describing their types
+	JSDoc bool
+	// export the top-level functions and enums so other modules can import them
+	Exports bool
+	// normalize the whitespace of the output with Pretty
+	Pretty bool
+	// the JS version the output has to run on
+	Target Target
+}
+
+var DefaultOptions = Options{Indent: "  "}
+
+// a version of JS to generate
+type Target string
+
+const (
+	// the default, which uses `let`, `const`, arrow functions and Map
+	ES2020 Target = "es2020"
+	// for older runtimes. variables are declared with `var` and functions are never arrow functions.
+	// newer syntax is spelled out: loops over lists and strings count through them, interpolated strings
+	// are concatenated, destructuring and `?.` read values one at a time, spreads are concatenated or copied,
+	// and modules are CommonJS. closures made in a loop copy its bindings, as `var`s are shared across iterations.
+	// maps are built without passing entries to the Map constructor, but Map itself still has to exist
+	ES5 Target = "es5"
+)
+
+type jsGenerator struct {
+	indent string
+	jsdoc  bool
+	target Target
+	// Kon names that had to be renamed in the output, mapped to their JS names
+	renamed map[string]string
+	// the runtime helpers that the output calls, which are declared at the end of it
+	helpers map[string]bool
+	// the structs and enums whose comparison is being generated, outermost first
+	comparing []string
+	// under ES5, the bindings of the loops around the code being generated, which closures made there copy
+	loopBindings []string
+	// under ES5, how many loops around the code being generated are in the same function, which keeps their counters apart
+	loopDepth int
+}
+
+// builds a Map from a list of entries, for runtimes whose Map constructor ignores them
+const mapHelper = "$makeMap"
+
+// compares two values by walking through their lists and objects, for types that contain themselves
+const equalsHelper = "$equals"
+
+// the generator for the body of a function, which isn't in any of the loops around it
+func (g jsGenerator) inFunction() jsGenerator {
+	g.loopBindings, g.loopDepth = nil, 0
+	return g
+}
+
+// the keyword that declares a variable
+func (g jsGenerator) binding(mutable bool) string {
+	if g.target == ES5 {
+		return "var"
+	}
+	if mutable {
+		return "let"
+	}
+	return "const"
+}
+
+// the start of a function expression taking @params, up to the opening brace of its body
+func (g jsGenerator) function(params string) string {
+	if g.target == ES5 {
+		return fmt.Sprintf("function (%s) {", params)
+	}
+	return fmt.Sprintf("(%s) => {", params)
+}
+
+// a function expression taking @params that returns @expr
+func (g jsGenerator) lambda(params, expr string) string {
+	if g.target == ES5 {
+		return fmt.Sprintf("function (%s) { return %s }", params, expr)
+	}
+	return fmt.Sprintf("(%s) => %s", params, expr)
+}
+
+// narrows an optional with `as`, which throws on none instead of letting null through.
+// a value that isn't a variable is only evaluated once, as the parameter of a function
+func (g jsGenerator) unwrap(cast ast.Cast) string {
+	throw := fmt.Sprintf(`(%s throw new Error("Cannot cast none to %s") })()`, g.function(""), cast.Type)
+	if identifier, ok := cast.Expr.(ast.Identifier); ok {
+		name := g.name(identifier.Name)
+		return fmt.Sprintf("(%s === null ? %s : %s)", name, throw, name)
+	}
+	return fmt.Sprintf("(%s)(%s)", g.lambda("value", "value === null ? "+throw+" : value"), g.toJSExpression(cast.Expr))
+}
+
+// the declarations of the helpers that were used.
+// function declarations are hoisted, so they can come after the code that calls them
+func (g jsGenerator) helperDeclarations() []string {
+	declarations := []string{}
+	if g.helpers[mapHelper] {
+		doc := g.makeDoc(fmt.Sprintf("function %s(entries) {", mapHelper))
+		doc.Indent()
+		doc.Line("var map = new Map()")
+		doc.Line("for (var i = 0; i < entries.length; i++) {")
+		doc.Indent()
+		doc.Line("map.set(entries[i][0], entries[i][1])")
+		doc.Dedent()
+		doc.Line("}")
+		doc.Line("return map")
+		doc.Dedent()
+		doc.Line("}")
+		declarations = append(declarations, strings.TrimRight(doc.String(), "\n"))
+	}
+	if g.helpers[assignHelper] {
+		declarations = append(declarations, g.assignHelperDeclaration())
+	}
+	if g.helpers[equalsHelper] {
+		doc := g.makeDoc(fmt.Sprintf("function %s(a, b) {", equalsHelper))
+		doc.Indent()
+		doc.Line("if (a === b) return true")
+		doc.Line("if (Array.isArray(a) && Array.isArray(b)) {")
+		doc.Indent()
+		doc.Line("if (a.length !== b.length) return false")
+		doc.Line("for (var i = 0; i < a.length; i++) {")
+		doc.Indent()
+		doc.Line(fmt.Sprintf("if (!%s(a[i], b[i])) return false", equalsHelper))
+		doc.Dedent()
+		doc.Line("}")
+		doc.Line("return true")
+		doc.Dedent()
+		doc.Line("}")
+		// Map may not be defined on older runtimes, where no value is a map
+		doc.Line(`if (typeof Map !== "undefined" && a instanceof Map && b instanceof Map) {`)
+		doc.Indent()
+		doc.Line("if (a.size !== b.size) return false")
+		doc.Line("var entries = Array.from(a.keys())")
+		doc.Line("for (var i = 0; i < entries.length; i++) {")
+		doc.Indent()
+		doc.Line(fmt.Sprintf("if (!b.has(entries[i]) || !%s(a.get(entries[i]), b.get(entries[i]))) return false", equalsHelper))
+		doc.Dedent()
+		doc.Line("}")
+		doc.Line("return true")
+		doc.Dedent()
+		doc.Line("}")
+		// structs and enums with values are plain objects, anything else is only equal to itself
+		doc.Line(`if (typeof a !== "object" || typeof b !== "object" || a === null || b === null) return false`)
+		doc.Line("if (Object.getPrototypeOf(a) !== Object.prototype || Object.getPrototypeOf(b) !== Object.prototype) return false")
+		doc.Line("var keys = Object.keys(a)")
+		doc.Line("if (keys.length !== Object.keys(b).length) return false")
+		doc.Line("for (var i = 0; i < keys.length; i++) {")
+		doc.Indent()
+		doc.Line(fmt.Sprintf("if (!%s(a[keys[i]], b[keys[i]])) return false", equalsHelper))
+		doc.Dedent()
+		doc.Line("}")
+		doc.Line("return true")
+		doc.Dedent()
+		doc.Line("}")
+		declarations = append(declarations, strings.TrimRight(doc.String(), "\n"))
+	}
+	return declarations
+}
+
+// words that can't be used as binding names in JS
+var reservedWords = map[string]bool{
+	"arguments": true, "await": true, "break": true, "case": true, "catch": true,
+	"class": true, "const": true, "continue": true, "debugger": true, "default": true,
+	"delete": true, "do": true, "else": true, "enum": true, "eval": true,
+	"export": true, "extends": true, "false": true, "finally": true, "for": true,
+	"function": true, "if": true, "implements": true, "import": true, "in": true,
+	"instanceof": true, "interface": true, "let": true, "new": true, "null": true,
+	"package": true, "private": true, "protected": true, "public": true, "return": true,
+	"static": true, "super": true, "switch": true, "this": true, "throw": true,
+	"true": true, "try": true, "typeof": true, "var": true, "void": true,
+	"while": true, "with": true, "yield": true,
+}
+
+// the JS name for a Kon identifier. names colliding with a reserved word get a trailing `$`,
+// which Kon identifiers can't contain, so the new name can't clash with another name in the program
+func (g jsGenerator) name(name string) string {
+	if renamed, ok := g.renamed[name]; ok {
+		return renamed
+	}
+	if !reservedWords[name] {
+		return name
+	}
+	renamed := name + "$"
+	g.renamed[name] = renamed
+	return renamed
+}
+
+func (g jsGenerator) names(names []string) []string {
+	renamed := make([]string, len(names))
+	for i, name := range names {
+		renamed[i] = g.name(name)
+	}
+	return renamed
+}
+
+func (g jsGenerator) makeDoc(content string) ast.Document {
+	doc := ast.MakeDoc(content)
+	doc.SetIndentUnit(g.indent)
+	return doc
+}
+
 func GenerateJS(program ast.Program) string {
-	doc := ast.MakeDoc("")
+	return GenerateJSWithOptions(program, DefaultOptions)
+}
+
+func GenerateJSWithOptions(program ast.Program, options Options) string {
+	var builder strings.Builder
+	// a strings.Builder never fails to write
+	GenerateJSToWithOptions(&builder, program, options)
+	return builder.String()
+}
+
+// GenerateJSTo writes the JS for @program to @w a statement at a time, instead of building it all in memory first
+func GenerateJSTo(w io.Writer, program ast.Program) error {
+	return GenerateJSToWithOptions(w, program, DefaultOptions)
+}
+
+// like GenerateJSTo, with @options. the --pretty pass looks at the whole output, so with Pretty it is buffered
+func GenerateJSToWithOptions(w io.Writer, program ast.Program, options Options) error {
+	if options.Pretty {
+		var builder strings.Builder
+		options.Pretty = false
+		GenerateJSToWithOptions(&builder, program, options)
+		_, err := io.WriteString(w, Pretty(builder.String()))
+		return err
+	}
+
+	g := jsGenerator{
+		indent:  options.Indent,
+		jsdoc:   options.JSDoc,
+		target:  options.Target,
+		renamed: make(map[string]string),
+		helpers: make(map[string]bool),
+	}
+	if g.indent == "" {
+		g.indent = DefaultOptions.Indent
+	}
+
+	out := &lineWriter{w: w}
+	var previous ast.Statement
+	for _, statement := range program.Statements {
+		generated := g.generateStatement(statement)
+		if generated.IsEmpty() {
+			continue
+		}
+		if previous != nil && (isDeclaration(previous) || isDeclaration(statement)) {
+			out.write("")
+		}
+		out.write(generated.String())
+		previous = statement
+	}
+
+	for _, helper := range g.helperDeclarations() {
+		out.write("")
+		out.write(helper)
+	}
+
+	if options.Exports {
+		if exports := g.exports(program); len(exports) > 0 {
+			out.write("")
+			out.write(g.exportStatement(exports))
+		}
+	}
+
+	return out.close()
+}
+
+// writes chunks of generated code on their own lines.
+// newlines at the end are held back until more code follows, so the output never ends in blank lines
+type lineWriter struct {
+	w io.Writer
+	// the number of chunks written so far
+	chunks int
+	// whether anything other than newlines has been written
+	written bool
+	// newlines that have been held back
+	pending int
+	err     error
+}
+
+func (l *lineWriter) write(chunk string) {
+	if l.chunks > 0 {
+		l.pending++
+	}
+	l.chunks++
+
+	chunk = strings.ReplaceAll(chunk, "%%", "%")
+	text := strings.TrimRight(chunk, "\n")
+	if text == "" {
+		l.pending += len(chunk)
+		return
+	}
+	if l.err == nil {
+		_, l.err = io.WriteString(l.w, strings.Repeat("\n", l.pending)+text)
+	}
+	l.pending = len(chunk) - len(text)
+	l.written = true
+}
+
+// ends the output with a single newline, unless nothing was written
+func (l *lineWriter) close() error {
+	if l.written && l.err == nil {
+		_, l.err = io.WriteString(l.w, "\n")
+	}
+	return l.err
+}
+
+// the JS names of the top-level functions and enums
+func (g jsGenerator) exports(program ast.Program) []string {
+	names := []string{}
 	for _, statement := range program.Statements {
-		doc.Append(generateStatement(statement))
+		switch statement := statement.(type) {
+		case ast.FunctionDeclaration:
+			names = append(names, g.name(statement.Name))
+		case ast.EnumDefinition:
+			names = append(names, g.name(statement.Type.Name))
+		}
 	}
+	return names
+}
 
-	return strings.ReplaceAll(doc.String(), "%%", "%")
+// ES5 has no modules, so its output is a CommonJS module
+func (g jsGenerator) exportStatement(names []string) string {
+	if g.target != ES5 {
+		return fmt.Sprintf("export { %s }", strings.Join(names, ", "))
+	}
+	properties := make([]string, len(names))
+	for i, name := range names {
+		properties[i] = fmt.Sprintf("%s: %s", name, name)
+	}
+	return fmt.Sprintf("module.exports = { %s }", strings.Join(properties, ", "))
 }
 
-func toJSExpression(node ast.Expression, _isStatement ...bool) string {
+// top-level declarations are separated from their neighbors by a blank line
+func isDeclaration(statement ast.Statement) bool {
+	switch statement.(type) {
+	case ast.FunctionDeclaration, ast.EnumDefinition, ast.StructDefinition:
+		return true
+	default:
+		return false
+	}
+}
+
+// whether @expr is a variable or a literal, which is as cheap to repeat as a temporary
+func isPlainValue(expr ast.Expression) bool {
+	switch expr.(type) {
+	case ast.Identifier, ast.BoolLiteral, ast.NumLiteral, ast.StrLiteral:
+		return true
+	default:
+		return false
+	}
+}
+
+// the subject of a match that isn't a plain value, bound inside the match's function
+const matchSubject = "$subject"
+
+func (g jsGenerator) toJSExpression(node ast.Expression, _isStatement ...bool) string {
 	isStatement := len(_isStatement) > 0 && _isStatement[0]
 	switch node.(type) {
 	case ast.Identifier:
-		return node.(ast.Identifier).Name
+		return g.name(node.(ast.Identifier).Name)
 	case ast.StrLiteral:
 		return node.(ast.StrLiteral).Value
 	case ast.InterpolatedStr:
 		{
 			str := node.(ast.InterpolatedStr)
+			if g.target == ES5 {
+				return g.concatenation(str)
+			}
 			output := "`"
 			for _, chunk := range str.Chunks {
 				if _, ok := chunk.(ast.StrLiteral); ok {
-					output += chunk.(ast.StrLiteral).Value
+					// text like the `${` in "$\{{" would otherwise start a substitution in the template literal
+					output += strings.ReplaceAll(chunk.(ast.StrLiteral).Value, "${", "$\\{")
 				} else {
-					output += fmt.Sprintf("${%s}", toJSExpression(chunk))
+					output += fmt.Sprintf("${%s}", g.toJSExpression(chunk))
 				}
 			}
 			return output + "`"
@@ -279,9 +1070,21 @@ func toJSExpression(node ast.Expression, _isStatement ...bool) string {
 	case ast.ListLiteral:
 		{
 			list := node.(ast.ListLiteral)
+			if g.target == ES5 && slices.ContainsFunc(list.Items, isSpread) {
+				return g.concatList(list.Items)
+			}
 			items := make([]string, len(list.Items))
 			for i, item := range list.Items {
-				items[i] = toJSExpression(item)
+				items[i] = g.toJSExpression(item)
+			}
+			return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+		}
+	case ast.TupleLiteral:
+		{
+			tuple := node.(ast.TupleLiteral)
+			items := make([]string, len(tuple.Items))
+			for i, item := range tuple.Items {
+				items[i] = g.toJSExpression(item)
 			}
 			return fmt.Sprintf("[%s]", strings.Join(items, ", "))
 		}
@@ -290,74 +1093,271 @@ func toJSExpression(node ast.Expression, _isStatement ...bool) string {
 			m := node.(ast.MapLiteral)
 			entries := make([]string, len(m.Entries))
 			for i, entry := range m.Entries {
-				entries[i] = fmt.Sprintf(`[%s, %s]`, entry.Key, toJSExpression(entry.Value))
+				entries[i] = fmt.Sprintf(`[%s, %s]`, entry.Key, g.toJSExpression(entry.Value))
+			}
+			if g.target == ES5 {
+				g.helpers[mapHelper] = true
+				return fmt.Sprintf("%s([%s])", mapHelper, strings.Join(entries, ", "))
 			}
 			return fmt.Sprintf("new Map([%s])", strings.Join(entries, ", "))
 		}
 	case ast.BinaryExpression:
 		binary := node.(ast.BinaryExpression)
-		lhs := toJSExpression(binary.Left)
+		if (binary.Operator == ast.Equal || binary.Operator == ast.NotEqual) && isObject(binary.Left.GetType()) {
+			comparison := fmt.Sprintf(
+				"(%s)(%s, %s)",
+				g.lambda("a, b", g.jsEquals(binary.Left.GetType(), "a", "b", 0)),
+				g.toJSExpression(binary.Left),
+				g.toJSExpression(binary.Right),
+			)
+			if binary.Operator == ast.NotEqual {
+				return "!" + comparison
+			}
+			return comparison
+		}
+		lhs := g.toJSOperand(binary.Left, binary.Operator, false)
 		op := resolveOperator(binary.Operator)
-		rhs := toJSExpression(binary.Right)
+		rhs := g.toJSOperand(binary.Right, binary.Operator, true)
 		if binary.HasPrecedence {
 			return "(" + lhs + " " + op + " " + rhs + ")"
 		}
 		return lhs + " " + op + " " + rhs
+	case ast.TypeQuery:
+		// only the checker is interested in the query
+		return g.toJSExpression(node.(ast.TypeQuery).Expr)
+	case ast.Cast:
+		cast := node.(ast.Cast)
+		_, fromOption := cast.Expr.GetType().(checker.OptionType)
+		if _, toOption := cast.Type.(checker.OptionType); fromOption && !toOption {
+			return g.unwrap(cast)
+		}
+		// optionals are plain values at runtime, so widening to one does nothing
+		if cast.Type == checker.StrType && cast.Expr.GetType() != checker.StrType {
+			return fmt.Sprintf("String(%s)", g.toJSExpression(cast.Expr))
+		}
+		return g.toJSExpression(cast.Expr)
 	case ast.UnaryExpression:
 		unary := node.(ast.UnaryExpression)
-		return resolveOperator(unary.Operator) + toJSExpression(unary.Operand)
+		op, operand := resolveOperator(unary.Operator), g.toJSExpression(unary.Operand)
+		// `--x` would be a decrement in JS, and a negated sum has to be negated as a whole
+		binary, isBinary := unary.Operand.(ast.BinaryExpression)
+		if (isBinary && !binary.HasPrecedence) || isConditional(unary.Operand) || (op == "-" && strings.HasPrefix(operand, "-")) {
+			return op + "(" + operand + ")"
+		}
+		return op + operand
 	case ast.AnonymousFunction:
 		fn := node.(ast.AnonymousFunction)
 		params := make([]string, len(fn.Parameters))
 		for i, param := range fn.Parameters {
-			params[i] = param.Name
-		}
-		doc := ast.MakeDoc(fmt.Sprintf("(%s) => {", strings.Join(params, ", ")))
-		for i, statement := range fn.Body {
-			doc.Nest(generateStatement(statement, i == len(fn.Body)-1))
+			params[i] = g.name(param.Name)
 		}
+		doc := g.makeDoc(g.function(strings.Join(params, ", ")))
+		g.inFunction().nestFunctionBody(&doc, fn.Body)
 		doc.Line("}")
+		if len(g.loopBindings) > 0 {
+			return g.copyLoopBindings(doc.String())
+		}
 		return doc.String()
 	case ast.StructInstance:
 		instance := node.(ast.StructInstance)
-		props := make([]string, len(instance.Properties))
-		for i, entry := range instance.Properties {
-			props[i] = fmt.Sprintf("%s: %s", entry.Name, toJSExpression(entry.Value))
+		props := make([]string, 0, len(instance.Properties)+1)
+		// the spread comes first so that the given fields override it
+		if instance.Spread != nil && g.target != ES5 {
+			props = append(props, "..."+g.toJSExpression(instance.Spread))
+		}
+		for _, entry := range instance.Properties {
+			value := g.toJSExpression(entry.Value)
+			// `{ name: name }` can be written as `{ name }`, unless the variable had to be renamed or it's ES5
+			if identifier, ok := entry.Value.(ast.Identifier); ok && identifier.Name == entry.Name && value == entry.Name && g.target != ES5 {
+				props = append(props, value)
+				continue
+			}
+			props = append(props, fmt.Sprintf("%s: %s", entry.Name, value))
+		}
+		if instance.Spread != nil && g.target == ES5 {
+			g.helpers[assignHelper] = true
+			return fmt.Sprintf("%s(%s, {%s})", assignHelper, g.toJSExpression(instance.Spread), strings.Join(props, ", "))
 		}
 		return fmt.Sprintf("{%s}", strings.Join(props, ", "))
+	case ast.Spread:
+		return "..." + g.toJSExpression(node.(ast.Spread).Expr)
 	case ast.FunctionCall:
 		call := getJsFunctionCall(node.(ast.FunctionCall))
+		// optionals are plain values at runtime, with null for absence
+		if call.Name == "some" {
+			return g.toJSExpression(call.Args[0])
+		}
+		if call.Name == "none" {
+			return "null"
+		}
+		// results are plain values at runtime and errors are thrown
+		if call.Name == "ok" {
+			return g.toJSExpression(call.Args[0])
+		}
+		if call.Name == "todo" || call.Name == "panic" || call.Name == "err" {
+			msg := `"todo"`
+			if len(call.Args) > 0 {
+				msg = g.toJSExpression(call.Args[0])
+			}
+			throw := fmt.Sprintf("throw new Error(%s)", msg)
+			if isStatement && call.Type.ReturnType == checker.NeverType {
+				return throw
+			}
+			// `throw` is a statement in JS
+			return fmt.Sprintf("(%s %s })()", g.function(""), throw)
+		}
+		if isAssertion(call) {
+			msg := `"Assertion failed"`
+			if len(call.Args) > 1 {
+				msg = g.toJSExpression(call.Args[1])
+			}
+			check := fmt.Sprintf("if (!(%s)) throw new Error(%s)", g.toJSExpression(call.Args[0]), msg)
+			if isStatement {
+				return check
+			}
+			// `if` is a statement in JS
+			return fmt.Sprintf("(%s %s })()", g.function(""), check)
+		}
 		args := make([]string, len(call.Args))
 		for i, arg := range call.Args {
-			args[i] = toJSExpression(arg)
+			args[i] = g.toJSExpression(arg)
 		}
-		result := fmt.Sprintf("%s(%s)", call.Name, strings.Join(args, ", "))
+		result := fmt.Sprintf("%s(%s)", g.name(call.Name), strings.Join(args, ", "))
 		if isStatement {
 			result += ";"
 		}
 		return result
+	case ast.EnumVariantInstance:
+		instance := node.(ast.EnumVariantInstance)
+		values := make([]string, len(instance.Values))
+		for i, value := range instance.Values {
+			values[i] = g.toJSExpression(value)
+		}
+		return fmt.Sprintf("{index: %s.%s, values: [%s]}", g.name(instance.Type.Name), instance.Variant, strings.Join(values, ", "))
 	case ast.MemberAccess:
 		expr := node.(ast.MemberAccess)
-		jsExpr := getJsMemberAccess(expr)
-		return fmt.Sprintf("%s.%s", toJSExpression(jsExpr.Target), toJSExpression(jsExpr.Member))
+		// variants of enums carrying data are all objects, even those without values
+		if enum, ok := expr.Target.GetType().(checker.EnumType); ok && enum.HasPayloads() && expr.AccessType == ast.Static {
+			return fmt.Sprintf("{index: %s.%s, values: []}", g.name(enum.Name), expr.Member.(ast.Identifier).Name)
+		}
+		if call, ok := expr.Member.(ast.FunctionCall); ok && expr.Target.GetType() == checker.NumType {
+			switch {
+			// `5.toString()` doesn't parse, so numbers are converted with String()
+			case call.Name == "toStr" && expr.AccessType == ast.Instance:
+				return fmt.Sprintf("String(%s)", g.toJSExpression(expr.Target))
+			// Number() is NaN for anything that isn't a number, which becomes none.
+			// it's 0 for a blank string though, so those are ruled out first
+			case call.Name == "from" && expr.AccessType == ast.Static:
+				isNaN := "Number.isNaN"
+				if g.target == ES5 {
+					isNaN = "isNaN"
+				}
+				parse := fmt.Sprintf(`s.trim() === "" || %s(Number(s)) ? null : Number(s)`, isNaN)
+				return fmt.Sprintf("(%s)(%s)", g.lambda("s", parse), g.toJSExpression(call.Args[0]))
+			}
+		}
+		// `includes` is newer than ES5
+		if call, ok := expr.Member.(ast.FunctionCall); ok && call.Name == "contains" && g.target == ES5 && isSearchable(expr.Target.GetType()) {
+			return fmt.Sprintf("(%s.indexOf(%s) !== -1)", g.toJSTarget(expr.Target), g.toJSExpression(call.Args[0]))
+		}
+		return g.memberAccess(getJsMemberAccess(expr), ".")
+	case ast.OptionalMemberAccess:
+		expr := node.(ast.OptionalMemberAccess)
+		if g.target == ES5 {
+			return g.optionalMember(expr)
+		}
+		// `none` is null at runtime, which is what `?.` short-circuits on
+		return g.memberAccess(getJsMemberAccess(ast.MemberAccess{
+			Target:     expr.Target,
+			AccessType: ast.Instance,
+			Member:     expr.Member,
+		}), "?.")
+	case ast.IndexAccess:
+		access := node.(ast.IndexAccess)
+		return fmt.Sprintf("%s[%s]", g.toJSExpression(access.Target), g.toJSExpression(access.Index))
+	case ast.TryExpression:
+		// a failed result has already thrown, so propagation is implicit
+		return g.toJSExpression(node.(ast.TryExpression).Expr, isStatement)
+	case ast.ConditionalExpression:
+		cond := node.(ast.ConditionalExpression)
+		return fmt.Sprintf(
+			"%s ? %s : %s",
+			g.toJSExpression(cond.Condition),
+			g.toJSExpression(cond.Consequent),
+			g.toJSExpression(cond.Alternative),
+		)
+	case ast.BlockExpression:
+		{
+			block := node.(ast.BlockExpression)
+			iife := g.makeDoc("(" + g.function(""))
+			for i, statement := range block.Body {
+				iife.Nest(g.generateStatement(statement, i == len(block.Body)-1))
+			}
+			iife.Line("})()")
+			if isStatement {
+				return iife.String() + ";"
+			}
+			return iife.String()
+		}
 	case ast.MatchExpression:
 		{
 			expr := node.(ast.MatchExpression)
-			armsDoc := ast.MakeDoc("")
-			for _, arm := range expr.Cases {
-				armsDoc.Line(
-					fmt.Sprintf(
-						"if (%s === %s) {",
-						toJSExpression(expr.Subject),
-						toJSExpression(arm.Pattern),
-					))
+			armsDoc := g.makeDoc("")
+			// the subject is evaluated once, before any arm is tested
+			subject := g.toJSExpression(expr.Subject)
+			if !isPlainValue(expr.Subject) {
+				armsDoc.Line(fmt.Sprintf("%s %s = %s", g.binding(false), matchSubject, subject))
+				subject = matchSubject
+			}
+			for index, arm := range expr.Cases {
+				keyword := "if"
+				if index > 0 {
+					keyword = "} else if"
+				}
+				if _, isWildcard := arm.Pattern.(ast.Wildcard); isWildcard {
+					if index == 0 {
+						armsDoc.Line("{")
+					} else {
+						armsDoc.Line("} else {")
+					}
+				} else if option, isOption := arm.Pattern.(ast.OptionPattern); isOption {
+					comparison := "==="
+					if option.IsSome {
+						comparison = "!=="
+					}
+					armsDoc.Line(fmt.Sprintf("%s (%s %s null) {", keyword, subject, comparison))
+					if option.IsSome {
+						armsDoc.Nest(g.makeDoc(fmt.Sprintf("%s %s = %s", g.binding(false), g.name(option.Binding), subject)))
+					}
+				} else if variant, isVariant := arm.Pattern.(ast.VariantPattern); isVariant {
+					armsDoc.Line(fmt.Sprintf("%s (%s.index === %s.%s) {", keyword, subject, g.name(variant.Type.Name), variant.Variant))
+					if g.target == ES5 {
+						armsDoc.Nest(g.destructureList(variant.Bindings, subject+".values"))
+					} else {
+						armsDoc.Nest(g.makeDoc(fmt.Sprintf("%s [%s] = %s.values", g.binding(false), strings.Join(g.names(variant.Bindings), ", "), subject)))
+					}
+				} else if enum, ok := expr.Subject.GetType().(checker.EnumType); ok && enum.HasPayloads() {
+					member := arm.Pattern.(ast.MemberAccess).Member.(ast.Identifier)
+					armsDoc.Line(fmt.Sprintf("%s (%s.index === %s.%s) {", keyword, subject, g.name(enum.Name), member.Name))
+				} else {
+					armsDoc.Line(
+						fmt.Sprintf(
+							"%s (%s === %s) {",
+							keyword,
+							subject,
+							g.toJSExpression(arm.Pattern),
+						))
+				}
 
 				for i, statement := range arm.Body {
-					armsDoc.Nest(generateStatement(statement, i == len(arm.Body)-1))
+					armsDoc.Nest(g.generateStatement(statement, i == len(arm.Body)-1))
 				}
+			}
+			// the checker rejected every arm, so there is no block to close
+			if len(expr.Cases) > 0 {
 				armsDoc.Line("}")
 			}
-			iife := ast.MakeDoc("(() => {")
+			iife := g.makeDoc("(" + g.function(""))
 			iife.Nest(armsDoc)
 			iife.Line("})()")
 			if isStatement {
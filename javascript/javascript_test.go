@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/akonwi/ard/ast"
+	"github.com/akonwi/ard/checker"
 	tree_sitter_ard "github.com/akonwi/tree-sitter-ard/bindings/go"
 	"github.com/google/go-cmp/cmp"
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
@@ -88,6 +89,16 @@ let num = 42
 				"const num = 42\n" +
 				"`num is ${num}`",
 		},
+		{
+			name: "escaped interpolation delimiters",
+			input: `
+let num = 42
+"\{{ num }} is {{ num }}"
+"costs $\{{ num }}"`,
+			output: "const num = 42\n" +
+				"`{{ num }} is ${num}`\n" +
+				`"costs ${{ num }}"`,
+		},
 		{
 			name:   "number",
 			input:  `42`,
@@ -108,11 +119,32 @@ let num = 42
 			input:  `["jane": 1, "joe": 2]`,
 			output: `new Map([["jane", 1], ["joe", 2]])`,
 		},
+		{
+			name:   "map literal entries keep their order",
+			input:  `["zoe": 3, "adam": 1, "mia": 2]`,
+			output: `new Map([["zoe", 3], ["adam", 1], ["mia", 2]])`,
+		},
 	}
 
 	runTests(t, tests)
 }
 
+func TestMapEntryOrder(t *testing.T) {
+	entries := []ast.MapEntry{}
+	for i, key := range []string{`"zoe"`, `"adam"`, `"mia"`, `"bo"`, `"kai"`} {
+		entries = append(entries, ast.MapEntry{Key: key, Value: ast.NumLiteral{Value: fmt.Sprint(i)}})
+	}
+	program := ast.Program{Statements: []ast.Statement{
+		ast.MapLiteral{Entries: entries, Type: checker.MakeMap(checker.NumType)},
+	}}
+
+	first := GenerateJS(program)
+	assertEquality(t, strings.TrimSpace(first), `new Map([["zoe", 0], ["adam", 1], ["mia", 2], ["bo", 3], ["kai", 4]])`)
+	for range 20 {
+		assertEquality(t, GenerateJS(program), first)
+	}
+}
+
 func TestBinaryExpressions(t *testing.T) {
 	tests := []test{
 		{
@@ -185,6 +217,26 @@ func TestBinaryExpressions(t *testing.T) {
 			input:  `20 >= 100`,
 			output: `20 >= 100`,
 		},
+		{
+			name:   "comparisons joined by and",
+			input:  `1 > 2 and 3 < 4`,
+			output: `1 > 2 && 3 < 4`,
+		},
+		{
+			name:   "comparisons joined by or",
+			input:  `1 >= 2 or 3 <= 4`,
+			output: `1 >= 2 || 3 <= 4`,
+		},
+		{
+			name:   "mixed and/or",
+			input:  `1 > 2 or 3 < 4 and 5 == 5`,
+			output: `1 > 2 || 3 < 4 && 5 === 5`,
+		},
+		{
+			name:   "explicit grouping of logical operators",
+			input:  `(1 > 2 or 3 < 4) and true`,
+			output: `(1 > 2 || 3 < 4) && true`,
+		},
 	}
 
 	runTests(t, tests)
@@ -202,11 +254,65 @@ func TestUnaryExpressions(t *testing.T) {
 			input:  `!true`,
 			output: `!true`,
 		},
+		{
+			name:   "boolean negation with the keyword",
+			input:  `not true`,
+			output: `!true`,
+		},
+		{
+			name:   "a negative literal",
+			input:  `let x = -30`,
+			output: `const x = -30`,
+		},
+		{
+			name: "subtracting a negation",
+			input: `
+let a = 1
+let b = 2
+a - -b`,
+			output: `
+const a = 1
+const b = 2
+a - -b`,
+		},
+		{
+			name: "double negation",
+			input: `
+let x = 1
+--x`,
+			output: `
+const x = 1
+-(-x)`,
+		},
 	}
 
 	runTests(t, tests)
 }
 
+// nodes built directly, for shapes that the parser only produces from some inputs
+func TestNegatingExpressions(t *testing.T) {
+	x := ast.Identifier{Name: "x", Type: checker.NumType}
+	negate := func(operand ast.Expression) ast.UnaryExpression {
+		return ast.UnaryExpression{Operator: ast.Minus, Operand: operand}
+	}
+	tests := []struct {
+		name   string
+		node   ast.Expression
+		output string
+	}{
+		{"negating a negation", negate(negate(x)), "-(-x)"},
+		{"negating a negative literal", negate(ast.NumLiteral{Value: "-30"}), "-(-30)"},
+		{"negating a sum", negate(ast.BinaryExpression{Left: x, Operator: ast.Plus, Right: ast.NumLiteral{Value: "1"}}), "-(x + 1)"},
+		{"subtracting a negation", ast.BinaryExpression{Left: x, Operator: ast.Minus, Right: negate(x)}, "x - -x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GenerateJS(ast.Program{Statements: []ast.Statement{tt.node}})
+			assertEquality(t, strings.TrimSpace(got), tt.output)
+		})
+	}
+}
+
 func TestVariableAssignment(t *testing.T) {
 	runTests(t, []test{
 		{
@@ -269,6 +375,48 @@ func TestVariableDeclaration(t *testing.T) {
 	runTests(t, tests)
 }
 
+func TestReservedWordNames(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "variables named after reserved words",
+			input: `
+let class = "A"
+mut new = 1
+new = new + 1
+print(class)`,
+			output: `
+const class$ = "A"
+let new$ = 1
+new$ = new$ + 1
+console.log(class$);`,
+		},
+		{
+			name: "functions and parameters named after reserved words",
+			input: `
+fn delete(this: Num) Num { this }
+delete(1)`,
+			output: `
+function delete$(this$) {
+  return this$
+}
+
+delete$(1);`,
+		},
+		{
+			name: "fields keep their names",
+			input: `
+struct Course { class: Str }
+let course = Course{ class: "math" }
+let { class } = course
+course.class`,
+			output: `
+const course = {class: "math"}
+const { class: class$ } = course
+course.class`,
+		},
+	})
+}
+
 func TestFunctionCalls(t *testing.T) {
 	runTests(t, []test{
 		{
@@ -282,6 +430,7 @@ get_msg()`,
 function get_msg() {
   return "hello"
 }
+
 get_msg();
 `,
 		},
@@ -294,8 +443,18 @@ add(1, 2)`,
 function add(x, y) {
   return x + y
 }
+
 add(1, 2);`,
 		},
+		{
+			name: "print",
+			input: `
+print("hello")
+print(42)`,
+			output: `
+console.log("hello");
+console.log(42);`,
+		},
 	})
 }
 
@@ -306,9 +465,162 @@ func TestStringMembers(t *testing.T) {
 			input:  `"foo".size`,
 			output: `"foo".length`,
 		},
+		{
+			name: "chained methods and properties",
+			input: `
+let name = "joe"
+name.trim().uppercase().size`,
+			output: `
+const name = "joe"
+name.trim().toUpperCase().length`,
+		},
+		{
+			name:   "Str.contains -> String.includes",
+			input:  `"hello world".contains("world")`,
+			output: `"hello world".includes("world")`,
+		},
+		{
+			name: "split, join and trim keep their names",
+			input: `
+let csv = " a,b "
+csv.trim().split(",").join(";")`,
+			output: `
+const csv = " a,b "
+csv.trim().split(",").join(";")`,
+		},
+		{
+			name: "slice keeps its name, with or without an end",
+			input: `
+let greeting = "hello world"
+greeting.slice(0, 5)
+greeting.slice(6)`,
+			output: `
+const greeting = "hello world"
+greeting.slice(0, 5)
+greeting.slice(6)`,
+		},
+	})
+}
+
+func TestNumConversions(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "formatting and parsing numbers",
+			input: `
+let count = 5
+let label = count.toStr()
+let parsed: Option<Num> = Num.from("5")`,
+			output: `
+const count = 5
+const label = String(count)
+const parsed = ((s) => s.trim() === "" || Number.isNaN(Number(s)) ? null : Number(s))("5")`,
+		},
+	})
+}
+
+// built directly so the conversions are generated even where the grammar isn't available
+func TestNumConversionNodes(t *testing.T) {
+	toStr := checker.NumType.GetProperty("toStr").(checker.FunctionType)
+	program := ast.Program{Statements: []ast.Statement{
+		ast.MemberAccess{
+			Target:     ast.NumLiteral{Value: "5", Type: checker.NumType},
+			AccessType: ast.Instance,
+			Member:     ast.FunctionCall{Name: "toStr", Args: []ast.Expression{}, Type: toStr},
+		},
+		ast.MemberAccess{
+			Target:     ast.Identifier{Name: "Num", Type: checker.NumType},
+			AccessType: ast.Static,
+			Member:     ast.FunctionCall{Name: "from", Args: []ast.Expression{ast.StrLiteral{Value: `"5"`}}, Type: checker.NumFrom},
+		},
+	}}
+	got := GenerateJS(program)
+	assertEquality(t, strings.TrimSpace(got), "String(5)\n((s) => s.trim() === \"\" || Number.isNaN(Number(s)) ? null : Number(s))(\"5\")")
+
+	// a blank string is none rather than the 0 that Number() makes of it
+	blank := ast.Program{Statements: []ast.Statement{ast.MemberAccess{
+		Target:     ast.Identifier{Name: "Num", Type: checker.NumType},
+		AccessType: ast.Static,
+		Member:     ast.FunctionCall{Name: "from", Args: []ast.Expression{ast.StrLiteral{Value: `"  "`}}, Type: checker.NumFrom},
+	}}}
+	assertEquality(t, strings.TrimSpace(GenerateJSWithOptions(blank, Options{Target: ES5})), `(function (s) { return s.trim() === "" || isNaN(Number(s)) ? null : Number(s) })("  ")`)
+}
+
+func TestCasts(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "narrowing checks for none and formatting is a conversion",
+			input: `
+let maybe: Option<Num> = some(42)
+let count = maybe as Num
+let label = count as Str`,
+			output: `
+const maybe = 42
+const count = (maybe === null ? (() => { throw new Error("Cannot cast none to Num") })() : maybe)
+const label = String(count)`,
+		},
+	})
+}
+
+func TestOptionNarrowing(t *testing.T) {
+	find := checker.FunctionType{Parameters: []checker.Type{}, ReturnType: checker.OptionType{Inner: checker.NumType}}
+	program := ast.Program{Statements: []ast.Statement{
+		ast.Cast{Expr: ast.FunctionCall{Name: "find", Args: []ast.Expression{}, Type: find}, Type: checker.NumType},
+	}}
+	assertEquality(t, strings.TrimSpace(GenerateJS(program)),
+		`((value) => value === null ? (() => { throw new Error("Cannot cast none to Num") })() : value)(find())`)
+	assertEquality(t, strings.TrimSpace(GenerateJSWithOptions(program, Options{Target: ES5})),
+		`(function (value) { return value === null ? (function () { throw new Error("Cannot cast none to Num") })() : value })(find())`)
+}
+
+func TestTrailingCommas(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "are left out of lists, maps and parameters",
+			input: `
+let items = [1, 2, 3,]
+let scores = ["a": 1,]
+fn double(x: Num,) Num { x * 2 }`,
+			output: `
+const items = [1, 2, 3]
+const scores = new Map([["a", 1]])
+
+function double(x) {
+  return x * 2
+}`,
+		},
+	})
+}
+
+func TestListContains(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "List.contains -> Array.includes",
+			input: `
+let items = [1, 2, 3]
+let found = items.contains(2)`,
+			output: `
+const items = [1, 2, 3]
+const found = items.includes(2)`,
+		},
 	})
 }
 
+// built directly so the chain is generated even where the grammar isn't available
+func TestChainedMemberAccess(t *testing.T) {
+	lowercase := checker.StrType.GetProperty("lowercase").(checker.FunctionType)
+	chain := ast.MemberAccess{
+		Target: ast.MemberAccess{
+			Target:     ast.StrLiteral{Value: `"A"`},
+			AccessType: ast.Instance,
+			Member:     ast.FunctionCall{Name: "lowercase", Args: []ast.Expression{}, Type: lowercase},
+		},
+		AccessType: ast.Instance,
+		Member:     ast.Identifier{Name: "size", Type: checker.NumType},
+	}
+	got := GenerateJS(ast.Program{Statements: []ast.Statement{chain}})
+	assertEquality(t, strings.TrimSpace(got), `"A".toLowerCase().length`)
+}
+
 func TestFunctionDeclaration(t *testing.T) {
 	tests := []test{
 		{
@@ -352,6 +664,119 @@ function add(x, y) {
 	runTests(t, tests)
 }
 
+func TestDefer(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "a deferred expression runs in a finally",
+			input: `
+fn read() Num {
+  defer print("closed")
+  42
+}`,
+			output: `
+function read() {
+  const $deferred = []
+  try {
+    $deferred.push(() => {
+      console.log("closed");
+    })
+    return 42
+  } finally {
+    while ($deferred.length > 0) {
+      $deferred.pop()()
+    }
+  }
+}`,
+		},
+		{
+			name: "deferred expressions run in reverse order",
+			input: `
+fn work() {
+  defer print("first")
+  print("working")
+  defer print("second")
+}`,
+			output: `
+function work() {
+  const $deferred = []
+  try {
+    $deferred.push(() => {
+      console.log("first");
+    })
+    console.log("working");
+    $deferred.push(() => {
+      console.log("second");
+    })
+  } finally {
+    while ($deferred.length > 0) {
+      $deferred.pop()()
+    }
+  }
+}`,
+		},
+	})
+}
+
+func TestDeferredLocals(t *testing.T) {
+	file := ast.Identifier{Name: "file", Type: checker.NumType}
+	closeFn := checker.FunctionType{Parameters: []checker.Type{checker.NumType}, ReturnType: checker.VoidType}
+	program := ast.Program{Statements: []ast.Statement{ast.FunctionDeclaration{
+		Name:       "read",
+		Parameters: []ast.Parameter{},
+		ReturnType: checker.NumType,
+		Type:       checker.FunctionType{Parameters: []checker.Type{}, ReturnType: checker.NumType},
+		Body: []ast.Statement{
+			ast.VariableDeclaration{Name: "file", Value: ast.NumLiteral{Value: "3"}, Type: checker.NumType},
+			ast.Defer{Expr: ast.FunctionCall{Name: "close", Args: []ast.Expression{file}, Type: closeFn}},
+			file,
+		},
+	}}}
+
+	assertEquality(t, strings.TrimSpace(GenerateJS(program)), strings.TrimSpace(`
+function read() {
+  const $deferred = []
+  try {
+    const file = 3
+    $deferred.push(() => {
+      close(file);
+    })
+    return file
+  } finally {
+    while ($deferred.length > 0) {
+      $deferred.pop()()
+    }
+  }
+}`))
+	assertEquality(t, strings.TrimSpace(GenerateJSWithOptions(program, Options{Target: ES5})), strings.TrimSpace(`
+function read() {
+  var $deferred = []
+  try {
+    var file = 3
+    $deferred.push(function () {
+      close(file);
+    })
+    return file
+  } finally {
+    while ($deferred.length > 0) {
+      $deferred.pop()()
+    }
+  }
+}`))
+}
+
+func TestGenericFunctions(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "type parameters are erased",
+			input: `fn first<T>(xs: [T]) T { xs[0] }`,
+			output: `
+function first(xs) {
+  return xs[0]
+}`,
+		},
+	})
+}
+
 func TestAnonymousFunctions(t *testing.T) {
 	tests := []test{
 		{
@@ -372,6 +797,41 @@ func TestAnonymousFunctions(t *testing.T) {
 	runTests(t, tests)
 }
 
+func TestListMethods(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "map, filter, and reduce",
+			input: `
+let list = [1, 2, 3]
+list.map((num) { num * 2 })
+list.filter((num) { num > 1 })
+list.reduce(0, (acc, num) { acc + num })`,
+			output: `
+const list = [1, 2, 3]
+list.map((num) => {
+  return num * 2
+})
+list.filter((num) => {
+  return num > 1
+})
+list.reduce(0, (acc, num) => {
+  return acc + num
+})`,
+		},
+		{
+			name: "slice keeps its name, with or without an end",
+			input: `
+let list = [1, 2, 3]
+list.slice(1, 2)
+list.slice(1)`,
+			output: `
+const list = [1, 2, 3]
+list.slice(1, 2)
+list.slice(1)`,
+		},
+	})
+}
+
 func TestStructs(t *testing.T) {
 	runTests(t, []test{
 		{
@@ -390,86 +850,547 @@ Person{ name: "Joe", age: 42, employed: true }`,
 			output: `
 {name: "Joe", age: 42, employed: true}`,
 		},
+		{
+			name: "struct with default fields",
+			input: `
+struct Person { name: Str, age: Num = 0, employed: Bool = false }
+Person{ name: "Joe" }`,
+			output: `
+{name: "Joe", age: 0, employed: false}`,
+		},
+		{
+			name: "shorthand for a variable named like the field",
+			input: `
+struct Person { name: Str, age: Num }
+let name = "Joe"
+let years = 42
+Person{ name: name, age: years }`,
+			output: `
+const name = "Joe"
+const years = 42
+{name, age: years}`,
+		},
 	})
 }
 
-func TestEnums(t *testing.T) {
+func TestSpread(t *testing.T) {
 	runTests(t, []test{
 		{
-			name:  "basic enum",
-			input: `enum Color { Red, Green, Yellow }`,
+			name: "spreading lists and structs",
+			input: `
+struct Person { name: Str, age: Num }
+let xs = [1, 2, 3]
+let more = [...xs, 4]
+let base = Person{ name: "Joe", age: 30 }
+let older = Person{ age: 31, ...base }`,
 			output: `
-const Color = Object.freeze({
-  Red: 0,
-  Green: 1,
-  Yellow: 2
-})`,
+const xs = [1, 2, 3]
+const more = [...xs, 4]
+const base = {name: "Joe", age: 30}
+const older = {...base, age: 31}`,
 		},
 	})
 }
 
-func TestWhileLoops(t *testing.T) {
+func TestObjectLiterals(t *testing.T) {
 	runTests(t, []test{
 		{
-			name:  "basic while loop",
-			input: `while true { 20 }`,
+			name: "object literals are emitted as they are",
+			input: `
+let person = { name: "Joe", age: 42 }
+person.name`,
 			output: `
-while (true) {
-  20
-}`,
+const person = {name: "Joe", age: 42}
+person.name`,
 		},
 	})
 }
 
-func TestForLoops(t *testing.T) {
+func TestStructPropertyOrder(t *testing.T) {
+	person := checker.StructType{Name: "Person", Fields: map[string]checker.Type{
+		"name": checker.StrType, "age": checker.NumType, "employed": checker.BoolType, "city": checker.StrType,
+	}}
+	instance := ast.StructInstance{
+		Type: person,
+		Properties: []ast.StructValue{
+			{Name: "name", Value: ast.StrLiteral{Value: `"Joe"`}},
+			{Name: "employed", Value: ast.BoolLiteral{Value: true}},
+			{Name: "city", Value: ast.StrLiteral{Value: `"Lagos"`}},
+			{Name: "age", Value: ast.NumLiteral{Value: "42"}},
+		},
+	}
+	program := ast.Program{Statements: []ast.Statement{instance}}
+
+	want := `{name: "Joe", employed: true, city: "Lagos", age: 42}`
+	for range 20 {
+		assertEquality(t, strings.TrimSpace(GenerateJS(program)), want)
+	}
+}
+
+func TestStructShorthand(t *testing.T) {
+	person := checker.StructType{Name: "Person", Fields: map[string]checker.Type{"name": checker.StrType, "delete": checker.BoolType}}
+	instance := ast.StructInstance{
+		Type: person,
+		Properties: []ast.StructValue{
+			{Name: "name", Value: ast.Identifier{Name: "name", Type: checker.StrType}},
+			// the variable is renamed in JS, so it no longer matches the field
+			{Name: "delete", Value: ast.Identifier{Name: "delete", Type: checker.BoolType}},
+		},
+	}
+	got := GenerateJS(ast.Program{Statements: []ast.Statement{instance}})
+	assertEquality(t, strings.TrimSpace(got), "{name, delete: delete$}")
+}
+
+func TestRenamedNamesDontCollide(t *testing.T) {
+	program := ast.Program{Statements: []ast.Statement{
+		ast.VariableDeclaration{Name: "class", Value: ast.StrLiteral{Value: `"A"`}, Type: checker.StrType},
+		ast.VariableDeclaration{Name: "class_", Value: ast.StrLiteral{Value: `"B"`}, Type: checker.StrType},
+	}}
+	assertEquality(t, strings.TrimSpace(GenerateJS(program)), "const class$ = \"A\"\nconst class_ = \"B\"")
+}
+
+func TestStructFieldAssignment(t *testing.T) {
 	runTests(t, []test{
 		{
-			name:  "looping over a number",
-			input: `for i in 10 { i }`,
+			name: "assigning a field",
+			input: `
+struct Person { name: Str, age: Num }
+mut person = Person{ name: "Joe", age: 42 }
+person.age = 31
+person.age =+ 1`,
 			output: `
-for (let i = 0; i < 10; i++) {
-  i
-}`,
+let person = {name: "Joe", age: 42}
+person.age = 31
+person.age += 1`,
 		},
+	})
+}
+
+func TestStructDestructuring(t *testing.T) {
+	runTests(t, []test{
 		{
-			name:  "looping over a range",
-			input: `for num in 0..10 { num }`,
+			name: "destructuring fields",
+			input: `
+struct Person { name: Str, age: Num }
+let person = Person{ name: "Joe", age: 42 }
+let { name, age } = person`,
 			output: `
-for (let num = 0; num < 10; num++) {
-  num
-}`,
+const person = {name: "Joe", age: 42}
+const { name, age } = person`,
 		},
+	})
+}
+
+func TestStrIndexing(t *testing.T) {
+	runTests(t, []test{
 		{
-			name:  "looping over an array",
-			input: `for name in ["jane", "joe"] { name }`,
+			name: "reading a character",
+			input: `
+let msg = "hello"
+let first = msg[0]`,
 			output: `
-for (const name of ["jane", "joe"]) {
-  name
-}`,
+const msg = "hello"
+const first = msg[0]`,
 		},
+	})
+}
+
+func TestOptionalMemberAccess(t *testing.T) {
+	runTests(t, []test{
 		{
-			name: "looping over a string",
+			name: "accessing a field of an optional struct",
 			input: `
-let msg = "hello world"
-for char in msg { char }`,
+struct Person { name: Str, age: Num }
+let person: Option<Person> = none()
+let name = person?.name`,
 			output: `
-const msg = "hello world"
-for (const char of msg) {
-  char
-}`,
+const person = null
+const name = person?.name`,
 		},
 	})
 }
 
-func TestIfStatements(t *testing.T) {
+func TestListElements(t *testing.T) {
 	runTests(t, []test{
 		{
-			name:  "simple conditions",
-			input: `if true { 42 }`,
+			name: "reading and assigning elements",
+			input: `
+mut items = [1, 2, 3]
+items[0] = 5
+items[1] =- 1
+items[1]`,
 			output: `
-if (true) {
-  42
-}`,
+let items = [1, 2, 3]
+items[0] = 5
+items[1] -= 1
+items[1]`,
+		},
+	})
+}
+
+func TestListDestructuring(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "destructuring a tuple",
+			input: `
+let pair = ("Joe", 42)
+let [name, age] = pair`,
+			output: `
+const pair = ["Joe", 42]
+const [name, age] = pair`,
+		},
+		{
+			name: "destructuring an annotated list",
+			input: `
+let xs: List<Num> = [1, 2]
+let [a, b] = xs
+for x in xs {}`,
+			output: `
+const xs = [1, 2]
+const [a, b] = xs
+for (const x of xs) {
+}`,
+		},
+		{
+			name: "multiple return values",
+			input: `
+fn minmax(xs: [Num]) (Num, Num) { (0, 0) }
+let (lo, hi) = minmax([3, 1, 2])`,
+			output: `
+function minmax(xs) {
+  return [0, 0]
+}
+
+const [lo, hi] = minmax([3, 1, 2])`,
+		},
+	})
+}
+
+func TestTypeAliases(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "aliases produce no code",
+			input: `
+type Id = Num
+let id: Id = 5`,
+			output: `const id = 5`,
+		},
+	})
+}
+
+func TestEnums(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "basic enum",
+			input: `enum Color { Red, Green, Yellow }`,
+			output: `
+const Color = Object.freeze({
+  Red: 0,
+  Green: 1,
+  Yellow: 2
+})`,
+		},
+	})
+}
+
+func TestWhileLoops(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "basic while loop",
+			input: `while true { 20 }`,
+			output: `
+while (true) {
+  20
+}`,
+		},
+	})
+}
+
+func TestForLoops(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "looping over a number",
+			input: `for i in 10 { i }`,
+			output: `
+for (let i = 0; i < 10; i++) {
+  i
+}`,
+		},
+		{
+			name:  "looping over a range",
+			input: `for num in 0..10 { num }`,
+			output: `
+for (let num = 0; num < 10; num++) {
+  num
+}`,
+		},
+		{
+			name:  "looping over a descending range",
+			input: `for num in 10..0 { num }`,
+			output: `
+for (let num = 10; num > 0; num--) {
+  num
+}`,
+		},
+		{
+			name:  "looping over a range into negatives",
+			input: `for num in 2..-2 { num }`,
+			output: `
+for (let num = 2; num > -2; num--) {
+  num
+}`,
+		},
+		{
+			name:  "looping over an array",
+			input: `for name in ["jane", "joe"] { name }`,
+			output: `
+for (const name of ["jane", "joe"]) {
+  name
+}`,
+		},
+		{
+			name: "looping over a string",
+			input: `
+let msg = "hello world"
+for char in msg { char }`,
+			output: `
+const msg = "hello world"
+for (const char of msg) {
+  char
+}`,
+		},
+		{
+			name: "looping with an index",
+			input: `
+let names = ["jane", "joe"]
+for i, name in names { name }`,
+			output: `
+const names = ["jane", "joe"]
+for (let i = 0; i < names.length; i++) {
+  const name = names[i]
+  name
+}`,
+		},
+	})
+}
+
+func TestDynamicRanges(t *testing.T) {
+	size := checker.FunctionType{Parameters: []checker.Type{}, ReturnType: checker.NumType}
+	loop := ast.ForLoop{
+		Cursor: ast.Identifier{Name: "num", Type: checker.NumType},
+		Iterable: ast.RangeExpression{
+			Start: ast.Identifier{Name: "start", Type: checker.NumType},
+			End:   ast.FunctionCall{Name: "size", Args: []ast.Expression{}, Type: size},
+		},
+		Body: []ast.Statement{},
+	}
+	// the direction is decided when the loop starts, so a start past the end counts down
+	assertEquality(t, strings.TrimSpace(GenerateJS(ast.Program{Statements: []ast.Statement{loop}})), `for (let num = start, $end0 = size(), $step0 = num > $end0 ? -1 : 1; $step0 > 0 ? num < $end0 : num > $end0; num += $step0) {
+}`)
+
+	loop.Iterable = ast.RangeExpression{Start: ast.NumLiteral{Value: "0", Type: checker.NumType}, End: ast.Identifier{Name: "end", Type: checker.NumType}}
+	assertEquality(t, strings.TrimSpace(GenerateJSWithOptions(ast.Program{Statements: []ast.Statement{loop}}, Options{Target: ES5})), `for (var num = 0, $step0 = num > end ? -1 : 1; $step0 > 0 ? num < end : num > end; num += $step0) {
+}`)
+}
+
+// built directly so the loops are generated even where the grammar isn't available.
+// each form must declare its cursor per iteration, so closures capture that iteration's value
+func TestLoopClosureCapture(t *testing.T) {
+	callback := checker.FunctionType{Parameters: []checker.Type{}, ReturnType: checker.NumType}
+	callbacks := ast.Identifier{Name: "callbacks", Type: checker.MakeList(callback)}
+	push := callbacks.Type.GetProperty("push").(checker.FunctionType)
+	pushCapturing := func(name string) ast.Statement {
+		return ast.MemberAccess{
+			Target:     callbacks,
+			AccessType: ast.Instance,
+			Member: ast.FunctionCall{
+				Name: "push",
+				Args: []ast.Expression{ast.AnonymousFunction{
+					Parameters: []ast.Parameter{},
+					ReturnType: checker.NumType,
+					Body:       []ast.Statement{ast.Identifier{Name: name, Type: checker.NumType}},
+				}},
+				Type: push,
+			},
+		}
+	}
+	items := ast.Identifier{Name: "items", Type: checker.MakeList(checker.NumType)}
+
+	tests := []struct {
+		name        string
+		loop        ast.Statement
+		output, es5 string
+	}{
+		{
+			name: "over a list",
+			loop: ast.ForLoop{
+				Cursor:   ast.Identifier{Name: "item", Type: checker.NumType},
+				Iterable: items,
+				Body:     []ast.Statement{pushCapturing("item")},
+			},
+			output: `
+for (const item of items) {
+  callbacks.push(() => {
+    return item
+  })
+}`,
+			es5: `
+for (var $i0 = 0; $i0 < items.length; $i0++) {
+  var item = items[$i0]
+  callbacks.push((function (item) {
+    return function () {
+      return item
+    }
+  })(item))
+}`,
+		},
+		{
+			name: "over a range",
+			loop: ast.ForLoop{
+				Cursor:   ast.Identifier{Name: "i", Type: checker.NumType},
+				Iterable: ast.RangeExpression{Start: ast.NumLiteral{Value: "1", Type: checker.NumType}, End: ast.NumLiteral{Value: "3", Type: checker.NumType}},
+				Body:     []ast.Statement{pushCapturing("i")},
+			},
+			output: `
+for (let i = 1; i < 3; i++) {
+  callbacks.push(() => {
+    return i
+  })
+}`,
+			es5: `
+for (var i = 1; i < 3; i++) {
+  callbacks.push((function (i) {
+    return function () {
+      return i
+    }
+  })(i))
+}`,
+		},
+		{
+			name: "with an index",
+			loop: ast.ForLoop{
+				Index:    &ast.Identifier{Name: "i", Type: checker.NumType},
+				Cursor:   ast.Identifier{Name: "item", Type: checker.NumType},
+				Iterable: items,
+				Body:     []ast.Statement{pushCapturing("item")},
+			},
+			output: `
+for (let i = 0; i < items.length; i++) {
+  const item = items[i]
+  callbacks.push(() => {
+    return item
+  })
+}`,
+			es5: `
+for (var i = 0; i < items.length; i++) {
+  var item = items[i]
+  callbacks.push((function (item, i) {
+    return function () {
+      return item
+    }
+  })(item, i))
+}`,
+		},
+		{
+			name: "a binding declared in the body of a while loop",
+			loop: ast.WhileLoop{
+				Condition: ast.BoolLiteral{Value: true},
+				Body: []ast.Statement{
+					ast.VariableDeclaration{Name: "n", Value: ast.NumLiteral{Value: "1", Type: checker.NumType}},
+					pushCapturing("n"),
+				},
+			},
+			output: `
+while (true) {
+  const n = 1
+  callbacks.push(() => {
+    return n
+  })
+}`,
+			es5: `
+while (true) {
+  var n = 1
+  callbacks.push((function (n) {
+    return function () {
+      return n
+    }
+  })(n))
+}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := ast.Program{Statements: []ast.Statement{tt.loop}}
+			assertEquality(t, strings.TrimSpace(GenerateJS(program)), strings.TrimSpace(tt.output))
+			assertEquality(t, strings.TrimSpace(GenerateJSWithOptions(program, Options{Target: ES5})), strings.TrimSpace(tt.es5))
+		})
+	}
+}
+
+func TestLoop(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "an unconditional loop",
+			input: `
+mut count = 0
+loop {
+  count =+ 1
+  if count > 3 {
+    break
+  }
+}`,
+			output: `
+let count = 0
+while (true) {
+  count += 1
+  if (count > 3) {
+    break
+  }
+}`,
+		},
+	})
+}
+
+func TestLoopLabels(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "breaking out of an outer loop",
+			input: `
+outer: for i in 3 {
+  for j in 3 {
+    break outer
+  }
+}`,
+			output: `
+outer: for (let i = 0; i < 3; i++) {
+  for (let j = 0; j < 3; j++) {
+    break outer
+  }
+}`,
+		},
+		{
+			name: "continuing the innermost loop",
+			input: `
+while true {
+  continue
+}`,
+			output: `
+while (true) {
+  continue
+}`,
+		},
+	})
+}
+
+func TestIfStatements(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "simple conditions",
+			input: `if true { 42 }`,
+			output: `
+if (true) {
+  42
+}`,
 		},
 		{
 			name:  "complex conditions",
@@ -547,15 +1468,1003 @@ const Sign = Object.freeze({
   Positive: 0,
   Negative: 1
 })
+
 const value = Sign.Positive
 (() => {
   if (value === Sign.Positive) {
     return "+"
-  }
-  if (value === Sign.Negative) {
+  } else if (value === Sign.Negative) {
     return "-"
   }
 })();`,
 		},
+		{
+			name: "wildcard arm",
+			input: `
+enum Sign { Positive, Negative, Zero }
+let value = Sign::Positive
+match value {
+	Sign::Positive => "+",
+	_ => "?"
+}`,
+			output: `
+const Sign = Object.freeze({
+  Positive: 0,
+  Negative: 1,
+  Zero: 2
+})
+
+const value = Sign.Positive
+(() => {
+  if (value === Sign.Positive) {
+    return "+"
+  } else {
+    return "?"
+  }
+})();`,
+		},
+	})
+}
+
+func TestEnumsWithData(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "constructing and matching variants with values",
+			input: `
+enum Shape { Circle(Num), Empty }
+let shape = Shape::Circle(2)
+let nothing = Shape::Empty
+match shape {
+	Shape::Circle(radius) => radius,
+	Shape::Empty => 0
+}`,
+			output: `
+const Shape = Object.freeze({
+  Circle: 0,
+  Empty: 1
+})
+
+const shape = {index: Shape.Circle, values: [2]}
+const nothing = {index: Shape.Empty, values: []}
+(() => {
+  if (shape.index === Shape.Circle) {
+    const [radius] = shape.values
+    return radius
+  } else if (shape.index === Shape.Empty) {
+    return 0
+  }
+})();`,
+		},
+	})
+}
+
+func TestEnumEquality(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "variants without values are compared as numbers",
+			input: `
+enum Color { Red, Green }
+let same = Color::Red == Color::Green`,
+			output: `
+const Color = Object.freeze({
+  Red: 0,
+  Green: 1
+})
+
+const same = Color.Red === Color.Green`,
+		},
+		{
+			name: "variants with values are compared by index and values",
+			input: `
+enum Shape { Circle(Num), Empty }
+let same = Shape::Circle(2) != Shape::Empty`,
+			output: `
+const Shape = Object.freeze({
+  Circle: 0,
+  Empty: 1
+})
+
+const same = !((a, b) => a.index === b.index && (a.index === Shape.Circle ? a.values[0] === b.values[0] : true))({index: Shape.Circle, values: [2]}, {index: Shape.Empty, values: []})`,
+		},
+	})
+}
+
+func TestStructEquality(t *testing.T) {
+	point := checker.StructType{Name: "Point", Fields: map[string]checker.Type{"x": checker.NumType, "y": checker.NumType}}
+	path := checker.StructType{Name: "Path", Fields: map[string]checker.Type{
+		"name":   checker.StrType,
+		"points": checker.MakeList(point),
+	}}
+	compare := func(t checker.Type, operator ast.Operator) string {
+		left := ast.Identifier{Name: "left", Type: t}
+		right := ast.Identifier{Name: "right", Type: t}
+		expr := ast.BinaryExpression{Left: left, Operator: operator, Right: right}
+		return strings.TrimSpace(GenerateJS(ast.Program{Statements: []ast.Statement{expr}}))
+	}
+
+	assertEquality(t, compare(point, ast.Equal), "((a, b) => a.x === b.x && a.y === b.y)(left, right)")
+	assertEquality(t, compare(point, ast.NotEqual), "!((a, b) => a.x === b.x && a.y === b.y)(left, right)")
+	assertEquality(t, compare(checker.StructType{Name: "Empty", Fields: map[string]checker.Type{}}, ast.Equal), "((a, b) => true)(left, right)")
+	assertEquality(
+		t,
+		compare(path, ast.Equal),
+		"((a, b) => a.name === b.name && a.points.length === b.points.length && a.points.every((item0, i0) => item0.x === b.points[i0].x && item0.y === b.points[i0].y))(left, right)",
+	)
+	assertEquality(
+		t,
+		compare(checker.TupleType{Items: []checker.Type{checker.StrType, point}}, ast.Equal),
+		"((a, b) => a[0] === b[0] && a[1].x === b[1].x && a[1].y === b[1].y)(left, right)",
+	)
+	assertEquality(
+		t,
+		compare(checker.OptionType{Inner: point}, ast.Equal),
+		"((a, b) => (a === null || b === null ? a === b : a.x === b.x && a.y === b.y))(left, right)",
+	)
+	assertEquality(
+		t,
+		compare(checker.MakeMap(point), ast.Equal),
+		"((a, b) => a.size === b.size && Array.from(a.keys()).every((key0) => b.has(key0) && a.get(key0).x === b.get(key0).x && a.get(key0).y === b.get(key0).y))(left, right)",
+	)
+	// values that aren't objects at runtime are still compared with ===
+	assertEquality(t, compare(checker.NumType, ast.Equal), "left === right")
+	assertEquality(t, compare(checker.OptionType{Inner: checker.NumType}, ast.Equal), "left === right")
+
+	shape := checker.EnumType{Name: "Shape", Variants: []string{"Dot", "Line", "Empty"}, Payloads: map[string][]checker.Type{
+		"Dot":  {point},
+		"Line": {point, point},
+	}}
+	assertEquality(
+		t,
+		compare(shape, ast.Equal),
+		"((a, b) => a.index === b.index && (a.index === Shape.Dot ? a.values[0].x === b.values[0].x && a.values[0].y === b.values[0].y : "+
+			"a.index === Shape.Line ? a.values[0].x === b.values[0].x && a.values[0].y === b.values[0].y && a.values[1].x === b.values[1].x && a.values[1].y === b.values[1].y : true))(left, right)",
+	)
+
+	// a type that contains itself leaves its nested values to a helper
+	tree := checker.StructType{Name: "Tree", Fields: map[string]checker.Type{"value": checker.NumType}}
+	tree.Fields["children"] = checker.MakeList(tree)
+	trees := compare(tree, ast.Equal)
+	assertEquality(t, strings.Split(trees, "\n")[0],
+		"((a, b) => a.children.length === b.children.length && a.children.every((item0, i0) => $equals(item0, b.children[i0])) && a.value === b.value)(left, right)")
+	if !strings.Contains(trees, "\nfunction $equals(a, b) {") {
+		t.Errorf("Expected the $equals helper to be declared:\n%s", trees)
+	}
+}
+
+func TestBlockExpressions(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "value producing block",
+			input: `
+let x = {
+  let a = 1
+  a + 1
+}`,
+			output: `
+const x = (() => {
+  const a = 1
+  return a + 1
+})()`,
+		},
+	})
+}
+
+func TestConditionalExpressions(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:   "ternary",
+			input:  `let sign = 1 > 0 ? "+" : "-"`,
+			output: `const sign = 1 > 0 ? "+" : "-"`,
+		},
+	})
+
+	flag := ast.Identifier{Name: "flag", Type: checker.BoolType}
+	numbers := ast.ConditionalExpression{Condition: flag, Consequent: ast.NumLiteral{Value: "1"}, Alternative: ast.NumLiteral{Value: "2"}}
+	bools := ast.ConditionalExpression{Condition: flag, Consequent: ast.BoolLiteral{Value: false}, Alternative: ast.BoolLiteral{Value: true}}
+	strs := ast.ConditionalExpression{Condition: flag, Consequent: ast.StrLiteral{Value: `"a"`}, Alternative: ast.StrLiteral{Value: `"bc"`}}
+	tests := []struct {
+		name   string
+		expr   ast.Expression
+		output string
+	}{
+		{
+			name:   "as an operand",
+			expr:   ast.BinaryExpression{Left: numbers, Operator: ast.Plus, Right: numbers},
+			output: "(flag ? 1 : 2) + (flag ? 1 : 2)",
+		},
+		{
+			name:   "as a unary operand",
+			expr:   ast.UnaryExpression{Operator: ast.Bang, Operand: bools},
+			output: "!(flag ? false : true)",
+		},
+		{
+			name: "as the target of a member",
+			expr: ast.MemberAccess{
+				Target:     strs,
+				AccessType: ast.Instance,
+				Member:     ast.Identifier{Name: "size", Type: checker.NumType},
+			},
+			output: "(flag ? \"a\" : \"bc\").length",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertEquality(t, strings.TrimSpace(GenerateJS(ast.Program{Statements: []ast.Statement{tt.expr}})), tt.output)
+		})
+	}
+}
+
+func TestMatchingOnOptionals(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "some and none arms",
+			input: `
+let maybe: Option<Num> = some(42)
+match maybe {
+	some(x) => x * 2,
+	none => 0
+}`,
+			output: `
+const maybe = 42
+(() => {
+  if (maybe !== null) {
+    const x = maybe
+    return x * 2
+  } else if (maybe === null) {
+    return 0
+  }
+})();`,
+		},
+	})
+}
+
+func TestMatchSubjectEvaluatedOnce(t *testing.T) {
+	find := checker.FunctionType{Parameters: []checker.Type{}, ReturnType: checker.OptionType{Inner: checker.NumType}}
+	match := ast.MatchExpression{
+		Subject: ast.FunctionCall{Name: "find", Args: []ast.Expression{}, Type: find},
+		Cases: []ast.MatchCase{
+			{
+				Pattern: ast.OptionPattern{IsSome: true, Binding: "x"},
+				Body:    []ast.Statement{ast.Identifier{Name: "x", Type: checker.NumType}},
+			},
+			{
+				Pattern: ast.OptionPattern{},
+				Body:    []ast.Statement{ast.NumLiteral{Value: "0", Type: checker.NumType}},
+			},
+		},
+	}
+	assertEquality(t, strings.TrimSpace(GenerateJS(ast.Program{Statements: []ast.Statement{match}})), strings.TrimSpace(`
+(() => {
+  const $subject = find()
+  if ($subject !== null) {
+    const x = $subject
+    return x
+  } else if ($subject === null) {
+    return 0
+  }
+})();`))
+}
+
+func TestTodoAndPanic(t *testing.T) {
+	runTests(t, []test{
+		{
+			name:  "todo() as a function body",
+			input: `fn get_name() Str { todo() }`,
+			output: `
+function get_name() {
+  throw new Error("todo")
+}`,
+		},
+		{
+			name:   "panic() in an expression",
+			input:  `let name: Str = panic("no name")`,
+			output: `const name = (() => { throw new Error("no name") })()`,
+		},
+	})
+}
+
+func TestAssert(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "assert() with a message",
+			input: `
+let count = 42
+assert(count > 0, "count must be positive")`,
+			output: `
+const count = 42
+if (!(count > 0)) throw new Error("count must be positive")`,
+		},
+		{
+			name:   "assert() without a message",
+			input:  `assert(1 < 2)`,
+			output: `if (!(1 < 2)) throw new Error("Assertion failed")`,
+		},
+		{
+			name:  "assert() at the end of a function body",
+			input: `fn check(count: Num) { assert(count > 0) }`,
+			output: `
+function check(count) {
+  if (!(count > 0)) throw new Error("Assertion failed")
+}`,
+		},
+	})
+
+	assertion := ast.FunctionCall{
+		Name: "assert",
+		Args: []ast.Expression{ast.BoolLiteral{Value: false}, ast.StrLiteral{Value: `"unreachable"`}},
+		Type: checker.FunctionType{Name: "assert", Parameters: []checker.Type{checker.BoolType, checker.StrType}, Optional: 1, ReturnType: checker.VoidType, Builtin: true},
+	}
+	body := GenerateJS(ast.Program{Statements: []ast.Statement{
+		ast.FunctionDeclaration{Name: "fail", Parameters: []ast.Parameter{}, ReturnType: checker.VoidType, Body: []ast.Statement{assertion}},
+	}})
+	assertEquality(t, strings.TrimSpace(body), "function fail() {\n  if (!(false)) throw new Error(\"unreachable\")\n}")
+
+	value := GenerateJS(ast.Program{Statements: []ast.Statement{ast.VariableDeclaration{Name: "x", Value: assertion}}})
+	assertEquality(t, strings.TrimSpace(value), `const x = (() => { if (!(false)) throw new Error("unreachable") })()`)
+}
+
+func TestMathBuiltins(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "min(), max() and abs() use the Math object",
+			input: `
+let low = min(3, 7)
+let high = max(3, 7)
+abs(low - high)`,
+			output: `
+const low = Math.min(3, 7)
+const high = Math.max(3, 7)
+Math.abs(low - high);`,
+		},
+		{
+			name: "functions the program declares with the same names are called as they are",
+			input: `
+fn max(xs: [Num]) Num { xs[0] }
+fn assert(ok: Bool) Bool { ok }
+max([3, 7])
+assert(true)`,
+			output: `
+function max(xs) {
+  return xs[0]
+}
+
+function assert(ok) {
+  return ok
+}
+
+max([3, 7]);
+assert(true);`,
+		},
+	})
+
+	shadowed := ast.FunctionCall{
+		Name: "max",
+		Args: []ast.Expression{ast.NumLiteral{Value: "3"}, ast.NumLiteral{Value: "7"}},
+		Type: checker.FunctionType{Name: "max", Parameters: []checker.Type{checker.NumType, checker.NumType}, ReturnType: checker.NumType},
+	}
+	assertEquality(t, strings.TrimSpace(GenerateJS(ast.Program{Statements: []ast.Statement{shadowed}})), "max(3, 7);")
+}
+
+func TestIndentation(t *testing.T) {
+	input := `
+fn greet(name: Str) Str {
+  if true { name }
+  name
+}`
+	tree := treeSitterParser.Parse([]byte(input), nil)
+	program, err := ast.NewParser([]byte(input), tree).Parse()
+	if err != nil {
+		t.Fatal(fmt.Errorf("Error parsing tree: %v", err))
+	}
+
+	for name, indent := range map[string]string{"2 spaces": "  ", "4 spaces": "    ", "tab": "\t"} {
+		t.Run(name, func(t *testing.T) {
+			want := strings.Join([]string{
+				"function greet(name) {",
+				indent + "if (true) {",
+				indent + indent + "name",
+				indent + "}",
+				indent + "return name",
+				"}",
+			}, "\n")
+			assertEquality(t, strings.TrimSpace(GenerateJSWithOptions(program, Options{Indent: indent})), want)
+		})
+	}
+}
+
+func TestTopLevelSpacing(t *testing.T) {
+	input := `
+struct Point { x: Num, y: Num }
+enum Sign { Positive, Negative }
+fn zero() Num { 0 }
+fn one() Num { 1 }
+let a = zero()
+let b = one()`
+	tree := treeSitterParser.Parse([]byte(input), nil)
+	program, err := ast.NewParser([]byte(input), tree).Parse()
+	if err != nil {
+		t.Fatal(fmt.Errorf("Error parsing tree: %v", err))
+	}
+
+	assertEquality(t, GenerateJS(program), `const Sign = Object.freeze({
+  Positive: 0,
+  Negative: 1
+})
+
+function zero() {
+  return 0
+}
+
+function one() {
+  return 1
+}
+
+const a = zero()
+const b = one()
+`)
+}
+
+func TestResults(t *testing.T) {
+	runTests(t, []test{
+		{
+			name: "errors are thrown and ? is implicit",
+			input: `
+fn parse(input: Str) Result<Num> {
+  if input.size == 0 { err("empty input") }
+  ok(42)
+}
+fn double(input: Str) Result<Num> {
+  let num = parse(input)?
+  ok(num * 2)
+}`,
+			output: `
+function parse(input) {
+  if (input.length === 0) {
+    (() => { throw new Error("empty input") })()
+  }
+  return 42
+}
+
+function double(input) {
+  const num = parse(input)
+  return num * 2
+}`,
+		},
 	})
 }
+
+func TestJSDoc(t *testing.T) {
+	input := `
+fn add(x: Num, y: Num) Num { x + y }
+fn is_loud(names: [Str], loud: Bool) Bool { loud }`
+	tree := treeSitterParser.Parse([]byte(input), nil)
+	program, err := ast.NewParser([]byte(input), tree).Parse()
+	if err != nil {
+		t.Fatal(fmt.Errorf("Error parsing tree: %v", err))
+	}
+
+	want := `
+/** @param {number} x @param {number} y @returns {number} */
+function add(x, y) {
+  return x + y
+}
+
+/** @param {Array<string>} names @param {boolean} loud @returns {boolean} */
+function is_loud(names, loud) {
+  return loud
+}`
+	got := GenerateJSWithOptions(program, Options{Indent: "  ", JSDoc: true})
+	assertEquality(t, strings.TrimSpace(got), strings.TrimSpace(want))
+
+	if strings.Contains(GenerateJS(program), "/**") {
+		t.Errorf("Expected no JSDoc without the option")
+	}
+}
+
+func TestJSDocRecursiveStruct(t *testing.T) {
+	tree := checker.StructType{Name: "Tree", Fields: map[string]checker.Type{"value": checker.NumType}}
+	tree.Fields["children"] = checker.MakeList(tree)
+	program := ast.Program{Statements: []ast.Statement{
+		ast.FunctionDeclaration{
+			Name:       "root",
+			Parameters: []ast.Parameter{{Name: "tree", Type: tree}},
+			ReturnType: checker.NumType,
+			Body:       []ast.Statement{ast.NumLiteral{Value: "0", Type: checker.NumType}},
+		},
+	}}
+
+	got := GenerateJSWithOptions(program, Options{JSDoc: true})
+	want := "/** @param {{children: Array<Object>, value: number}} tree @returns {number} */"
+	assertEquality(t, strings.Split(got, "\n")[0], want)
+}
+
+func TestJSDocMap(t *testing.T) {
+	scores := checker.MakeMap(checker.NumType)
+	program := ast.Program{Statements: []ast.Statement{
+		ast.FunctionDeclaration{
+			Name:       "total",
+			Parameters: []ast.Parameter{{Name: "scores", Type: scores}},
+			ReturnType: checker.NumType,
+			Body:       []ast.Statement{ast.NumLiteral{Value: "0", Type: checker.NumType}},
+		},
+	}}
+
+	// maps are Map instances at runtime, not plain objects
+	got := GenerateJSWithOptions(program, Options{JSDoc: true})
+	assertEquality(t, strings.Split(got, "\n")[0], "/** @param {Map<string, number>} scores @returns {number} */")
+}
+
+func TestDTS(t *testing.T) {
+	input := `
+struct Person { name: Str, age: Num, nicknames: [Str] }
+enum Color { Red, Green }
+fn greet(person: Person, loud: Bool) Str { person.name }
+let count = 1`
+	tree := treeSitterParser.Parse([]byte(input), nil)
+	program, err := ast.NewParser([]byte(input), tree).Parse()
+	if err != nil {
+		t.Fatal(fmt.Errorf("Error parsing tree: %v", err))
+	}
+
+	want := `
+interface Person {
+  age: number;
+  name: string;
+  nicknames: Array<string>;
+}
+
+declare enum Color {
+  Red = 0,
+  Green = 1,
+}
+
+declare function greet(person: Person, loud: boolean): string;`
+	got := GenerateDTS(program, DefaultOptions)
+	assertEquality(t, strings.TrimSpace(got), strings.TrimSpace(want))
+}
+
+func TestDTSRenamedStruct(t *testing.T) {
+	class := checker.StructType{Name: "class", Fields: map[string]checker.Type{"size": checker.NumType}}
+	program := ast.Program{Statements: []ast.Statement{
+		ast.StructDefinition{Type: class},
+		ast.FunctionDeclaration{Name: "make", Parameters: []ast.Parameter{}, ReturnType: class},
+	}}
+
+	// the interface is named like the generated script names the struct
+	assertEquality(t, strings.TrimSpace(GenerateDTS(program, DefaultOptions)), strings.TrimSpace(`
+interface class$ {
+  size: number;
+}
+
+declare function make(): class$;`))
+}
+
+func TestModules(t *testing.T) {
+	utilSource := `
+fn greet(name: Str) Str { "Hello, {{name}}" }
+enum Color { Red, Green }`
+	utilParser := ast.NewParser([]byte(utilSource), treeSitterParser.Parse([]byte(utilSource), nil))
+	util, err := utilParser.Parse()
+	if err != nil {
+		t.Fatal(fmt.Errorf("Error parsing tree: %v", err))
+	}
+
+	assertEquality(t, strings.TrimSpace(GenerateJSWithOptions(util, Options{Exports: true})), strings.TrimSpace(`
+function greet(name) {
+  return `+"`Hello, ${name}`"+`
+}
+
+const Color = Object.freeze({
+  Red: 0,
+  Green: 1
+})
+
+export { greet, Color }`))
+
+	mainSource := `
+use lib/util
+util.greet("Alice")`
+	mainParser := ast.NewParser([]byte(mainSource), treeSitterParser.Parse([]byte(mainSource), nil))
+	mainParser.AddModule("lib/util", utilParser.Module("util"))
+	program, err := mainParser.Parse()
+	if err != nil {
+		t.Fatal(fmt.Errorf("Error parsing tree: %v", err))
+	}
+
+	assertEquality(t, strings.TrimSpace(GenerateJS(program)), strings.TrimSpace(`
+import * as util from "./lib/util.js"
+util.greet("Alice")`))
+}
+
+// a program of @size functions, each with a loop nested a few levels deep
+func syntheticProgram(size int) ast.Program {
+	program := ast.Program{Statements: []ast.Statement{}}
+	for i := range size {
+		count := ast.Identifier{Name: "count", Type: checker.NumType}
+		body := []ast.Statement{
+			ast.VariableAssignment{Name: "count", Operator: ast.Increment, Value: ast.NumLiteral{Value: "1"}},
+		}
+		for range 3 {
+			body = []ast.Statement{
+				ast.WhileLoop{
+					Condition: ast.BinaryExpression{Left: count, Operator: ast.LessThan, Right: ast.NumLiteral{Value: "10"}},
+					Body:      body,
+				},
+			}
+		}
+		program.Statements = append(program.Statements, ast.FunctionDeclaration{
+			Name:       fmt.Sprintf("fn_%d", i),
+			Parameters: []ast.Parameter{},
+			ReturnType: checker.VoidType,
+			Body: append([]ast.Statement{
+				ast.VariableDeclaration{Name: "count", Mutable: true, Value: ast.NumLiteral{Value: "0"}, Type: checker.NumType},
+			}, body...),
+		})
+	}
+	return program
+}
+
+func BenchmarkGenerateJS(b *testing.B) {
+	program := syntheticProgram(2000)
+	b.ResetTimer()
+	for range b.N {
+		GenerateJS(program)
+	}
+}
+
+// counts the writes it receives, to show output is streamed rather than written at once
+type countingWriter struct {
+	output strings.Builder
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.output.Write(p)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("disk full")
+}
+
+func TestGenerateJSTo(t *testing.T) {
+	greet := ast.FunctionDeclaration{Name: "greet", Parameters: []ast.Parameter{}, ReturnType: checker.VoidType, Body: []ast.Statement{}}
+	declaration := ast.VariableDeclaration{Name: "x", Value: ast.NumLiteral{Value: "1", Type: checker.NumType}, Type: checker.NumType}
+	programs := map[string]ast.Program{
+		"empty":         {Statements: []ast.Statement{}},
+		"only a struct": {Statements: []ast.Statement{ast.StructDefinition{Type: checker.StructType{Name: "Point", Fields: map[string]checker.Type{}}}}},
+		"declarations":  {Statements: []ast.Statement{declaration, greet, ast.Comment{Value: "// 100%%"}}},
+		"synthetic":     syntheticProgram(3),
+	}
+	for name, program := range programs {
+		t.Run(name, func(t *testing.T) {
+			for _, options := range []Options{DefaultOptions, {Indent: "\t", Exports: true}, {Indent: "  ", Pretty: true}} {
+				var streamed countingWriter
+				if err := GenerateJSToWithOptions(&streamed, program, options); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				assertEquality(t, streamed.output.String(), GenerateJSWithOptions(program, options))
+			}
+		})
+	}
+
+	var streamed countingWriter
+	if err := GenerateJSTo(&streamed, programs["declarations"]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertEquality(t, streamed.output.String(), "const x = 1\n\nfunction greet() {\n}\n\n// 100%\n")
+	if streamed.writes < 3 {
+		t.Errorf("Expected a write per statement, got %d", streamed.writes)
+	}
+
+	if err := GenerateJSTo(failingWriter{}, programs["declarations"]); err == nil || err.Error() != "disk full" {
+		t.Errorf("Expected the writer's error, got %v", err)
+	}
+}
+
+// every node kind, built directly so each one goes through the generator's type switches
+func TestEveryNodeKind(t *testing.T) {
+	num := func(value string) ast.NumLiteral { return ast.NumLiteral{Value: value, Type: checker.NumType} }
+	items := ast.Identifier{Name: "items", Type: checker.ListType{ItemType: checker.NumType}}
+	person := checker.StructType{Name: "Person", Fields: map[string]checker.Type{"age": checker.NumType}}
+	shape := checker.EnumType{Name: "Shape", Variants: []string{"Circle"}, Payloads: map[string][]checker.Type{"Circle": {checker.NumType}}}
+
+	tests := []struct {
+		name   string
+		node   ast.Statement
+		output string
+	}{
+		{"Comment", ast.Comment{Value: "// note"}, "// note"},
+		{"VariableDeclaration", ast.VariableDeclaration{Name: "x", Value: num("1")}, "const x = 1"},
+		{"StructDestructuring", ast.StructDestructuring{Names: []string{"age"}, Value: ast.Identifier{Name: "p", Type: person}}, "const { age } = p"},
+		{"ListDestructuring", ast.ListDestructuring{Mutable: true, Names: []string{"a", "b"}, Value: items}, "let [a, b] = items"},
+		{"VariableAssignment", ast.VariableAssignment{Name: "x", Operator: ast.Decrement, Value: num("1")}, "x -= 1"},
+		{"MemberAssignment", ast.MemberAssignment{Target: ast.Identifier{Name: "p", Type: person}, Member: "age", Operator: ast.Assign, Value: num("2")}, "p.age = 2"},
+		{"IndexAssignment", ast.IndexAssignment{Target: items, Index: num("0"), Operator: ast.Increment, Value: num("1")}, "items[0] += 1"},
+		{"FunctionDeclaration", ast.FunctionDeclaration{Name: "noop", Parameters: []ast.Parameter{}, ReturnType: checker.VoidType, Body: []ast.Statement{}}, "function noop() {\n}"},
+		{"StructDefinition", ast.StructDefinition{Type: person}, ""},
+		{"EnumDefinition", ast.EnumDefinition{Type: shape}, "const Shape = Object.freeze({\n  Circle: 0\n})"},
+		{"TypeAlias", ast.TypeAlias{Name: "Count", Type: checker.NumType}, ""},
+		{"Import", ast.Import{Path: "lib/util", Name: "util"}, `import * as util from "./lib/util.js"`},
+		{"WhileLoop", ast.WhileLoop{Condition: ast.BoolLiteral{Value: false}, Body: []ast.Statement{}}, "while (false) {\n}"},
+		{"ForLoop", ast.ForLoop{Cursor: ast.Identifier{Name: "i", Type: checker.NumType}, Iterable: items, Body: []ast.Statement{}}, "for (const i of items) {\n}"},
+		{"ForLoop with an index", ast.ForLoop{Index: &ast.Identifier{Name: "i", Type: checker.NumType}, Cursor: ast.Identifier{Name: "item", Type: checker.NumType}, Iterable: items, Body: []ast.Statement{}}, "for (let i = 0; i < items.length; i++) {\n  const item = items[i]\n}"},
+		{"ForLoop with an index over a call", ast.ForLoop{Index: &ast.Identifier{Name: "i", Type: checker.NumType}, Cursor: ast.Identifier{Name: "item", Type: checker.NumType}, Iterable: ast.FunctionCall{Name: "load", Args: []ast.Expression{}, Type: checker.FunctionType{Name: "load", ReturnType: items.Type}}, Body: []ast.Statement{}}, "for (let i = 0, $items0 = load(); i < $items0.length; i++) {\n  const item = $items0[i]\n}"},
+		{"Loop", ast.Loop{Body: []ast.Statement{ast.Break{}}}, "while (true) {\n  break\n}"},
+		{"Break", ast.Break{Label: "outer"}, "break outer"},
+		{"Continue", ast.Continue{}, "continue"},
+		{"Defer", ast.Defer{Expr: ast.Identifier{Name: "items", Type: items.Type}}, "items"},
+		{"FunctionDeclaration with a Defer", ast.FunctionDeclaration{Name: "noop", Parameters: []ast.Parameter{}, ReturnType: checker.VoidType, Body: []ast.Statement{ast.Defer{Expr: items}}}, "function noop() {\n  const $deferred = []\n  try {\n    $deferred.push(() => {\n      items\n    })\n  } finally {\n    while ($deferred.length > 0) {\n      $deferred.pop()()\n    }\n  }\n}"},
+		{"IfStatement", ast.IfStatement{Condition: ast.BoolLiteral{Value: true}, Body: []ast.Statement{}}, "if (true) {\n}"},
+		{"Identifier", items, "items"},
+		{"StrLiteral", ast.StrLiteral{Value: `"hi"`}, `"hi"`},
+		{"InterpolatedStr", ast.InterpolatedStr{Chunks: []ast.Expression{ast.StrLiteral{Value: "n = "}, ast.Identifier{Name: "n", Type: checker.NumType}}}, "`n = ${n}`"},
+		{"InterpolatedStr with a literal ${", ast.InterpolatedStr{Chunks: []ast.Expression{ast.StrLiteral{Value: "${{"}, ast.Identifier{Name: "n", Type: checker.NumType}}}, "`$\\{{${n}`"},
+		{"NumLiteral", num("42"), "42"},
+		{"BoolLiteral", ast.BoolLiteral{Value: true}, "true"},
+		{"ListLiteral", ast.ListLiteral{Items: []ast.Expression{num("1"), num("2")}}, "[1, 2]"},
+		{"TupleLiteral", ast.TupleLiteral{Items: []ast.Expression{num("1"), ast.StrLiteral{Value: `"a"`}}}, `[1, "a"]`},
+		{"MapLiteral", ast.MapLiteral{Entries: []ast.MapEntry{{Key: `"a"`, Value: num("1")}}}, `new Map([["a", 1]])`},
+		{"BinaryExpression", ast.BinaryExpression{Left: num("1"), Operator: ast.Plus, Right: num("2")}, "1 + 2"},
+		{"UnaryExpression", ast.UnaryExpression{Operator: ast.Bang, Operand: ast.BoolLiteral{Value: true}}, "!true"},
+		{"AnonymousFunction", ast.AnonymousFunction{Parameters: []ast.Parameter{}, ReturnType: checker.VoidType, Body: []ast.Statement{}}, "() => {\n}"},
+		{"StructInstance", ast.StructInstance{Type: person, Properties: []ast.StructValue{{Name: "age", Value: num("3")}}}, "{age: 3}"},
+		{"StructInstance with a Spread", ast.StructInstance{Type: person, Properties: []ast.StructValue{{Name: "age", Value: num("3")}}, Spread: ast.Identifier{Name: "p", Type: person}}, "{...p, age: 3}"},
+		{"Spread", ast.ListLiteral{Items: []ast.Expression{ast.Spread{Expr: items}, num("4")}}, "[...items, 4]"},
+		{"FunctionCall", ast.FunctionCall{Name: "print", Args: []ast.Expression{num("1")}, Type: checker.FunctionType{Name: "print", ReturnType: checker.VoidType}}, "console.log(1);"},
+		{"EnumVariantInstance", ast.EnumVariantInstance{Type: shape, Variant: "Circle", Values: []ast.Expression{num("1")}}, "{index: Shape.Circle, values: [1]}"},
+		{"MemberAccess", ast.MemberAccess{Target: ast.Identifier{Name: "p", Type: person}, AccessType: ast.Instance, Member: ast.Identifier{Name: "age", Type: checker.NumType}}, "p.age"},
+		{"OptionalMemberAccess", ast.OptionalMemberAccess{Target: ast.Identifier{Name: "p", Type: checker.OptionType{Inner: person}}, Member: ast.Identifier{Name: "age", Type: checker.NumType}, Type: checker.OptionType{Inner: checker.NumType}}, "p?.age"},
+		{"Cast", ast.Cast{Expr: num("1"), Type: checker.StrType}, "String(1)"},
+		{"IndexAccess", ast.IndexAccess{Target: items, Index: num("0"), Type: checker.NumType}, "items[0]"},
+		{"TypeQuery", ast.TypeQuery{Expr: items}, "items"},
+		{"TryExpression", ast.TryExpression{Expr: items, Type: items.Type}, "items"},
+		{"ConditionalExpression", ast.ConditionalExpression{Condition: ast.BoolLiteral{Value: true}, Consequent: num("1"), Alternative: num("2")}, "true ? 1 : 2"},
+		{"BlockExpression", ast.BlockExpression{Body: []ast.Statement{num("1")}, Type: checker.NumType}, "(() => {\n  return 1\n})();"},
+		{"MatchExpression without arms", ast.MatchExpression{Subject: ast.BoolLiteral{Value: true}}, "(() => {\n})();"},
+		{"MatchExpression", ast.MatchExpression{Subject: ast.BoolLiteral{Value: true}, Cases: []ast.MatchCase{{Pattern: ast.Wildcard{Type: checker.BoolType}, Body: []ast.Statement{num("1")}}}}, "(() => {\n  {\n    return 1\n  }\n})();"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GenerateJS(ast.Program{Statements: []ast.Statement{tt.node}})
+			assertEquality(t, strings.TrimSpace(got), tt.output)
+		})
+	}
+}
+
+// the parser and the generator must agree on the node types, otherwise generation panics
+func TestParsedProgramRoundTrip(t *testing.T) {
+	input := `
+struct Person { name: Str, age: Num }
+enum Color { Red, Green }
+fn describe(person: Person) Str { person.name }
+mut count = 0
+while count < 3 {
+	count =+ 1
+}
+for i in 1..3 {
+	print(i)
+}
+let people = [Person{ name: "Alice", age: 30 }]
+for person in people {
+	print(describe(person))
+}
+let { name, age } = Person{ name: "Bob", age: 4 }
+if count > 1 {
+	print("many")
+} else {
+	print("few")
+}
+let color = Color::Red
+let label = match color {
+	Color::Red => "red",
+	Color::Green => "green"
+}`
+	tree := treeSitterParser.Parse([]byte(input), nil)
+	parser := ast.NewParser([]byte(input), tree)
+	program, err := parser.Parse()
+	if err != nil {
+		t.Fatal(fmt.Errorf("Error parsing tree: %v", err))
+	}
+	if diagnostics := parser.GetDiagnostics(); len(diagnostics) > 0 {
+		t.Fatalf("Unexpected diagnostics: %v", diagnostics)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("Generating the parsed program panicked: %v", r)
+		}
+	}()
+	if js := GenerateJS(program); js == "" {
+		t.Errorf("Expected JS for the parsed program")
+	}
+}
+
+// built directly so both targets are generated even where the grammar isn't available
+func TestTargets(t *testing.T) {
+	num := func(value string) ast.NumLiteral { return ast.NumLiteral{Value: value, Type: checker.NumType} }
+	numMap := checker.MapType{KeyType: checker.StrType, ValueType: checker.NumType}
+	items := ast.Identifier{Name: "items", Type: checker.MakeList(checker.NumType)}
+	person := checker.StructType{Name: "Person", Fields: map[string]checker.Type{"name": checker.StrType, "class": checker.StrType}}
+
+	tests := []struct {
+		name        string
+		statements  []ast.Statement
+		output, es5 string
+	}{
+		{
+			name: "variables",
+			statements: []ast.Statement{
+				ast.VariableDeclaration{Name: "x", Value: num("1")},
+				ast.VariableDeclaration{Mutable: true, Name: "y", Value: num("2")},
+				ast.ListDestructuring{Names: []string{"a", "b"}, Value: items},
+			},
+			output: "const x = 1\nlet y = 2\nconst [a, b] = items",
+			es5:    "var x = 1\nvar y = 2\nvar a = items[0]\nvar b = items[1]",
+		},
+		{
+			name: "anonymous functions",
+			statements: []ast.Statement{
+				ast.VariableDeclaration{Name: "double", Value: ast.AnonymousFunction{
+					Parameters: []ast.Parameter{{Name: "n", Type: checker.NumType}},
+					ReturnType: checker.NumType,
+					Body:       []ast.Statement{ast.BinaryExpression{Operator: ast.Multiply, Left: ast.Identifier{Name: "n", Type: checker.NumType}, Right: num("2")}},
+				}},
+			},
+			output: "const double = (n) => {\n  return n * 2\n}",
+			es5:    "var double = function (n) {\n  return n * 2\n}",
+		},
+		{
+			name: "comparing lists",
+			statements: []ast.Statement{
+				ast.BinaryExpression{Operator: ast.Equal, Left: items, Right: items},
+			},
+			output: "((a, b) => a.length === b.length && a.every((item0, i0) => item0 === b[i0]))(items, items)",
+			es5:    "(function (a, b) { return a.length === b.length && a.every(function (item0, i0) { return item0 === b[i0] }) })(items, items)",
+		},
+		{
+			name: "a block expression",
+			statements: []ast.Statement{
+				ast.VariableDeclaration{Name: "x", Value: ast.BlockExpression{Body: []ast.Statement{num("1")}, Type: checker.NumType}},
+			},
+			output: "const x = (() => {\n  return 1\n})()",
+			es5:    "var x = (function () {\n  return 1\n})()",
+		},
+		{
+			name: "a loop over a range",
+			statements: []ast.Statement{
+				ast.ForLoop{
+					Cursor:   ast.Identifier{Name: "i", Type: checker.NumType},
+					Iterable: ast.RangeExpression{Start: num("1"), End: num("3")},
+					Body:     []ast.Statement{},
+				},
+			},
+			output: "for (let i = 1; i < 3; i++) {\n}",
+			es5:    "for (var i = 1; i < 3; i++) {\n}",
+		},
+		{
+			name: "a map literal",
+			statements: []ast.Statement{
+				ast.VariableDeclaration{Name: "ages", Value: ast.MapLiteral{Entries: []ast.MapEntry{{Key: `"jane"`, Value: num("1")}, {Key: `"joe"`, Value: num("2")}}, Type: numMap}},
+				ast.VariableDeclaration{Name: "empty", Value: ast.MapLiteral{Entries: []ast.MapEntry{}, Type: numMap}},
+			},
+			output: `const ages = new Map([["jane", 1], ["joe", 2]])` + "\n" + `const empty = new Map([])`,
+			es5: `var ages = $makeMap([["jane", 1], ["joe", 2]])
+var empty = $makeMap([])
+
+function $makeMap(entries) {
+  var map = new Map()
+  for (var i = 0; i < entries.length; i++) {
+    map.set(entries[i][0], entries[i][1])
+  }
+  return map
+}`,
+		},
+		{
+			name: "an interpolated string",
+			statements: []ast.Statement{
+				ast.InterpolatedStr{Chunks: []ast.Expression{
+					ast.StrLiteral{Value: "Hello, "},
+					ast.Identifier{Name: "name", Type: checker.StrType},
+					ast.StrLiteral{Value: " is "},
+					ast.Identifier{Name: "age", Type: checker.NumType},
+				}},
+			},
+			output: "`Hello, ${name} is ${age}`",
+			es5:    `("Hello, " + name + " is " + String(age))`,
+		},
+		{
+			name: "a loop over a string from a call",
+			statements: []ast.Statement{
+				ast.ForLoop{
+					Cursor:   ast.Identifier{Name: "c", Type: checker.StrType},
+					Iterable: ast.FunctionCall{Name: "read", Args: []ast.Expression{}, Type: checker.FunctionType{Name: "read", Parameters: []checker.Type{}, ReturnType: checker.StrType}},
+					Body:     []ast.Statement{},
+				},
+			},
+			output: "for (const c of read()) {\n}",
+			es5:    "var $items0 = read()\nfor (var $i0 = 0; $i0 < $items0.length; $i0++) {\n  var c = $items0[$i0]\n}",
+		},
+		{
+			name: "destructuring a struct from a call",
+			statements: []ast.Statement{
+				ast.StructDestructuring{
+					Names: []string{"name", "class"},
+					Value: ast.FunctionCall{Name: "load", Args: []ast.Expression{}, Type: checker.FunctionType{Name: "load", Parameters: []checker.Type{}, ReturnType: person}},
+				},
+			},
+			output: "const { name, class: class$ } = load()",
+			es5:    "var $destructured = load()\nvar name = $destructured.name\nvar class$ = $destructured.class",
+		},
+		{
+			name: "spreads",
+			statements: []ast.Statement{
+				ast.ListLiteral{Items: []ast.Expression{num("1"), ast.Spread{Expr: items}, num("2"), num("3")}, Type: items.Type},
+				ast.StructInstance{
+					Type:       person,
+					Properties: []ast.StructValue{{Name: "name", Value: ast.Identifier{Name: "name", Type: checker.StrType}}},
+					Spread:     ast.Identifier{Name: "base", Type: person},
+				},
+			},
+			output: "[1, ...items, 2, 3]\n{...base, name}",
+			es5: `[].concat([1], items, [2, 3])
+$assign(base, {name: name})
+
+function $assign(base, fields) {
+  var object = {}
+  for (var key in base) {
+    object[key] = base[key]
+  }
+  for (var key in fields) {
+    object[key] = fields[key]
+  }
+  return object
+}`,
+		},
+		{
+			name: "optional members and contains",
+			statements: []ast.Statement{
+				ast.OptionalMemberAccess{
+					Target: ast.Identifier{Name: "maybe", Type: checker.OptionType{Inner: person}},
+					Member: ast.Identifier{Name: "name", Type: checker.StrType},
+					Type:   checker.OptionType{Inner: checker.StrType},
+				},
+				ast.MemberAccess{
+					Target:     items,
+					AccessType: ast.Instance,
+					Member:     ast.FunctionCall{Name: "contains", Args: []ast.Expression{num("2")}, Type: items.Type.GetProperty("contains").(checker.FunctionType)},
+				},
+			},
+			output: "maybe?.name\nitems.includes(2)",
+			es5:    "(maybe == null ? null : maybe.name)\n(items.indexOf(2) !== -1)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := ast.Program{Statements: tt.statements}
+			assertEquality(t, strings.TrimSpace(GenerateJS(program)), tt.output)
+			assertEquality(t, strings.TrimSpace(GenerateJSWithOptions(program, Options{Target: ES5})), tt.es5)
+		})
+	}
+}
+
+func TestES5Modules(t *testing.T) {
+	program := ast.Program{Statements: []ast.Statement{
+		ast.Import{Path: "std/math", Name: "math"},
+		ast.FunctionDeclaration{Name: "zero", Parameters: []ast.Parameter{}, ReturnType: checker.NumType, Body: []ast.Statement{
+			ast.NumLiteral{Value: "0", Type: checker.NumType},
+		}},
+	}}
+	assertEquality(t, strings.TrimSpace(GenerateJSWithOptions(program, Options{Target: ES5, Exports: true})), strings.TrimSpace(`
+var math = require("./std/math.js")
+
+function zero() {
+  return 0
+}
+
+module.exports = { zero: zero }`))
+}
@@ -0,0 +1,98 @@
+package javascript
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/akonwi/ard/ast"
+	"github.com/akonwi/ard/checker"
+)
+
+// a one line comment like `/** @param {number} x @returns {number} */`
+func (g jsGenerator) jsDocComment(decl ast.FunctionDeclaration) string {
+	tags := make([]string, 0, len(decl.Parameters)+1)
+	for _, param := range decl.Parameters {
+		tags = append(tags, fmt.Sprintf("@param {%s} %s", jsDocType(param.Type), g.name(param.Name)))
+	}
+	tags = append(tags, fmt.Sprintf("@returns {%s}", jsDocType(decl.ReturnType)))
+	return fmt.Sprintf("/** %s */", strings.Join(tags, " "))
+}
+
+// the JSDoc spelling of a Kon type, matching how values are represented at runtime
+func jsDocType(t checker.Type) string {
+	return jsDocTypeIn(t, nil)
+}
+
+// like jsDocType, inside the fields of the @enclosing structs.
+// structs are spelled by their fields, so one that contains itself is an Object where it repeats
+func jsDocTypeIn(t checker.Type, enclosing []string) string {
+	if t == nil {
+		return "*"
+	}
+	switch t.Kind() {
+	case checker.KindNum:
+		return "number"
+	case checker.KindStr:
+		return "string"
+	case checker.KindBool:
+		return "boolean"
+	case checker.KindVoid:
+		return "void"
+	case checker.KindNever:
+		return "never"
+	}
+
+	switch t := t.(type) {
+	case checker.ListType:
+		return fmt.Sprintf("Array<%s>", jsDocTypeIn(t.ItemType, enclosing))
+	case checker.MapType:
+		return fmt.Sprintf("Map<string, %s>", jsDocTypeIn(t.ValueType, enclosing))
+	case checker.TupleType:
+		items := make([]string, len(t.Items))
+		for i, item := range t.Items {
+			items[i] = jsDocTypeIn(item, enclosing)
+		}
+		return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+	case checker.OptionType:
+		return fmt.Sprintf("(%s|null)", jsDocTypeIn(t.Inner, enclosing))
+	case checker.ResultType:
+		// failures are thrown, so a result is its ok value
+		return jsDocTypeIn(t.OkType, enclosing)
+	case checker.StructType:
+		if !t.IsAnonymous() {
+			if slices.Contains(enclosing, t.Name) {
+				return "Object"
+			}
+			enclosing = append(slices.Clone(enclosing), t.Name)
+		}
+		fields := make([]string, 0, len(t.Fields))
+		for _, name := range sortedKeys(t.Fields) {
+			fields = append(fields, fmt.Sprintf("%s: %s", name, jsDocTypeIn(t.Fields[name], enclosing)))
+		}
+		return fmt.Sprintf("{%s}", strings.Join(fields, ", "))
+	case checker.EnumType:
+		if t.HasPayloads() {
+			return "{index: number, values: Array<*>}"
+		}
+		return "number"
+	case checker.FunctionType:
+		params := make([]string, len(t.Parameters))
+		for i, param := range t.Parameters {
+			params[i] = jsDocTypeIn(param, enclosing)
+		}
+		return fmt.Sprintf("function(%s): %s", strings.Join(params, ", "), jsDocTypeIn(t.ReturnType, enclosing))
+	default:
+		return "*"
+	}
+}
+
+func sortedKeys(fields map[string]checker.Type) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
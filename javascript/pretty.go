@@ -0,0 +1,65 @@
+package javascript
+
+import "strings"
+
+// Pretty normalizes the whitespace of generated JS: trailing whitespace is trimmed from each line,
+// runs of blank lines are collapsed into one, and the output ends with a single newline.
+// lines inside multi-line template literals are left alone, since their whitespace is part of a string
+func Pretty(js string) string {
+	lines := strings.Split(js, "\n")
+	pretty := make([]string, 0, len(lines))
+	inTemplate := false
+	blank := false
+	for _, line := range lines {
+		startsInTemplate := inTemplate
+		inTemplate = endsInTemplate(line, inTemplate)
+		// whitespace before a line break inside a template literal is kept
+		if !inTemplate {
+			line = strings.TrimRight(line, " \t\r")
+		}
+		if startsInTemplate {
+			pretty = append(pretty, line)
+			blank = false
+			continue
+		}
+
+		if line == "" {
+			if blank || len(pretty) == 0 {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		pretty = append(pretty, line)
+	}
+
+	output := strings.TrimRight(strings.Join(pretty, "\n"), "\n")
+	if output == "" {
+		return ""
+	}
+	return output + "\n"
+}
+
+// whether a template literal is still open at the end of @line, given whether one was open at its start
+func endsInTemplate(line string, inTemplate bool) bool {
+	var quote byte
+	if inTemplate {
+		quote = '`'
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '/' && i+1 < len(line) && line[i+1] == '/':
+			return false
+		}
+	}
+	return quote == '`'
+}
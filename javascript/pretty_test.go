@@ -0,0 +1,48 @@
+package javascript
+
+import "testing"
+
+func TestPretty(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "trailing whitespace",
+			input: "const x = 1  \nif (x) {\t\n  x \n}",
+			want:  "const x = 1\nif (x) {\n  x\n}\n",
+		},
+		{
+			name:  "doubled blank lines",
+			input: "const x = 1\n\n\n\nconst y = 2\n  \n\nconst z = 3",
+			want:  "const x = 1\n\nconst y = 2\n\nconst z = 3\n",
+		},
+		{
+			name:  "leading and trailing blank lines",
+			input: "\n\nconst x = 1\n\n\n",
+			want:  "const x = 1\n",
+		},
+		{
+			name:  "template literals keep their whitespace",
+			input: "const s = `a  \n\n\nb  `  \nconst t = \"`\"  ",
+			want:  "const s = `a  \n\n\nb  `\nconst t = \"`\"\n",
+		},
+		{
+			name:  "backticks in comments",
+			input: "// uses `x`  \n\n\nx",
+			want:  "// uses `x`\n\nx\n",
+		},
+		{
+			name:  "empty output",
+			input: "\n  \n",
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertEquality(t, Pretty(tt.input), tt.want)
+		})
+	}
+}